@@ -1,32 +1,44 @@
 package adele
 
 import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
 	"github.com/cidekar/adele-framework/cache"
 	"github.com/cidekar/adele-framework/cache/badgerdriver"
+	"github.com/cidekar/adele-framework/cache/memorydriver"
 	"github.com/cidekar/adele-framework/cache/redisdriver"
+	"github.com/cidekar/adele-framework/cache/sqldriver"
+	"github.com/cidekar/adele-framework/cache/tiereddriver"
+	"github.com/cidekar/adele-framework/config"
 	"github.com/cidekar/adele-framework/database"
-	"github.com/cidekar/adele-framework/filesystem/miniofilesystem"
-	"github.com/cidekar/adele-framework/filesystem/s3filesystem"
-	"github.com/cidekar/adele-framework/filesystem/sftpfilesystem"
-	"github.com/cidekar/adele-framework/filesystem/webdavfilesystem"
+	"github.com/cidekar/adele-framework/database/seed"
+	"github.com/cidekar/adele-framework/database/sqlitedriver"
+	"github.com/cidekar/adele-framework/health"
 	"github.com/cidekar/adele-framework/helpers"
 	"github.com/cidekar/adele-framework/logger"
 	"github.com/cidekar/adele-framework/mailer"
+	"github.com/cidekar/adele-framework/metrics"
 	"github.com/cidekar/adele-framework/middleware"
 	"github.com/cidekar/adele-framework/mux"
 	"github.com/cidekar/adele-framework/render"
 	"github.com/cidekar/adele-framework/session"
 	crs "github.com/go-chi/cors"
-	"github.com/joho/godotenv"
 	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
@@ -38,9 +50,75 @@ var Helpers = &helpers.Helpers{}
 
 // Create a new instance of the Adele type using a pointer to Adele with the
 // root path of the application as a argument. The new-up is called by project adele's consuming package
-// to bootstrap the framework.
+// to bootstrap the framework. The configuration is layered from config/app.yml, config/app.<APP_ENV>.yml,
+// the .env file, the environment variables and --KEY=value flags, in that order of precedence.
 func (a *Adele) New(rootPath string) error {
 
+	err := a.CreateEnvironmentFile(rootPath)
+	if err != nil {
+		return err
+	}
+
+	values, err := (&config.Loader{RootPath: rootPath, Args: os.Args[1:]}).Load()
+	if err != nil {
+		return err
+	}
+
+	// Packages reading the environment directly, e.g., the logger, see the merged values
+	// of the keys they read.
+	// TODO: remove once those packages take their settings from the configuration.
+	err = values.Export(legacyEnvKeys...)
+	if err != nil {
+		return err
+	}
+
+	r := values.Reader()
+	a.config, err = decodeConfig(r)
+	if err != nil {
+		return err
+	}
+
+	if a.Log == nil {
+		a.Log = logger.CreateLogger()
+	}
+
+	for key, suggestion := range r.Misspelled() {
+		a.Log.Warnf("configuration key %s is not used by the framework; did you mean %s?", key, suggestion)
+	}
+
+	return a.bootstrap(rootPath)
+}
+
+// Create and bootstrap a new instance of the Adele type from a configuration built by
+// the caller rather than the environment. Options are applied in order and may override
+// any section of the configuration or add providers; the error of a provider failing to
+// be added or registered is returned. Example:
+//
+//	cfg := adele.ConfigFromEnv()
+//	app, err := adele.NewWithConfig(rootPath, cfg, adele.WithDatabase(adele.DatabaseConfig{}))
+func NewWithConfig(rootPath string, cfg Config, opts ...Option) (*Adele, error) {
+	a := &Adele{config: cfg}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if err := errors.Join(a.registry.errs...); err != nil {
+		return nil, err
+	}
+
+	if err := a.bootstrap(rootPath); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Bootstrap every framework subsystem from the application configuration. When a
+// subsystem fails, the resources already opened, e.g., the database pool, are closed and
+// the error is returned.
+func (a *Adele) bootstrap(rootPath string) error {
+
 	directories := []string{"handlers", "logs", "jobs", "middleware", "migrations", "models", "public", "resources", "resources/views", "resources/mail", "storage"}
 
 	err := a.CreateDirectories(rootPath, directories)
@@ -48,18 +126,41 @@ func (a *Adele) New(rootPath string) error {
 		return err
 	}
 
-	err = a.CreateEnvironmentFile(rootPath)
+	if a.Log == nil {
+		a.Log = logger.CreateLogger()
+	}
+
+	a.AppName = a.config.AppName
+	a.Debug = a.config.Debug
+	a.RootPath = rootPath
+	a.Version = Version
+	a.ViewsTemplateDir = a.config.ViewsTemplateDir
+
+	err = a.bootstrapSubsystems(rootPath)
+	if err == nil {
+		a.provideSubsystems()
+		err = a.registerProviders()
+	}
 	if err != nil {
+		if closeErr := a.Shutdown(context.Background()); closeErr != nil {
+			a.Log.Error(closeErr)
+		}
 		return err
 	}
 
-	err = godotenv.Load(rootPath + "/.env")
+	return nil
+}
+
+// Bootstrap the subsystems in the order they depend on each other.
+func (a *Adele) bootstrapSubsystems(rootPath string) error {
+	a.BootstrapScheduler()
+
+	// The database comes first so the sessions can be stored in it.
+	err := a.BootstrapDatabase()
 	if err != nil {
 		return err
 	}
 
-	a.Log = logger.CreateLogger()
-
 	sess, err := a.BootstrapSessionManager()
 	if err != nil {
 		return err
@@ -75,109 +176,247 @@ func (a *Adele) New(rootPath string) error {
 	}
 
 	a.Routes = muxRouter.(*mux.Mux)
-	a.Debug, _ = strconv.ParseBool(os.Getenv("APP_DEBUG"))
-	a.RootPath = rootPath
-	a.Version = Version
-	a.ViewsTemplateDir = Helpers.Getenv("VIEWS_TEMPLATE_DIR", "resources/views")
-	a.config = config{
-		port:        Helpers.Getenv("HTTP_PORT", "4000"),
-		renderer:    Helpers.Getenv("RENDERER", "jet"),
-		sessionType: Helpers.Getenv("SESSION_TYPE"),
+
+	err = a.BootstrapMetrics()
+	if err != nil {
+		return err
 	}
 
 	a.BoostrapFilesystem()
-
 	a.Mail = a.BoootstrapMailer()
-
 	a.JetViews = a.BootstrapJetEngine()
-
 	a.Render = a.BootstrapRender()
-
-	a.BootstrapDatabase()
-
 	a.Helpers = a.BootstrapHelpers()
 
-	a.BootstrapScheduler()
-
 	err = a.BootstrapCache(rootPath)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return a.BootstrapHealth()
 }
 
 // Initializes and sets up a database connection for the application—establishes a database
-// connection during application startup and stores it in the Adele struct.
-func (a *Adele) BootstrapDatabase() {
-	db, err := database.OpenDB(os.Getenv("DATABASE_TYPE"), &database.DataSourceName{
-		Host:         Helpers.Getenv("DATABASE_HOST", "localhost"),
-		Port:         Helpers.Getenv("DATABASE_PORT", "5432"),
-		User:         Helpers.Getenv("DATABASE_USER"),
-		Password:     Helpers.Getenv("DATABASE_PASSWORD"),
-		DatabaseName: Helpers.Getenv("DATABASE_NAME"),
-		SslMode:      Helpers.Getenv("DATABASE_SSL_MODE"),
+// connection during application startup and stores it in the Adele struct, along with its
+// read replicas. The returned error wraps ErrDatabaseUnavailable.
+func (a *Adele) BootstrapDatabase() error {
+	c := a.config.Database
+	if database.Driver(c.Type) == "sqlite" && c.Name != sqlitedriver.Memory && c.Name != "" && !filepath.IsAbs(c.Name) {
+		c.Name = filepath.Join(a.RootPath, c.Name)
+	}
+	dsn := &database.DataSourceName{
+		Host:         c.Host,
+		Port:         c.Port,
+		User:         c.User,
+		Password:     c.Password,
+		DatabaseName: c.Name,
+		SslMode:      c.SSLMode,
+
+		MaxOpenConns:    c.MaxOpenConns,
+		MaxIdleConns:    c.MaxIdleConns,
+		ConnMaxLifetime: c.ConnMaxLifetime,
+		ConnMaxIdleTime: c.ConnMaxIdleTime,
+		PingTimeout:     c.PingTimeout,
+
+		BusyTimeout: c.BusyTimeout,
+		JournalMode: c.JournalMode,
+	}
+
+	if c.LogQueries || c.SlowQuery > 0 || a.tracksQueries() {
+		dsn.OnQuery = a.logQuery
+	}
+
+	db, err := database.OpenDBWithRetry(context.Background(), c.Type, dsn, database.Retry{
+		Attempts: c.ConnectRetries,
+		Backoff:  c.ConnectBackoff,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			a.Log.Warnf("database connection attempt %d failed, retrying in %s: %v", attempt, wait, err)
+		},
 	})
 
 	if err != nil {
-		a.Log.Error(err)
-		os.Exit(1)
+		return fmt.Errorf("%w: %w", ErrDatabaseUnavailable, err)
+	}
+
+	txRetry := database.DefaultTxRetry
+	txRetry.OnRetry = func(attempt int, err error, wait time.Duration) {
+		a.Log.Debugf("transaction attempt %d failed, retrying in %s: %v", attempt, wait, err)
 	}
+
 	a.DB = &database.Database{
-		DataType: os.Getenv("DATABASE_TYPE"),
+		DataType: c.Type,
 		Pool:     db,
+		TxRetry:  &txRetry,
 	}
-}
 
-// Initializes the file system auto-configuration method for the framework by detecting and
-// initializes available file storage systems based on environment variables during application startup.
-func (a *Adele) BoostrapFilesystem() {
-	fileSystem := make(map[string]interface{})
+	if db == nil {
+		return nil
+	}
 
-	if os.Getenv("S3_KEY") != "" {
-		s3 := s3filesystem.S3{
-			Key:    os.Getenv("S3_KEY"),
-			Secret: os.Getenv("S3_SECRET"),
-			Region: os.Getenv("S3_REGION"),
-			Bucket: os.Getenv("S3_BUCKET"),
+	if len(c.Replicas) > 0 {
+		replicas, err := database.OpenReplicas(c.Type, dsn, c.Replicas, c.ReplicaPolicy)
+		if err != nil {
+			db.Close()
+			return fmt.Errorf("%w: %w", ErrDatabaseUnavailable, err)
 		}
-		fileSystem["S3"] = s3
-	}
 
-	if os.Getenv("MINIO_SECRET") != "" {
-		useSSL := false
-		if strings.ToLower(os.Getenv("MINIO_USESSL")) == "true" {
-			useSSL = true
+		replicas.StickyWindow = c.StickyWindow
+		replicas.OnChange = func(replica int, err error) {
+			if err != nil {
+				a.Log.Warnf("database replica %s ejected: %v", c.Replicas[replica], err)
+				return
+			}
+			a.Log.Infof("database replica %s admitted", c.Replicas[replica])
+		}
+		for i, host := range c.Replicas {
+			if replicas.Down(i) {
+				a.Log.Warnf("database replica %s is unreachable and starts ejected", host)
+			}
 		}
 
-		minio := miniofilesystem.Minio{
-			Endpoint: os.Getenv("MINIO_ENDPOINT"),
-			Key:      os.Getenv("MINIO_KEY"),
-			Secret:   os.Getenv("MINIO_SECRET"),
-			UseSSL:   useSSL,
-			Region:   os.Getenv("MINIO_REGION"),
-			Bucket:   os.Getenv("MINIO_BUCKET"),
+		a.DB.Replicas = replicas
+
+		interval, timeout := c.ReplicaCheckInterval, c.PingTimeout
+		if interval <= 0 {
+			interval = 10 * time.Second
+		}
+		if timeout <= 0 {
+			timeout = database.DefaultPingTimeout
 		}
-		fileSystem["MINIO"] = minio
-	}
 
-	if os.Getenv("SFTP_HOST") != "" {
-		sftp := sftpfilesystem.SFTP{
-			Host:     os.Getenv("SFTP_HOST"),
-			User:     os.Getenv("SFTP_USER"),
-			Password: os.Getenv("SFTP_PASSWORD"),
-			Port:     os.Getenv("SFTP_PORT"),
+		if a.Scheduler != nil {
+			a.Scheduler.AddFunc("@every "+interval.String(), func() {
+				ctx, cancel := context.WithTimeout(context.Background(), timeout)
+				defer cancel()
+				replicas.CheckReplicas(ctx)
+			})
 		}
-		fileSystem["SFTP"] = sftp
 	}
 
-	if os.Getenv("WEBDAV_HOST") != "" {
-		webDAV := webdavfilesystem.WebDAV{
-			Host:     os.Getenv("WEBDAV_HOST"),
-			User:     os.Getenv("WEBDAV_USER"),
-			Password: os.Getenv("WEBDAV_PASSWORD"),
+	if a.Metrics != nil {
+		a.databaseMetrics()
+	}
+
+	if c.WaitWarning > 0 && a.Scheduler != nil {
+		a.Scheduler.AddFunc("@every 1m", a.DB.WaitMonitor(int64(c.WaitWarning), func(waits int64, waited time.Duration) {
+			a.Log.Warnf("%d queries waited %s in total for a database connection in the last minute; consider raising DATABASE_MAX_OPEN_CONNS", waits, waited)
+		}))
+	}
+
+	return nil
+}
+
+// Record the statistics of the database connection pool as gauges.
+func (a *Adele) databaseMetrics() {
+	gauges := []struct {
+		name, help string
+		value      func(s sql.DBStats) float64
+	}{
+		{"adele_db_max_open_connections", "Maximum number of open connections to the database.", func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }},
+		{"adele_db_open_connections", "Number of open connections to the database.", func(s sql.DBStats) float64 { return float64(s.OpenConnections) }},
+		{"adele_db_in_use_connections", "Number of connections in use.", func(s sql.DBStats) float64 { return float64(s.InUse) }},
+		{"adele_db_idle_connections", "Number of idle connections.", func(s sql.DBStats) float64 { return float64(s.Idle) }},
+		{"adele_db_wait_count", "Number of queries that waited for a connection.", func(s sql.DBStats) float64 { return float64(s.WaitCount) }},
+		{"adele_db_wait_duration_seconds", "Time queries waited for a connection.", func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }},
+		{"adele_db_max_idle_closed", "Number of connections closed because of the idle connection limit.", func(s sql.DBStats) float64 { return float64(s.MaxIdleClosed) }},
+		{"adele_db_max_idle_time_closed", "Number of connections closed because they were idle too long.", func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }},
+		{"adele_db_max_lifetime_closed", "Number of connections closed because they reached their lifetime.", func(s sql.DBStats) float64 { return float64(s.MaxLifetimeClosed) }},
+	}
+
+	for _, g := range gauges {
+		value := g.value
+		a.Metrics.GaugeFunc(g.name, g.help, nil, nil, func() float64 {
+			return value(a.DB.Stats())
+		})
+	}
+}
+
+// Log a statement run on the database when DATABASE_LOG_QUERIES is set, or as a warning
+// when it took DATABASE_SLOW_QUERY or longer, tagged with the ID of the request. The
+// values of the arguments are redacted unless DATABASE_LOG_QUERY_ARGS is set.
+func (a *Adele) logQuery(ctx context.Context, q database.Query) {
+	c := a.config.Database
+	slow := c.SlowQuery > 0 && q.Duration >= c.SlowQuery
+	if !slow && !c.LogQueries {
+		return
+	}
+
+	fields := logrus.Fields{
+		"sql":         q.Statement,
+		"duration_ms": float64(q.Duration.Nanoseconds()) / 1000000.0,
+	}
+	if reqID := middleware.GetReqID(ctx); reqID != "" {
+		fields["req_id"] = reqID
+	}
+	if len(q.Args) > 0 {
+		args := q.Args
+		if !c.LogQueryArgs {
+			args = make([]interface{}, len(q.Args))
+			for i := range args {
+				args[i] = "[redacted]"
+			}
 		}
-		fileSystem["WEBDAV"] = webDAV
+		fields["args"] = args
+	}
+	if q.Err != nil {
+		fields["error"] = q.Err.Error()
+	}
+
+	entry := a.Log.WithFields(fields)
+	if slow {
+		entry.Warnf("slow query took %s", q.Duration.Round(time.Millisecond))
+		return
+	}
+	entry.Info("query")
+}
+
+// Log the number of statements a request ran and warn of the statements it ran at least
+// DATABASE_REPEATED_QUERY_WARNING times, which usually means a query runs for every row
+// of another, i.e., an N+1 query, better written as a join or a single IN query.
+func (a *Adele) reportQueries(r *http.Request, stats *database.QueryStats) {
+	if stats.Count() == 0 {
+		return
+	}
+
+	fields := logrus.Fields{"uri": r.URL.RequestURI()}
+	if reqID := middleware.GetReqID(r.Context()); reqID != "" {
+		fields["req_id"] = reqID
+	}
+	entry := a.Log.WithFields(fields)
+
+	for _, q := range stats.Repeated(a.config.Database.RepeatedQueryWarning) {
+		entry.WithField("sql", q.Statement).Warnf("possible N+1 query: statement ran %d times in one request", q.Count)
+	}
+
+	entry.Debugf("request ran %d queries in %s", stats.Count(), stats.Duration().Round(time.Microsecond))
+}
+
+// Report whether the statements of every request are counted to warn of N+1 queries,
+// i.e., in debug mode with a database.
+func (a *Adele) tracksQueries() bool {
+	return a.Debug && a.config.Database.Type != "" && a.config.Database.RepeatedQueryWarning > 0
+}
+
+// Initializes the file system auto-configuration method for the framework by detecting and
+// initializes available file storage systems based on the application configuration during startup.
+func (a *Adele) BoostrapFilesystem() {
+	fileSystem := make(map[string]interface{})
+	c := a.config.Filesystem
+
+	if c.S3.Key != "" {
+		fileSystem["S3"] = c.S3
+	}
+
+	if c.Minio.Secret != "" {
+		fileSystem["MINIO"] = c.Minio
+	}
+
+	if c.SFTP.Host != "" {
+		fileSystem["SFTP"] = c.SFTP
+	}
+
+	if c.WebDAV.Host != "" {
+		fileSystem["WEBDAV"] = c.WebDAV
 	}
 
 	a.FileSystem = fileSystem
@@ -189,15 +428,12 @@ func (a *Adele) BootstrapHelpers() *helpers.Helpers {
 
 	// Define the file types allowd by the system and add any provided by the application developer.
 	mimeTypes := []string{"image/gif", "image/jpeg", "image/png", "application/pdf"}
-	exploded := strings.Split(Helpers.Getenv("FILE_TYPES_ALLOWED"), "")
-	mimeTypes = append(mimeTypes, exploded...)
+	mimeTypes = append(mimeTypes, a.config.Upload.AllowedMimeTypes...)
 
-	// Max file upload size is set to 10 mb
-	var maxUploadSize int64
-	if max, err := strconv.Atoi(Helpers.Getenv("FILE_MAX_UPLOAD_SIZE")); err != nil {
+	// Max file upload size defaults to 10 mb
+	maxUploadSize := a.config.Upload.MaxSize
+	if maxUploadSize <= 0 {
 		maxUploadSize = 10 << 20
-	} else {
-		maxUploadSize = int64(max)
 	}
 
 	return &helpers.Helpers{
@@ -212,25 +448,24 @@ func (a *Adele) BootstrapHelpers() *helpers.Helpers {
 }
 
 // Configure the mailer for the application by initializing mailer struct. The mailer
-// values are populated by the environemnt variables parsed from the .env file
-// at the root of the application.
+// values are populated by the mail section of the application configuration.
 func (a *Adele) BoootstrapMailer() mailer.Mail {
-	port, _ := strconv.Atoi(Helpers.Getenv("SMTP_PORT", "1025"))
+	c := a.config.Mail
 	m := mailer.Mail{
-		Domain:      os.Getenv("MAIL_DOMAIN"),
+		Domain:      c.Domain,
 		Templates:   a.RootPath + "/resources/mail",
-		Host:        os.Getenv("SMTP_HOST"),
-		Port:        port,
-		Username:    os.Getenv("SMTP_USERNAME"),
-		Password:    os.Getenv("SMTP_PASSWORD"),
-		Encryption:  os.Getenv("SMTP_ENCRYPTION"),
-		FromName:    os.Getenv("MAILER_FROM_NAME"),
-		FromAddress: os.Getenv("MAILER_FROM_ADDRESS"),
+		Host:        c.Host,
+		Port:        c.Port,
+		Username:    c.Username,
+		Password:    c.Password,
+		Encryption:  c.Encryption,
+		FromName:    c.FromName,
+		FromAddress: c.FromAddress,
 		Jobs:        make(chan mailer.Message, 20),
 		Results:     make(chan mailer.Result, 20),
-		API:         os.Getenv("MAILER_API"),
-		APIKey:      os.Getenv("MAILER_KEY"),
-		APIUrl:      os.Getenv("MAILER_URL"),
+		API:         c.API,
+		APIKey:      c.APIKey,
+		APIUrl:      c.APIURL,
 	}
 	return m
 }
@@ -245,6 +480,15 @@ func (a *Adele) BootstrapMiddleware() {
 		Log:              a.Log,
 		Session:          a.Session,
 		MaintenanceMode:  a.MaintenanceMode,
+		Rate:             a.config.HTTP.RateLimit,
+		Duration:         a.config.HTTP.RateDuration,
+	}
+
+	if !a.config.Health.Disabled {
+		myMiddleware.MaintenanceBypass = []string{health.LivenessPath, health.ReadinessPath}
+	}
+	if a.config.Metrics.Enabled {
+		myMiddleware.MaintenanceBypass = append(myMiddleware.MaintenanceBypass, a.config.Metrics.Path)
 	}
 
 	a.middleware = myMiddleware
@@ -256,24 +500,46 @@ func (a *Adele) BootstrapScheduler() {
 	a.Scheduler = cron.New()
 }
 
+// The table of the SQLite session store, as expected by scs/sqlite3store.
+const sqliteSessionsTable = `CREATE TABLE IF NOT EXISTS sessions (
+	token TEXT PRIMARY KEY,
+	data BLOB NOT NULL,
+	expiry REAL NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sessions_expiry_idx ON sessions(expiry);`
+
 // Configure and create the session manager by initializing a session struct, populating
-// its cookie fields by retrieving values from environment variables.
+// its cookie fields from the session section of the application configuration. SQLite
+// sessions are stored in the application database, creating the sessions table when it
+// does not exist; the error wraps ErrSessionUnavailable when the database is not SQLite.
 func (a *Adele) BootstrapSessionManager() (*scs.SessionManager, error) {
+	c := a.config.Session
 
 	session := session.Session{
-		CookieDomain:   Helpers.Getenv("COOKIE_DOMAIN", "localhost"),
-		CookieLifetime: Helpers.Getenv("COOKIE_LIFETIME", "1"),
-		CookieName:     Helpers.Getenv("COOKIE_NAME", "adele"),
-		CookiePersist:  Helpers.Getenv("COOKIE_PERSIST", "true"),
-		CookieSecure:   Helpers.Getenv("COOKIE_SECURE", "false"),
+		CookieDomain:   c.CookieDomain,
+		CookieLifetime: strconv.Itoa(c.CookieLifetime),
+		CookieName:     c.CookieName,
+		CookiePersist:  strconv.FormatBool(c.CookiePersist),
+		CookieSecure:   strconv.FormatBool(c.CookieSecure),
+		SessionType:    c.Type,
 	}
 
-	switch strings.ToLower(os.Getenv("SESSION_TYPE")) {
+	switch strings.ToLower(c.Type) {
 	case "redis":
 		//...
 
 	case "mysql", "postgres", "mariadb", "postgresql":
 		//...
+
+	case "sqlite", "sqlite3":
+		if a.DB == nil || a.DB.Pool == nil || database.Driver(a.DB.DataType) != "sqlite" {
+			return nil, fmt.Errorf("%w: SESSION_TYPE %s requires a SQLite database", ErrSessionUnavailable, c.Type)
+		}
+		if _, err := a.DB.Pool.Exec(sqliteSessionsTable); err != nil {
+			return nil, fmt.Errorf("%w: failed to create the sessions table: %w", ErrSessionUnavailable, err)
+		}
+		session.DBPool = a.DB.Pool
+
 	default:
 		a.Log.Warn("sessions using in-memory session store")
 	}
@@ -310,17 +576,23 @@ func (a *Adele) BootstrapMux(rootPath string) (http.Handler, error) {
 	// from a YAML file and returning it as a mux.Cors object.
 	configFile, err := os.ReadFile(fmt.Sprintf("%s/config/cors.yml", rootPath))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read cors config file: %v", err)
+		return nil, fmt.Errorf("%w: failed to read cors config file: %w", ErrRouterUnavailable, err)
 	}
 
 	var corsConfig mux.Cors
 	err = yaml.Unmarshal(configFile, &corsConfig)
 	if err != nil {
-		return nil, fmt.Errorf("failed to parse config file: %v", err)
+		return nil, fmt.Errorf("%w: failed to parse cors config file: %w", ErrRouterUnavailable, err)
 	}
 
 	mux := mux.NewRouter()
 	mux.Use(middleware.RequestID())
+	if len(a.config.Database.Replicas) > 0 {
+		mux.Use(database.TrackWritesHandler)
+	}
+	if a.tracksQueries() {
+		mux.Use(database.TrackQueriesHandler(a.reportQueries))
+	}
 	mux.Use(middleware.RealIP())
 	mux.Use(a.middleware.RateLimiter())
 
@@ -354,9 +626,9 @@ func (a *Adele) BootstrapMux(rootPath string) (http.Handler, error) {
 func (a *Adele) BootstrapRender() *render.Render {
 	r := render.Render{
 		Directory: a.ViewsTemplateDir,
-		Renderer:  a.config.renderer,
+		Renderer:  a.config.Renderer,
 		RootPath:  a.RootPath,
-		Port:      a.config.port,
+		Port:      a.config.HTTP.Port,
 		JetViews:  a.JetViews,
 		Session:   a.Session,
 	}
@@ -365,41 +637,366 @@ func (a *Adele) BootstrapRender() *render.Render {
 }
 
 // Cache initialization method that automatically detects and configures the appropriate
-// caching system during application startup based on environment variables.
+// caching system during application startup based on the application configuration. The
+// returned error wraps ErrCacheUnavailable.
 func (a *Adele) BootstrapCache(rootPath string) error {
-	if cache.UsesRedis() {
-		pool, err := redisdriver.CreateRedisPool(Helpers.Getenv("REDIS_MAX_IDEL", "50"), Helpers.Getenv("REDIS_MAX_ACTIVE_CONNECTIONS", "10000"), Helpers.Getenv("REDIS_TIMEOUT", "240"), Helpers.Getenv("REDIS_HOST", "localhost"), Helpers.Getenv("REDIS_PORT", "6380"))
+	c := a.config.Cache
+
+	codec, err := cache.CodecByName(c.Codec)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCacheUnavailable, err)
+	}
+
+	if a.usesRedis() {
+		opts, err := redisOptions(c.Redis)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %w", ErrCacheUnavailable, err)
 		}
 
 		rc := redisdriver.RedisCache{
-			Conn:   pool,
-			Prefix: Helpers.Getenv("REDIS_PREFIX", Helpers.Getenv("APP_NAME")),
+			Prefix: c.Redis.Prefix,
+			Codec:  codec,
+		}
+
+		if opts.Cluster {
+			rc.Cluster, err = redisdriver.NewCluster(opts)
+		} else {
+			rc.Conn, err = redisdriver.NewPool(opts)
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCacheUnavailable, err)
 		}
 
 		a.Cache = &rc
 
 	}
 
-	if cache.UsesBadger() {
+	if c.Driver == "badger" {
+
+		path := c.Badger.Path
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(rootPath, path)
+		}
+
+		pool, err := badgerdriver.Open(badgerdriver.Options{
+			Path:          path,
+			InMemory:      c.Badger.InMemory,
+			EncryptionKey: []byte(c.Badger.EncryptionKey),
+		})
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrCacheUnavailable, err)
+		}
 
 		bc := badgerdriver.BadgerCache{
-			Conn: badgerdriver.CreateBadgerPool(a.RootPath + "/resources/badger"),
+			Conn:           pool,
+			Codec:          codec,
+			GCDiscardRatio: c.Badger.GCDiscardRatio,
+			MaxSize:        c.Badger.MaxSize,
 		}
 
 		a.Cache = &bc
 
-		a.Scheduler.AddFunc("@daily", func() {
+		interval := c.Badger.GCInterval
+		if interval <= 0 {
+			interval = 24 * time.Hour
+		}
+
+		a.Scheduler.AddFunc("@every "+interval.String(), func() {
 			if err := badgerdriver.BadgerCacheClean(&bc); err != nil {
 				a.Log.Errorf("Badger cache cleanup failed: %v", err)
 			}
 		})
+
+		if a.Metrics != nil {
+			a.Metrics.GaugeFunc("adele_badger_lsm_size_bytes", "Size on disk of the LSM tree of the Badger cache.", nil, nil, func() float64 {
+				return float64(bc.Stats().LSMSize)
+			})
+			a.Metrics.GaugeFunc("adele_badger_vlog_size_bytes", "Size on disk of the value log of the Badger cache.", nil, nil, func() float64 {
+				return float64(bc.Stats().VlogSize)
+			})
+		}
+	}
+
+	if c.Driver == "database" {
+		if a.DB == nil || a.DB.Pool == nil {
+			return fmt.Errorf("%w: the database cache requires DATABASE_TYPE", ErrCacheUnavailable)
+		}
+
+		sc := sqldriver.New(a.DB)
+		sc.Codec = codec
+
+		if err := sc.Migrate(context.Background()); err != nil {
+			return fmt.Errorf("%w: %w", ErrCacheUnavailable, err)
+		}
+
+		a.Cache = sc
+
+		a.Scheduler.AddFunc("@every 5m", func() {
+			if _, err := sc.PurgeExpired(context.Background()); err != nil {
+				a.Log.Errorf("Database cache cleanup failed: %v", err)
+			}
+		})
+	}
+
+	if c.Driver == "memory" {
+		mc := memorydriver.New(c.Memory.MaxEntries, c.Memory.MaxBytes)
+		mc.Codec = codec
+
+		a.Cache = mc
+
+		a.Scheduler.AddFunc("@every 5m", mc.PurgeExpired)
+	}
+
+	if c.LocalTTL > 0 {
+		switch backend := a.Cache.(type) {
+		case *redisdriver.RedisCache, *badgerdriver.BadgerCache, *sqldriver.SQLCache:
+			l1 := memorydriver.New(c.Memory.MaxEntries, c.Memory.MaxBytes)
+			l1.Codec = codec
+
+			tc := tiereddriver.New(l1, backend.(tiereddriver.Backend), c.LocalTTL)
+
+			// Badger is embedded in a single process, so only Redis is shared with other
+			// instances.
+			if rc, ok := backend.(*redisdriver.RedisCache); ok {
+				channel := rc.Prefix + ":invalidate"
+
+				pool := rc.Conn
+				if rc.Cluster != nil {
+					pool = rc.Cluster.Pool(channel)
+				}
+
+				inv := redisdriver.NewInvalidator(pool, channel)
+				inv.OnError = func(err error) {
+					a.Log.Errorf("Cache invalidation failed: %v", err)
+				}
+				tc.Invalidator = inv
+				tc.Listen()
+			}
+
+			a.Cache = tc
+
+			a.Scheduler.AddFunc("@every 5m", l1.PurgeExpired)
+		}
+	}
+
+	if a.Metrics != nil && a.Cache != nil {
+		ic := cache.Instrument(a.Cache, c.Driver, a.Metrics)
+		if a.Debug {
+			ic.Log = a.Log
+		}
+
+		a.Cache = ic
 	}
 
 	return nil
 }
 
+// Return the options of the Redis connection, read from the URL when it is set.
+func redisOptions(c RedisConfig) (redisdriver.Options, error) {
+	opts := redisdriver.Options{
+		Addrs:    []string{net.JoinHostPort(c.Host, c.Port)},
+		Username: c.Username,
+		Password: c.Password,
+		DB:       c.DB,
+	}
+
+	if c.URL != "" {
+		var err error
+		if opts, err = redisdriver.ParseURL(c.URL); err != nil {
+			return opts, err
+		}
+	} else if c.TLS {
+		opts.TLS = &tls.Config{}
+	}
+
+	opts.MaxIdle = c.MaxIdle
+	opts.MaxActive = c.MaxActive
+	opts.IdleTimeout = c.IdleTimeout
+
+	return opts, nil
+}
+
+// Create the metrics registry and serve it on METRICS_PATH in the Prometheus text format
+// when metrics are enabled. The subsystems bootstrapped afterwards, e.g., the cache, record
+// their metrics in it and applications add their own. Example:
+//
+//	jobs := app.Metrics.Counter("app_jobs_total", "Jobs processed.", "queue")
+//	jobs.With("emails").Inc()
+func (a *Adele) BootstrapMetrics() error {
+	c := a.config.Metrics
+	if !c.Enabled {
+		return nil
+	}
+
+	a.Metrics = metrics.New()
+
+	if a.DB != nil && a.DB.Pool != nil {
+		a.databaseMetrics()
+	}
+
+	if a.Routes != nil {
+		a.Routes.Get(c.Path, a.Metrics.Handler())
+	}
+
+	return nil
+}
+
+// Configure the health checks for every subsystem the application uses and register the
+// liveness and readiness endpoints on the router. Both endpoints respond with a JSON
+// report and a 503 status when a check fails or the application is in maintenance mode.
+// The errors of the checks are only reported in debug mode.
+// Applications add their own checks to the registry. Example:
+//
+//	app.Health.Add("search", func(ctx context.Context) error {
+//	    return client.Ping(ctx)
+//	})
+func (a *Adele) BootstrapHealth() error {
+	c := a.config.Health
+	if c.Disabled {
+		return nil
+	}
+
+	registry := health.New(c.Timeout)
+	registry.Verbose = a.Debug
+
+	if a.DB != nil && a.DB.Pool != nil {
+		registry.Add("database", health.DB(a.DB.Pool), health.DBStats(a.DB.Pool))
+	}
+
+	backend := a.Cache
+	for {
+		w, ok := backend.(cache.Wrapper)
+		if !ok {
+			break
+		}
+		backend = w.Unwrap()
+	}
+
+	switch c := backend.(type) {
+	case *redisdriver.RedisCache:
+		registry.Add("redis", c.Ping)
+	case *badgerdriver.BadgerCache:
+		registry.Add("badger", health.Badger(c.Conn))
+	}
+
+	if m := a.config.Mail; m.Host != "" && (m.API == "" || m.API == "smtp") {
+		registry.Add("smtp", health.Dial(net.JoinHostPort(m.Host, strconv.Itoa(m.Port))))
+	}
+
+	fs := a.config.Filesystem
+	if fs.S3.Key != "" {
+		endpoint := fs.S3.Endpoint
+		if endpoint == "" {
+			endpoint = fmt.Sprintf("s3.%s.amazonaws.com", fs.S3.Region)
+		}
+		registry.Add("s3", health.Dial(hostPort(endpoint, "443")))
+	}
+	if fs.Minio.Secret != "" {
+		port := "80"
+		if fs.Minio.UseSSL {
+			port = "443"
+		}
+		registry.Add("minio", health.Dial(hostPort(fs.Minio.Endpoint, port)))
+	}
+	if fs.SFTP.Host != "" {
+		registry.Add("sftp", health.Dial(net.JoinHostPort(fs.SFTP.Host, fs.SFTP.Port)))
+	}
+	if fs.WebDAV.Host != "" {
+		registry.Add("webdav", health.Dial(hostPort(fs.WebDAV.Host, "80")))
+	}
+
+	a.Health = registry
+
+	inMaintenance := func() bool {
+		return a.MaintenanceMode
+	}
+
+	if a.Routes != nil {
+		a.Routes.Get(health.LivenessPath, registry.LivenessHandler(inMaintenance))
+		a.Routes.Get(health.ReadinessPath, registry.ReadinessHandler(inMaintenance))
+	}
+
+	return nil
+}
+
+// Return the host and port of an address that may be a URL or a host without a port. The
+// port defaults to the scheme of a URL, i.e., 443 for https, or the default port.
+func hostPort(address, defaultPort string) string {
+	if u, err := url.Parse(address); err == nil && u.Host != "" {
+		if u.Scheme == "https" {
+			defaultPort = "443"
+		}
+		address = u.Host
+	}
+
+	if _, _, err := net.SplitHostPort(address); err == nil {
+		return address
+	}
+
+	return net.JoinHostPort(address, defaultPort)
+}
+
+// Return the application cache as a context aware Store for use with cache.GetAs and
+// cache.SetAs, or nil when no cache is configured. Example:
+//
+//	user, err := cache.GetAs[User](ctx, app.CacheStore(), "user:42")
+func (a *Adele) CacheStore() cache.Store {
+	if a.Cache == nil {
+		return nil
+	}
+	return cache.Adapt(a.Cache)
+}
+
+// Return a middleware caching whole responses to GET and HEAD requests in the application
+// cache for the TTL, or for the TTL of the route given by its cache annotation. Middleware
+// is added to a router before its routes, so use it on a router mounted on the
+// application routes. Example:
+//
+//	blog := mux.NewRouter()
+//	blog.Use(app.ResponseCache(time.Minute).Handler)
+//	blog.Get("/posts[cache:5m]", handlers.Posts)
+//	app.Routes.Mount("/blog", blog)
+func (a *Adele) ResponseCache(ttl time.Duration) *middleware.ResponseCache {
+	rc := middleware.NewResponseCache(a.CacheStore(), ttl)
+	rc.Log = a.Log
+
+	if a.Routes != nil {
+		rc.RouteTTL = a.Routes.CacheTTL
+	}
+
+	return rc
+}
+
+// Return a migrator applying the migration files in the migrations directory of the
+// application, and the migrations written in Go, to the application database. Example:
+//
+//	if err := app.Migrator().Up(ctx); err != nil {
+//	    return err
+//	}
+func (a *Adele) Migrator(migrations ...database.Migration) *database.Migrator {
+	return &database.Migrator{
+		DB:         a.DB,
+		FS:         os.DirFS(filepath.Join(a.RootPath, "migrations")),
+		Migrations: migrations,
+	}
+}
+
+// Run the named seeders registered with the seed package, or every seeder when no name
+// is given, against the application database. Example:
+//
+//	if err := app.Seed(ctx, "users", "posts"); err != nil {
+//	    return err
+//	}
+func (a *Adele) Seed(ctx context.Context, names ...string) error {
+	return seed.Run(ctx, a.DB, names...)
+}
+
+// Checks if any framework service is configured to use Redis—the cache or the session
+// store.
+func (a *Adele) usesRedis() bool {
+	return a.config.Cache.Driver == "redis" || a.config.Session.Type == "redis"
+}
+
 // Ensure that a environment file at a specific path exists, creating it if it's missing, and returning
 // any errors that may arise.
 func (a *Adele) CreateEnvironmentFile(rootPath string) error {
@@ -0,0 +1,445 @@
+package adele
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/cache/badgerdriver"
+	"github.com/cidekar/adele-framework/cache/memorydriver"
+	"github.com/cidekar/adele-framework/cache/tiereddriver"
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/health"
+	"github.com/cidekar/adele-framework/metrics"
+	"github.com/cidekar/adele-framework/mux"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+// Create an application root holding the files the framework reads at bootstrap.
+func testRoot(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config", "cors.yml"), []byte("allowedOrigins:\n  - '*'\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+func TestNewWithConfig_DatabaseUnavailable(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Database = DatabaseConfig{
+		Type:           "mysql",
+		Host:           "127.0.0.1",
+		Port:           "1",
+		User:           "adele",
+		Name:           "adele",
+		ConnectRetries: 1,
+		ConnectBackoff: time.Millisecond,
+	}
+
+	_, err := NewWithConfig(testRoot(t), cfg)
+	if !errors.Is(err, ErrDatabaseUnavailable) {
+		t.Fatalf("expected ErrDatabaseUnavailable, got %v", err)
+	}
+}
+
+func TestNewWithConfig_Sqlite(t *testing.T) {
+	root := testRoot(t)
+
+	cfg := ConfigFromEnv()
+	cfg.Database = DatabaseConfig{Type: "sqlite", Name: "app.db"}
+	cfg.Cache = CacheConfig{Driver: "database", Codec: "json"}
+
+	a, err := NewWithConfig(root, cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer a.Shutdown(context.Background())
+
+	if _, err := os.Stat(filepath.Join(root, "app.db")); err != nil {
+		t.Errorf("database file was not created in the root: %v", err)
+	}
+
+	if err := a.Cache.Set("greeting", "hello", 60); err != nil {
+		t.Fatalf("Set() error = %v", err)
+	}
+	if got, err := a.Cache.Get("greeting"); err != nil || got != "hello" {
+		t.Errorf("Get() = %v, %v; want hello", got, err)
+	}
+}
+
+func TestNewWithConfig_SqliteSessions(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{}
+	cfg.Session.Type = "sqlite"
+
+	_, err := NewWithConfig(testRoot(t), cfg)
+	if !errors.Is(err, ErrSessionUnavailable) {
+		t.Fatalf("expected ErrSessionUnavailable without a SQLite database, got %v", err)
+	}
+
+	cfg.Database = DatabaseConfig{Type: "sqlite", Name: "app.db"}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer a.Shutdown(context.Background())
+
+	handler := a.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a.Session.Put(r.Context(), "user", "adele")
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	var sessions int
+	if err := a.DB.Pool.QueryRow("SELECT COUNT(*) FROM sessions").Scan(&sessions); err != nil {
+		t.Fatal(err)
+	}
+	if sessions != 1 {
+		t.Errorf("stored sessions = %d, want 1", sessions)
+	}
+}
+
+func TestNewWithConfig_RouterUnavailable(t *testing.T) {
+	_, err := NewWithConfig(t.TempDir(), ConfigFromEnv())
+	if !errors.Is(err, ErrRouterUnavailable) {
+		t.Fatalf("expected ErrRouterUnavailable, got %v", err)
+	}
+}
+
+func TestNewWithConfig_CacheUnavailable(t *testing.T) {
+	root := testRoot(t)
+
+	// A file where the badger directory should be cannot be opened as a database.
+	if err := os.WriteFile(filepath.Join(root, "badger"), []byte("not a directory"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "badger", Badger: BadgerConfig{Path: "badger"}}
+
+	_, err := NewWithConfig(root, cfg)
+	if !errors.Is(err, ErrCacheUnavailable) {
+		t.Fatalf("expected ErrCacheUnavailable, got %v", err)
+	}
+
+	// The database cache cannot be used without a database.
+	cfg.Cache = CacheConfig{Driver: "database"}
+
+	_, err = NewWithConfig(testRoot(t), cfg)
+	if !errors.Is(err, ErrCacheUnavailable) {
+		t.Fatalf("expected ErrCacheUnavailable, got %v", err)
+	}
+}
+
+func TestBootstrapHealth(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "badger", Badger: BadgerConfig{Path: "badger"}}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer a.Shutdown(context.Background())
+
+	ts := httptest.NewServer(a.Routes)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + health.ReadinessPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var report health.Report
+	json.NewDecoder(res.Body).Decode(&report)
+	res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		t.Errorf("readiness code = %d, want 200", res.StatusCode)
+	}
+	if report.Checks["badger"].Status != health.StatusUp {
+		t.Errorf("badger check = %+v, want up", report.Checks["badger"])
+	}
+
+	a.MaintenanceMode = true
+
+	for _, path := range []string{health.LivenessPath, health.ReadinessPath} {
+		res, err = http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("%s code in maintenance mode = %d, want 503", path, res.StatusCode)
+		}
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+	}{
+		{"localhost:9000", "localhost:9000"},
+		{"minio.example.com", "minio.example.com:80"},
+		{"https://dav.example.com/remote.php", "dav.example.com:443"},
+		{"http://dav.example.com:8080", "dav.example.com:8080"},
+	}
+
+	for _, tt := range tests {
+		if got := hostPort(tt.address, "80"); got != tt.want {
+			t.Errorf("hostPort(%q) = %q, want %q", tt.address, got, tt.want)
+		}
+	}
+}
+
+func TestBootstrapCache_Memory(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "memory", Codec: "gob", Memory: MemoryConfig{MaxEntries: 10}}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	mc, ok := a.Cache.(*memorydriver.MemoryCache)
+	if !ok {
+		t.Fatalf("Cache = %T, want *memorydriver.MemoryCache", a.Cache)
+	}
+
+	if mc.MaxEntries != 10 || mc.ValueCodec() != cache.Gob {
+		t.Errorf("memory cache not configured: %+v", mc)
+	}
+}
+
+func TestBootstrapCache_LocalTTL(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "badger", Codec: "json", LocalTTL: time.Second, Badger: BadgerConfig{Path: "badger"}}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	tc, ok := a.Cache.(*tiereddriver.TieredCache)
+	if !ok {
+		t.Fatalf("Cache = %T, want *tiereddriver.TieredCache", a.Cache)
+	}
+	defer tc.Close()
+
+	if _, ok := tc.L2.(*badgerdriver.BadgerCache); !ok || tc.TTL != time.Second {
+		t.Errorf("tiered cache not configured: %+v", tc)
+	}
+}
+
+func TestBootstrapMetrics(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "memory", Codec: "json"}
+	cfg.Metrics = MetricsConfig{Enabled: true, Path: "/metrics"}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	if _, ok := a.Cache.(*cache.Instrumented); !ok {
+		t.Fatalf("Cache = %T, want *cache.Instrumented", a.Cache)
+	}
+
+	a.Cache.Set("user", "adele")
+	a.Cache.Get("user")
+
+	ts := httptest.NewServer(a.Routes)
+	defer ts.Close()
+
+	res, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+
+	if want := `adele_cache_hits_total{cache="memory"} 1`; !strings.Contains(string(body), want) {
+		t.Errorf("metrics do not contain %s:\n%s", want, body)
+	}
+}
+
+func TestBootstrapCache_BadgerInMemory(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "badger", Codec: "json", Badger: BadgerConfig{InMemory: true, MaxSize: 1 << 20}}
+	cfg.Metrics = MetricsConfig{Enabled: true, Path: "/metrics"}
+
+	root := testRoot(t)
+	a, err := NewWithConfig(root, cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer a.Cache.(io.Closer).Close()
+
+	bc, ok := a.Cache.(cache.Wrapper).Unwrap().(*badgerdriver.BadgerCache)
+	if !ok {
+		t.Fatalf("Cache wraps %T, want *badgerdriver.BadgerCache", a.Cache.(cache.Wrapper).Unwrap())
+	}
+	if bc.MaxSize != 1<<20 || bc.GCDiscardRatio != 0 {
+		t.Errorf("badger cache not configured: %+v", bc)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "badger")); !os.IsNotExist(err) {
+		t.Errorf("an in-memory badger cache should not write to disk: %v", err)
+	}
+
+	var body strings.Builder
+	a.Metrics.WriteText(&body)
+	if !strings.Contains(body.String(), "adele_badger_vlog_size_bytes") {
+		t.Errorf("metrics do not report the badger size:\n%s", body.String())
+	}
+}
+
+func TestResponseCache(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{Driver: "memory", Codec: "json"}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	calls := 0
+	blog := mux.NewRouter()
+	blog.Use(a.ResponseCache(0).Handler)
+	blog.Get("/posts[cache:5m]", func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Write([]byte("posts"))
+	})
+	a.Routes.Mount("/blog", blog)
+
+	for i := 0; i < 2; i++ {
+		w := httptest.NewRecorder()
+		a.Routes.ServeHTTP(w, httptest.NewRequest("GET", "/blog/posts", nil))
+		if w.Body.String() != "posts" {
+			t.Fatalf("response = %d %q", w.Code, w.Body.String())
+		}
+	}
+
+	if calls != 1 {
+		t.Errorf("handler called %d times, want 1", calls)
+	}
+}
+
+func TestMigrator_NoDatabase(t *testing.T) {
+	a, err := NewWithConfig(testRoot(t), ConfigFromEnv())
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	if err := a.Migrator().Up(context.Background()); err == nil {
+		t.Error("Up() error = nil, want an error without a database")
+	}
+}
+
+func TestSeed_NoDatabase(t *testing.T) {
+	a, err := NewWithConfig(testRoot(t), ConfigFromEnv())
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+
+	if err := a.Seed(context.Background()); err == nil {
+		t.Error("Seed() error = nil, want an error without a database")
+	}
+}
+
+func TestDatabaseMetrics(t *testing.T) {
+	pool, err := sql.Open("pgx", "postgres://localhost/adele")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	pool.SetMaxOpenConns(7)
+
+	a := &Adele{
+		DB:      &database.Database{DataType: "postgres", Pool: pool},
+		Metrics: metrics.New(),
+	}
+	a.databaseMetrics()
+
+	var b strings.Builder
+	if err := a.Metrics.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{"adele_db_max_open_connections 7", "adele_db_in_use_connections 0", "adele_db_wait_count 0"} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, b.String())
+		}
+	}
+}
+
+func TestQueryLogging(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Debug = true
+	cfg.Database = DatabaseConfig{Type: "sqlite", Name: "app.db", LogQueries: true, RepeatedQueryWarning: 3}
+
+	a, err := NewWithConfig(testRoot(t), cfg)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer a.Shutdown(context.Background())
+
+	log, hook := logtest.NewNullLogger()
+	log.SetLevel(logrus.DebugLevel)
+	a.Log = log
+
+	if _, err := a.DB.Pool.Exec("CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	a.Routes.Get("/posts", func(w http.ResponseWriter, r *http.Request) {
+		posts := a.DB.Session(r.Context()).Collection("posts")
+		for _, title := range []string{"One", "Two", "Three"} {
+			if _, err := posts.Insert(map[string]string{"title": title}); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	hook.Reset()
+
+	a.Routes.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/posts", nil))
+
+	var logged, repeated, counted bool
+	for _, entry := range hook.AllEntries() {
+		switch {
+		case entry.Message == "query" && strings.HasPrefix(entry.Data["sql"].(string), `INSERT INTO "posts"`):
+			args, _ := entry.Data["args"].([]interface{})
+			logged = entry.Data["req_id"] != nil && len(args) == 1 && args[0] == "[redacted]"
+		case strings.HasPrefix(entry.Message, "possible N+1 query"):
+			repeated = entry.Level == logrus.WarnLevel && entry.Data["req_id"] != nil
+		case strings.HasPrefix(entry.Message, "request ran"):
+			counted = true
+		}
+	}
+
+	if !logged {
+		t.Error("statement was not logged with the request ID and redacted arguments")
+	}
+	if !repeated {
+		t.Error("repeated statement was not warned of")
+	}
+	if !counted {
+		t.Error("number of statements of the request was not logged")
+	}
+}
@@ -1,6 +1,8 @@
 package badgerdriver
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/cidekar/adele-framework/cache"
@@ -10,14 +12,17 @@ import (
 type BadgerCache struct {
 	Conn   *badger.DB
 	Prefix string
+	Codec  cache.Codec
+
+	// The fraction of a value log file that must be stale for Clean to rewrite the file,
+	// DefaultGCDiscardRatio unless set, and the size on disk above which Clean evicts the
+	// entries expiring first. A MaxSize of zero disables the limit.
+	GCDiscardRatio float64
+	MaxSize        int64
 }
 
 func (b *BadgerCache) Has(str string) (bool, error) {
-	_, err := b.Get(str)
-	if err != nil {
-		return false, nil
-	}
-	return true, nil
+	return b.HasContext(context.Background(), str)
 }
 
 func (b *BadgerCache) Get(str string) (interface{}, error) {
@@ -75,7 +80,7 @@ func (b *BadgerCache) Set(str string, value interface{}, expires ...int) error {
 		})
 	}
 
-	return nil
+	return err
 }
 
 func (b *BadgerCache) Forget(str string) error {
@@ -145,15 +150,81 @@ func (b *BadgerCache) emptyByMatch(str string) error {
 	return err
 }
 
-func CreateBadgerPool(storagePath string) *badger.DB {
-	pool, err := badger.Open(badger.DefaultOptions(storagePath).WithLogger(nil))
-	if err != nil {
+// Get the encoded value stored under the key, returning cache.ErrMiss when the key is
+// not in the cache.
+func (b *BadgerCache) GetContext(ctx context.Context, str string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		item, err := txn.Get([]byte(str))
+		if err != nil {
+			return err
+		}
+		value, err = item.ValueCopy(nil)
+		return err
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return nil, cache.ErrMiss
+	}
+
+	return value, err
+}
+
+// Store the encoded value under the key. A TTL of zero stores the value without expiry.
+func (b *BadgerCache) SetContext(ctx context.Context, str string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		e := badger.NewEntry([]byte(str), value)
+		if ttl > 0 {
+			e = e.WithTTL(ttl)
+		}
+		return txn.SetEntry(e)
+	})
+}
+
+func (b *BadgerCache) HasContext(ctx context.Context, str string) (bool, error) {
+	_, err := b.GetContext(ctx, str)
+	if errors.Is(err, cache.ErrMiss) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (b *BadgerCache) ForgetContext(ctx context.Context, str string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return b.Forget(str)
+}
+
+// Return the codec values are encoded with, JSON unless set.
+func (b *BadgerCache) ValueCodec() cache.Codec {
+	if b.Codec == nil {
+		return cache.JSON
+	}
+	return b.Codec
+}
+
+// Close the database, flushing pending writes to disk.
+func (b *BadgerCache) Close() error {
+	if b.Conn == nil {
 		return nil
 	}
-	return pool
+	return b.Conn.Close()
+}
+
+// Open the Badger database at the storage path, creating it when it does not exist.
+func CreateBadgerPool(storagePath string) (*badger.DB, error) {
+	return Open(Options{Path: storagePath})
 }
 
 // Performance optimization to keep the Badger database size under control by cleaning up deleted data
 func BadgerCacheClean(cache *BadgerCache) error {
-	return cache.Conn.RunValueLogGC(0.7)
+	return cache.Clean()
 }
@@ -1,6 +1,15 @@
 package badgerdriver
 
-import "testing"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+)
 
 func TestBadgerCache_Has(t *testing.T) {
 	err := testBadgerCache.Forget("foo")
@@ -140,3 +149,255 @@ func TestBadgerCache_EmptyByMatch(t *testing.T) {
 		t.Error("beta not found in cache, and it should be there")
 	}
 }
+
+func TestBadgerCache_TypedValues(t *testing.T) {
+	type user struct {
+		ID   int
+		Name string
+	}
+
+	ctx := context.Background()
+
+	for _, codec := range []cache.Codec{cache.JSON, cache.Gob, cache.Msgpack} {
+		testBadgerCache.Codec = codec
+
+		if err := cache.SetAs(ctx, &testBadgerCache, "user", user{ID: 42, Name: "Ada"}, time.Minute); err != nil {
+			t.Fatal(err)
+		}
+
+		u, err := cache.GetAs[user](ctx, &testBadgerCache, "user")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if u.ID != 42 || u.Name != "Ada" {
+			t.Errorf("%T: got %+v", codec, u)
+		}
+	}
+
+	testBadgerCache.Codec = nil
+
+	if err := testBadgerCache.ForgetContext(ctx, "user"); err != nil {
+		t.Error(err)
+	}
+
+	if _, err := testBadgerCache.GetContext(ctx, "user"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected cache.ErrMiss, got %v", err)
+	}
+}
+
+func TestBadgerCache_Tags(t *testing.T) {
+	ctx := context.Background()
+
+	if err := testBadgerCache.Tags("user:42", "orders").Set("orders:42", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBadgerCache.Tags("user:42").Set("profile:42", "profile", 60); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetAs(ctx, testBadgerCache.Tags("orders"), "orders:7", 7, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := testBadgerCache.Set("untagged", "value"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := testBadgerCache.FlushTags(ctx, "user:42"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"orders:42": false, "profile:42": false, "orders:7": true, "untagged": true} {
+		if got, _ := testBadgerCache.Has(key); got != want {
+			t.Errorf("Has(%s) = %v, want %v", key, got, want)
+		}
+	}
+
+	if err := testBadgerCache.Tags("orders").Flush(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, _ := testBadgerCache.Has("orders:7"); got {
+		t.Error("orders:7 should have been flushed")
+	}
+
+	testBadgerCache.Forget("untagged")
+}
+
+func TestBadgerCache_FlushTagsInBatches(t *testing.T) {
+	ctx := context.Background()
+
+	for i := 0; i < flushBatchSize+10; i++ {
+		if err := testBadgerCache.Tags("bulk").Set(fmt.Sprintf("bulk:%d", i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := testBadgerCache.FlushTags(ctx, "bulk"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, i := range []int{0, flushBatchSize + 9} {
+		if got, _ := testBadgerCache.Has(fmt.Sprintf("bulk:%d", i)); got {
+			t.Errorf("bulk:%d should have been flushed", i)
+		}
+	}
+}
+
+func TestBadgerCache_Counter(t *testing.T) {
+	ctx := context.Background()
+
+	if n, err := testBadgerCache.Increment(ctx, "hits", 5, time.Minute); err != nil || n != 5 {
+		t.Errorf("Increment() = %d, %v; want 5", n, err)
+	}
+	if n, err := testBadgerCache.Decrement(ctx, "hits", 2, time.Minute); err != nil || n != 3 {
+		t.Errorf("Decrement() = %d, %v; want 3", n, err)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := testBadgerCache.Increment(ctx, "hits", 1, 0); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := testBadgerCache.Increment(ctx, "hits", 0, 0); n != 23 {
+		t.Errorf("counter after concurrent increments = %d, want 23", n)
+	}
+
+	testBadgerCache.Forget("hits")
+}
+
+func TestBadgerCache_Lock(t *testing.T) {
+	ctx := context.Background()
+
+	lock, err := testBadgerCache.Lock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := testBadgerCache.Lock(ctx, "job", time.Minute); !errors.Is(err, cache.ErrNotAcquired) {
+		t.Errorf("expected cache.ErrNotAcquired, got %v", err)
+	}
+
+	if err := lock.Extend(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lock.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := lock.Release(ctx); !errors.Is(err, cache.ErrNotHeld) {
+		t.Errorf("expected cache.ErrNotHeld, got %v", err)
+	}
+
+	// A lock without a TTL is held until it is released.
+	for _, extend := range []bool{false, true} {
+		lock, err := testBadgerCache.Lock(ctx, "job", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if extend {
+			if err := lock.Extend(ctx, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if _, err := testBadgerCache.Lock(ctx, "job", time.Minute); !errors.Is(err, cache.ErrNotAcquired) {
+			t.Errorf("expected cache.ErrNotAcquired for a lock without a TTL, got %v", err)
+		}
+		if err := lock.Release(ctx); err != nil {
+			t.Errorf("Release() of a lock without a TTL error = %v", err)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if _, err := Open(Options{InMemory: true, EncryptionKey: []byte("short")}); err == nil {
+		t.Error("expected an error opening with a key of the wrong length")
+	}
+
+	path := t.TempDir()
+	key := []byte("0123456789abcdef0123456789abcdef")
+
+	db, err := Open(Options{Path: path, EncryptionKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := BadgerCache{Conn: db}
+	bc.Set("foo", "bar")
+	bc.Close()
+
+	if db, err := Open(Options{Path: path, EncryptionKey: []byte("fedcba9876543210fedcba9876543210")}); err == nil {
+		db.Close()
+		t.Error("expected an error opening an encrypted database with another key")
+	}
+
+	db, err = Open(Options{Path: path, EncryptionKey: key})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc = BadgerCache{Conn: db}
+	defer bc.Close()
+
+	if x, err := bc.Get("foo"); err != nil || x != "bar" {
+		t.Errorf("Get() = %v, %v; want bar", x, err)
+	}
+}
+
+func TestBadgerCache_Evict(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := Open(Options{InMemory: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := BadgerCache{Conn: db}
+	defer bc.Close()
+
+	bc.SetContext(ctx, "forever", []byte("value"), 0)
+	bc.SetContext(ctx, "hour", []byte("value"), time.Hour)
+	bc.SetContext(ctx, "minute", []byte("value"), time.Minute)
+	bc.Tags("user").Set("tagged", "value")
+
+	lock, err := bc.Lock(ctx, "job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lock.Release(ctx)
+
+	// Any size removes at least one entry, the one expiring first.
+	removed, err := bc.evict(1)
+	if err != nil || removed != 1 {
+		t.Fatalf("evict() = %d, %v; want 1", removed, err)
+	}
+
+	for key, want := range map[string]bool{"minute": false, "hour": true, "forever": true, "tagged": true} {
+		if got, _ := bc.HasContext(ctx, key); got != want {
+			t.Errorf("Has(%s) = %v, want %v", key, got, want)
+		}
+	}
+
+	if _, err := bc.evict(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"hour", "forever", "tagged"} {
+		if got, _ := bc.HasContext(ctx, key); got {
+			t.Errorf("%s should have been evicted", key)
+		}
+	}
+
+	if err := lock.Extend(ctx, time.Second); err != nil {
+		t.Errorf("a lock should never be evicted: %v", err)
+	}
+
+	if err := bc.Clean(); err != nil {
+		t.Errorf("Clean() of an in-memory database error = %v", err)
+	}
+}
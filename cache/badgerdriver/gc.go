@@ -0,0 +1,140 @@
+package badgerdriver
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// The fraction of a value log file that must be stale for the file to be rewritten when
+// the GCDiscardRatio of the cache is not set.
+const DefaultGCDiscardRatio = 0.7
+
+// Return the size on disk of the database.
+func (b *BadgerCache) Stats() Stats {
+	lsm, vlog := b.Conn.Size()
+	return Stats{LSMSize: lsm, VlogSize: vlog}
+}
+
+// Keep the size of the database under control: evict the entries expiring first while
+// the database is larger than MaxSize, then rewrite the value log files until none holds
+// enough stale data to be worth rewriting. Meant to run on a schedule. Example:
+//
+//	a.Scheduler.AddFunc("@every 1h", func() {
+//	    if err := bc.Clean(); err != nil {
+//	        a.Log.Error(err)
+//	    }
+//	})
+func (b *BadgerCache) Clean() error {
+	if _, err := b.Evict(); err != nil {
+		return err
+	}
+
+	ratio := b.GCDiscardRatio
+	if ratio <= 0 {
+		ratio = DefaultGCDiscardRatio
+	}
+
+	for {
+		err := b.Conn.RunValueLogGC(ratio)
+		switch {
+		case err == nil:
+			continue
+		case errors.Is(err, badger.ErrNoRewrite), errors.Is(err, badger.ErrGCInMemoryMode), errors.Is(err, badger.ErrRejected):
+			// Nothing left to rewrite, nothing on disk, or another collection is running.
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// Remove the entries expiring first, the entries without expiry last, until the entries
+// removed add up to the amount the database is larger than MaxSize, returning the number
+// of entries removed. The space is returned to the file system once the value log is
+// collected by Clean. Locks and tag indexes are never evicted.
+func (b *BadgerCache) Evict() (int, error) {
+	if b.MaxSize <= 0 {
+		return 0, nil
+	}
+
+	stats := b.Stats()
+	excess := stats.LSMSize + stats.VlogSize - b.MaxSize
+	if excess <= 0 {
+		return 0, nil
+	}
+
+	return b.evict(excess)
+}
+
+// Remove the entries expiring first until at least size bytes were removed.
+func (b *BadgerCache) evict(size int64) (int, error) {
+	type candidate struct {
+		key     []byte
+		expires uint64
+		size    int64
+	}
+
+	var candidates []candidate
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Rewind(); it.Valid(); it.Next() {
+			item := it.Item()
+			if internal(item.Key()) {
+				continue
+			}
+
+			expires := item.ExpiresAt()
+			if expires == 0 {
+				expires = math.MaxUint64
+			}
+			candidates = append(candidates, candidate{key: item.KeyCopy(nil), expires: expires, size: item.EstimatedSize()})
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].expires < candidates[j].expires
+	})
+
+	var keys [][]byte
+	for _, c := range candidates {
+		if size <= 0 {
+			break
+		}
+		keys = append(keys, c.key)
+		size -= c.size
+	}
+
+	for start := 0; start < len(keys); start += flushBatchSize {
+		batch := keys[start:min(start+flushBatchSize, len(keys))]
+		err := b.update(func(txn *badger.Txn) error {
+			for _, key := range batch {
+				if err := txn.Delete(key); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return start, err
+		}
+	}
+
+	return len(keys), nil
+}
+
+// Report whether the key is kept by the driver itself rather than stored by a caller.
+func internal(key []byte) bool {
+	return bytes.HasPrefix(key, []byte(tagPrefix)) || bytes.HasPrefix(key, []byte(lockPrefix))
+}
@@ -0,0 +1,158 @@
+package badgerdriver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// Prefix of the keys holding the token of a lock.
+const lockPrefix = "\x00lock\x00"
+
+// A lock held on a key of the cache. The token identifies the holder so a lock that
+// expired and was taken by someone else is not released.
+type badgerLock struct {
+	cache *BadgerCache
+	key   []byte
+	token []byte
+}
+
+// Take a lock on the key, held until it is released or expires after the TTL; a TTL of
+// zero holds the lock until it is released. The error is cache.ErrNotAcquired when the
+// key is locked by someone else. Badger is not shared between processes, so the lock
+// only excludes the other holders within the process.
+func (b *BadgerCache) Lock(ctx context.Context, str string, ttl time.Duration) (cache.Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	l := &badgerLock{cache: b, key: []byte(lockPrefix + str), token: []byte(hex.EncodeToString(token))}
+
+	err := b.update(func(txn *badger.Txn) error {
+		_, err := txn.Get(l.key)
+		if err == nil {
+			return cache.ErrNotAcquired
+		}
+		if !errors.Is(err, badger.ErrKeyNotFound) {
+			return err
+		}
+		return txn.SetEntry(lockEntry(l.key, l.token, ttl))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Add the delta to the counter held by the key. The counter is read and written in one
+// transaction, retried when a concurrent transaction changed it. Example:
+//
+//	hits, err := bc.Increment(ctx, "hits:"+ip, 1, time.Minute)
+func (b *BadgerCache) Increment(ctx context.Context, str string, delta int64, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	var value int64
+	err := b.update(func(txn *badger.Txn) error {
+		value = delta
+
+		e := badger.NewEntry([]byte(str), nil)
+		if ttl > 0 {
+			e = e.WithTTL(ttl)
+		}
+
+		item, err := txn.Get([]byte(str))
+		switch {
+		case err == nil:
+			current, err := item.ValueCopy(nil)
+			if err != nil {
+				return err
+			}
+			n, err := strconv.ParseInt(string(current), 10, 64)
+			if err != nil {
+				return fmt.Errorf("cache key %s does not hold a counter: %w", str, err)
+			}
+			value += n
+			if expires := item.ExpiresAt(); expires > 0 {
+				e.ExpiresAt = expires
+			}
+		case !errors.Is(err, badger.ErrKeyNotFound):
+			return err
+		}
+
+		e.Value = []byte(strconv.FormatInt(value, 10))
+		return txn.SetEntry(e)
+	})
+
+	return value, err
+}
+
+// Subtract the delta from the counter held by the key.
+func (b *BadgerCache) Decrement(ctx context.Context, str string, delta int64, ttl time.Duration) (int64, error) {
+	return b.Increment(ctx, str, -delta, ttl)
+}
+
+// Release the lock when it is still held.
+func (l *badgerLock) Release(ctx context.Context) error {
+	return l.update(ctx, func(txn *badger.Txn) error {
+		return txn.Delete(l.key)
+	})
+}
+
+// Reset the TTL of the lock when it is still held; a TTL of zero removes the expiry.
+func (l *badgerLock) Extend(ctx context.Context, ttl time.Duration) error {
+	return l.update(ctx, func(txn *badger.Txn) error {
+		return txn.SetEntry(lockEntry(l.key, l.token, ttl))
+	})
+}
+
+// Create the entry of a lock expiring after the TTL, or never when the TTL is zero.
+func lockEntry(key, token []byte, ttl time.Duration) *badger.Entry {
+	e := badger.NewEntry(key, token)
+	if ttl > 0 {
+		e = e.WithTTL(ttl)
+	}
+	return e
+}
+
+// Run the function in a transaction once the key is known to still hold the token of
+// the lock, returning cache.ErrNotHeld when it does not.
+func (l *badgerLock) update(ctx context.Context, fn func(txn *badger.Txn) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return l.cache.update(func(txn *badger.Txn) error {
+		item, err := txn.Get(l.key)
+		if errors.Is(err, badger.ErrKeyNotFound) {
+			return cache.ErrNotHeld
+		}
+		if err != nil {
+			return err
+		}
+
+		token, err := item.ValueCopy(nil)
+		if err != nil {
+			return err
+		}
+		if string(token) != string(l.token) {
+			return cache.ErrNotHeld
+		}
+
+		return fn(txn)
+	})
+}
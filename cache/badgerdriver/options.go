@@ -0,0 +1,44 @@
+package badgerdriver
+
+import (
+	"fmt"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// The memory given to the index of the tables of an encrypted database, which Badger
+// requires to decrypt the index once rather than on every read.
+const encryptedIndexCacheSize = 64 << 20
+
+// Open the Badger database described by the options, creating it when it does not
+// exist. Example:
+//
+//	db, err := badgerdriver.Open(badgerdriver.Options{
+//	    Path:          "resources/badger",
+//	    EncryptionKey: []byte(os.Getenv("BADGER_ENCRYPTION_KEY")),
+//	})
+func Open(opts Options) (*badger.DB, error) {
+	switch len(opts.EncryptionKey) {
+	case 0, 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("badger encryption key must be 16, 24 or 32 bytes; got %d", len(opts.EncryptionKey))
+	}
+
+	o := badger.DefaultOptions(opts.Path).WithLogger(nil)
+	if opts.InMemory {
+		o = badger.DefaultOptions("").WithInMemory(true).WithLogger(nil)
+	}
+	if len(opts.EncryptionKey) > 0 {
+		o = o.WithEncryptionKey(opts.EncryptionKey).WithIndexCacheSize(encryptedIndexCacheSize)
+	}
+
+	db, err := badger.Open(o)
+	if err != nil {
+		if opts.InMemory {
+			return nil, fmt.Errorf("failed to open in-memory badger database: %w", err)
+		}
+		return nil, fmt.Errorf("failed to open badger database at %s: %w", opts.Path, err)
+	}
+
+	return db, nil
+}
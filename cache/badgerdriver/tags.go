@@ -0,0 +1,149 @@
+package badgerdriver
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/dgraph-io/badger/v3"
+)
+
+// Prefix of the index keys recording the keys stored with a tag. The index key of a tag
+// and a key is the prefix, the tag, a zero byte and the key.
+const tagPrefix = "\x00tag\x00"
+
+// The number of entries removed by a single transaction when tags are flushed.
+const flushBatchSize = 1000
+
+// The number of times a transaction that conflicts with a concurrent one is retried and
+// the longest wait before a retry; the wait is random so contending writers spread out.
+const (
+	conflictRetries = 50
+	conflictBackoff = time.Millisecond
+)
+
+// Create a cache storing every entry with the tags. Example:
+//
+//	err := bc.Tags("user:42", "orders").Set("orders:user:42", orders, 3600)
+func (b *BadgerCache) Tags(tags ...string) *cache.TaggedCache {
+	return cache.NewTagged(b, tags...)
+}
+
+// Store the value under the key like Set, recording the key in the index of each tag.
+func (b *BadgerCache) SetTagged(str string, value interface{}, tags []string, expires ...int) error {
+	entry := cache.Entry{}
+	entry[str] = value
+
+	encoded, err := cache.Encode(entry)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if len(expires) > 0 {
+		ttl = time.Duration(expires[0]) * time.Second
+	}
+
+	return b.SetTaggedContext(context.Background(), str, encoded, ttl, tags)
+}
+
+// Store the encoded value under the key like SetContext, recording the key in the index
+// of each tag. The value and the index keys are written in one transaction and the index
+// keys expire with the value.
+func (b *BadgerCache) SetTaggedContext(ctx context.Context, str string, value []byte, ttl time.Duration, tags []string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return b.update(func(txn *badger.Txn) error {
+		entries := []*badger.Entry{badger.NewEntry([]byte(str), value)}
+		for _, tag := range tags {
+			entries = append(entries, badger.NewEntry(tagIndexKey(tag, str), nil))
+		}
+
+		for _, e := range entries {
+			if ttl > 0 {
+				e = e.WithTTL(ttl)
+			}
+			if err := txn.SetEntry(e); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// Remove every entry stored with any of the tags. Each entry is removed along with its
+// index key in the same transaction, and the index is read again until it is empty, so
+// an entry written with a tag while the tag is flushed is either removed or was written
+// after the flush.
+func (b *BadgerCache) FlushTags(ctx context.Context, tags ...string) error {
+	for _, tag := range tags {
+		prefix := tagIndexKey(tag, "")
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			removed := 0
+			err := b.update(func(txn *badger.Txn) error {
+				removed = 0
+
+				opts := badger.DefaultIteratorOptions
+				opts.PrefetchValues = false
+				it := txn.NewIterator(opts)
+
+				var keys [][]byte
+				for it.Seek(prefix); it.ValidForPrefix(prefix) && len(keys) < flushBatchSize; it.Next() {
+					keys = append(keys, it.Item().KeyCopy(nil))
+				}
+				it.Close()
+
+				for _, indexKey := range keys {
+					if err := txn.Delete(bytes.TrimPrefix(indexKey, prefix)); err != nil {
+						return err
+					}
+					if err := txn.Delete(indexKey); err != nil {
+						return err
+					}
+				}
+
+				removed = len(keys)
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+
+			if removed < flushBatchSize {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// Run the function in a read-write transaction, retrying when it conflicts with a
+// concurrent transaction.
+func (b *BadgerCache) update(fn func(txn *badger.Txn) error) error {
+	var err error
+	for i := 0; i < conflictRetries; i++ {
+		err = b.Conn.Update(fn)
+		if !errors.Is(err, badger.ErrConflict) {
+			return err
+		}
+		time.Sleep(rand.N(conflictBackoff))
+	}
+	return err
+}
+
+// Return the index key recording that the key was stored with the tag.
+func tagIndexKey(tag, str string) []byte {
+	return []byte(tagPrefix + tag + "\x00" + str)
+}
@@ -0,0 +1,20 @@
+package badgerdriver
+
+// Options describe how to open the Badger database. The database is kept at Path unless
+// InMemory is set, in which case nothing is written to disk and the entries are lost when
+// the database is closed, which suits tests. An EncryptionKey of 16, 24 or 32 bytes
+// encrypts the data at rest with AES-128, AES-192 or AES-256; the same key must be given
+// every time the database is opened.
+type Options struct {
+	Path          string
+	InMemory      bool
+	EncryptionKey []byte
+}
+
+// Stats report the size on disk of the LSM tree, holding the keys and small values, and
+// of the value log, holding the larger values. Badger refreshes the sizes about once a
+// minute and reports zero for an in-memory database.
+type Stats struct {
+	LSMSize  int64
+	VlogSize int64
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codecs shipped with the framework.
+var (
+	JSON    Codec = JSONCodec{}
+	Gob     Codec = GobCodec{}
+	Msgpack Codec = MsgpackCodec{}
+)
+
+// Return the codec with the name, i.e., json, gob or msgpack. An empty name returns the
+// JSON codec.
+func CodecByName(name string) (Codec, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "json":
+		return JSON, nil
+	case "gob":
+		return Gob, nil
+	case "msgpack":
+		return Msgpack, nil
+	default:
+		return nil, fmt.Errorf("unsupported cache codec: %s", name)
+	}
+}
+
+// Return the codec the store encodes values with, or JSON when the store has no codec.
+func CodecOf(s Store) Codec {
+	if c, ok := s.(Coded); ok {
+		if codec := c.ValueCodec(); codec != nil {
+			return codec
+		}
+	}
+	return JSON
+}
+
+func (JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
+
+func (MsgpackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (MsgpackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
@@ -0,0 +1,310 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Upper bounds of the buckets of the operation latency, in seconds.
+var latencyBuckets = []float64{.0001, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// Wrap the cache to record its operations in the registry under the name, e.g., the
+// driver. Caches instrumented with the same registry share the metrics, told apart by the
+// cache label. Example:
+//
+//	app.Cache = cache.Instrument(app.Cache, "redis", app.Metrics)
+func Instrument(c Cache, name string, registry *metrics.Registry) *Instrumented {
+	i := &Instrumented{
+		cache: c,
+		store: Adapt(c),
+		name:  name,
+		metrics: cacheMetrics{
+			hits:      registry.Counter("adele_cache_hits_total", "Cache reads that found the key.", "cache"),
+			misses:    registry.Counter("adele_cache_misses_total", "Cache reads that did not find the key.", "cache"),
+			sets:      registry.Counter("adele_cache_sets_total", "Values stored in the cache.", "cache"),
+			evictions: registry.Counter("adele_cache_evictions_total", "Keys removed from the cache, by a caller or by the limits of the cache.", "cache"),
+			errors:    registry.Counter("adele_cache_errors_total", "Cache operations that failed.", "cache", "operation"),
+			duration:  registry.Histogram("adele_cache_operation_duration_seconds", "Latency of the cache operations.", latencyBuckets, "cache", "operation"),
+		},
+	}
+
+	if e, ok := c.(Evictor); ok {
+		evictions := i.metrics.evictions.With(name)
+		e.OnEvict(func(key string) {
+			evictions.Inc()
+		})
+	}
+
+	return i
+}
+
+func (i *Instrumented) Has(key string) (bool, error) {
+	start := time.Now()
+	ok, err := i.cache.Has(key)
+	i.observe("has", key, start, err)
+	return ok, err
+}
+
+// Get the value stored under the key. The original Cache interface reports a missing key
+// as an error, so every error is counted as a miss.
+func (i *Instrumented) Get(key string) (interface{}, error) {
+	start := time.Now()
+	value, err := i.cache.Get(key)
+	i.observe("get", key, start, nil)
+	i.read(err == nil)
+	return value, err
+}
+
+func (i *Instrumented) Set(key string, value interface{}, expires ...int) error {
+	start := time.Now()
+	err := i.cache.Set(key, value, expires...)
+	i.observe("set", key, start, err)
+	i.set(err)
+	return err
+}
+
+func (i *Instrumented) Forget(key string) error {
+	start := time.Now()
+	err := i.cache.Forget(key)
+	i.observe("forget", key, start, err)
+	i.evict(err)
+	return err
+}
+
+func (i *Instrumented) EmptyByMatch(pattern string) error {
+	start := time.Now()
+	err := i.cache.EmptyByMatch(pattern)
+	i.observe("empty_by_match", pattern, start, err)
+	return err
+}
+
+func (i *Instrumented) Empty() error {
+	start := time.Now()
+	err := i.cache.Empty()
+	i.observe("empty", "", start, err)
+	return err
+}
+
+func (i *Instrumented) GetContext(ctx context.Context, key string) ([]byte, error) {
+	start := time.Now()
+	value, err := i.store.GetContext(ctx, key)
+
+	if errors.Is(err, ErrMiss) {
+		i.observe("get", key, start, nil)
+		i.read(false)
+		return value, err
+	}
+
+	i.observe("get", key, start, err)
+	if err == nil {
+		i.read(true)
+	}
+
+	return value, err
+}
+
+func (i *Instrumented) SetContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	start := time.Now()
+	err := i.store.SetContext(ctx, key, value, ttl)
+	i.observe("set", key, start, err)
+	i.set(err)
+	return err
+}
+
+func (i *Instrumented) HasContext(ctx context.Context, key string) (bool, error) {
+	start := time.Now()
+	ok, err := i.store.HasContext(ctx, key)
+	i.observe("has", key, start, err)
+	return ok, err
+}
+
+func (i *Instrumented) ForgetContext(ctx context.Context, key string) error {
+	start := time.Now()
+	err := i.store.ForgetContext(ctx, key)
+	i.observe("forget", key, start, err)
+	i.evict(err)
+	return err
+}
+
+// Take the lock on the key from the wrapped cache or a cache it wraps. A lock held by
+// someone else is not counted as an error.
+func (i *Instrumented) Lock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	locker, ok := lockerOf(i.cache)
+	if !ok {
+		return nil, i.unsupported("lock", key)
+	}
+
+	start := time.Now()
+	lock, err := locker.Lock(ctx, key, ttl)
+	if errors.Is(err, ErrNotAcquired) {
+		i.observe("lock", key, start, nil)
+	} else {
+		i.observe("lock", key, start, err)
+	}
+	return lock, err
+}
+
+// Add the delta to the counter of the wrapped cache or a cache it wraps.
+func (i *Instrumented) Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	counter, ok := counterOf(i.cache)
+	if !ok {
+		return 0, i.unsupported("increment", key)
+	}
+
+	start := time.Now()
+	n, err := counter.Increment(ctx, key, delta, ttl)
+	i.observe("increment", key, start, err)
+	return n, err
+}
+
+// Subtract the delta from the counter of the wrapped cache or a cache it wraps.
+func (i *Instrumented) Decrement(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error) {
+	counter, ok := counterOf(i.cache)
+	if !ok {
+		return 0, i.unsupported("decrement", key)
+	}
+
+	start := time.Now()
+	n, err := counter.Decrement(ctx, key, delta, ttl)
+	i.observe("decrement", key, start, err)
+	return n, err
+}
+
+// Create a cache storing every entry with the tags through the instrumented cache.
+// Example:
+//
+//	err := app.Cache.(*cache.Instrumented).Tags("user:42").Set("orders:user:42", orders, 3600)
+func (i *Instrumented) Tags(tags ...string) *TaggedCache {
+	return NewTagged(i, tags...)
+}
+
+func (i *Instrumented) SetTagged(key string, value interface{}, tags []string, expires ...int) error {
+	tagger, ok := i.cache.(Tagger)
+	if !ok {
+		return i.unsupported("set", key)
+	}
+
+	start := time.Now()
+	err := tagger.SetTagged(key, value, tags, expires...)
+	i.observe("set", key, start, err)
+	i.set(err)
+	return err
+}
+
+func (i *Instrumented) SetTaggedContext(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	tagger, ok := i.cache.(Tagger)
+	if !ok {
+		return i.unsupported("set", key)
+	}
+
+	start := time.Now()
+	err := tagger.SetTaggedContext(ctx, key, value, ttl, tags)
+	i.observe("set", key, start, err)
+	i.set(err)
+	return err
+}
+
+func (i *Instrumented) FlushTags(ctx context.Context, tags ...string) error {
+	tagger, ok := i.cache.(Tagger)
+	if !ok {
+		return i.unsupported("flush_tags", strings.Join(tags, ","))
+	}
+
+	start := time.Now()
+	err := tagger.FlushTags(ctx, tags...)
+	i.observe("flush_tags", strings.Join(tags, ","), start, err)
+	return err
+}
+
+// Return the codec values are encoded with by the wrapped cache.
+func (i *Instrumented) ValueCodec() Codec {
+	return CodecOf(i.store)
+}
+
+// Return the wrapped cache.
+func (i *Instrumented) Unwrap() Cache {
+	return i.cache
+}
+
+// Close the wrapped cache when it can be closed.
+func (i *Instrumented) Close() error {
+	if closer, ok := i.cache.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Find the Counter of the cache or of a cache it wraps.
+func counterOf(c Cache) (Counter, bool) {
+	for {
+		if counter, ok := c.(Counter); ok {
+			return counter, true
+		}
+
+		w, ok := c.(Wrapper)
+		if !ok {
+			return nil, false
+		}
+		c = w.Unwrap()
+	}
+}
+
+// Count an operation the wrapped cache does not support as an error and return it.
+func (i *Instrumented) unsupported(op, key string) error {
+	err := fmt.Errorf("%w: %s on %T", ErrUnsupported, op, i.cache)
+	i.observe(op, key, time.Now(), err)
+	return err
+}
+
+// Record the latency and any error of an operation and log it.
+func (i *Instrumented) observe(op, key string, start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	i.metrics.duration.With(i.name, op).Observe(elapsed.Seconds())
+	if err != nil {
+		i.metrics.errors.With(i.name, op).Inc()
+	}
+
+	if i.Log != nil {
+		entry := i.Log.WithFields(logrus.Fields{
+			"cache":     i.name,
+			"operation": op,
+			"key":       key,
+			"duration":  elapsed,
+		})
+		if err != nil {
+			entry = entry.WithError(err)
+		}
+		entry.Debug("cache operation")
+	}
+}
+
+// Count a read as a hit or a miss.
+func (i *Instrumented) read(hit bool) {
+	if hit {
+		i.metrics.hits.With(i.name).Inc()
+	} else {
+		i.metrics.misses.With(i.name).Inc()
+	}
+}
+
+// Count a successful set.
+func (i *Instrumented) set(err error) {
+	if err == nil {
+		i.metrics.sets.With(i.name).Inc()
+	}
+}
+
+// Count a key removed by a caller.
+func (i *Instrumented) evict(err error) {
+	if err == nil {
+		i.metrics.evictions.With(i.name).Inc()
+	}
+}
@@ -0,0 +1,116 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/metrics"
+)
+
+func TestInstrument(t *testing.T) {
+	ctx := context.Background()
+	registry := metrics.New()
+	c := Instrument(mapCache{}, "map", registry)
+
+	if err := SetAs(ctx, c, "user", 42, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GetAs[int](ctx, c, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetContext(ctx, "missing"); !errors.Is(err, ErrMiss) {
+		t.Fatalf("expected ErrMiss, got %v", err)
+	}
+	if _, err := c.Get("missing"); err == nil {
+		t.Fatal("expected an error for a missing key")
+	}
+	if err := c.Forget("user"); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`adele_cache_hits_total{cache="map"} 1`,
+		`adele_cache_misses_total{cache="map"} 2`,
+		`adele_cache_sets_total{cache="map"} 1`,
+		`adele_cache_evictions_total{cache="map"} 1`,
+		`adele_cache_operation_duration_seconds_count{cache="map",operation="get"} 3`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, out.String())
+		}
+	}
+
+	if strings.Contains(out.String(), "adele_cache_errors_total{") {
+		t.Errorf("no error should be recorded:\n%s", out.String())
+	}
+
+	if c.Unwrap() == nil {
+		t.Error("Unwrap() should return the wrapped cache")
+	}
+}
+
+// A cache taking locks through a syncStore.
+type lockingCache struct {
+	mapCache
+	*syncStore
+}
+
+func TestInstrument_Forwarding(t *testing.T) {
+	ctx := context.Background()
+	registry := metrics.New()
+	c := Instrument(lockingCache{mapCache{}, newSyncStore()}, "locking", registry)
+
+	if _, ok := lockerOf(c); !ok {
+		t.Fatal("an instrumented Locker should be found as a Locker")
+	}
+	lock, err := c.Lock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Lock(ctx, "job", time.Minute); !errors.Is(err, ErrNotAcquired) {
+		t.Fatalf("expected ErrNotAcquired, got %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Increment(ctx, "hits", 1, 0); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Increment: expected ErrUnsupported, got %v", err)
+	}
+	if err := c.Tags("user:42").Set("orders", 1); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Tags().Set: expected ErrUnsupported, got %v", err)
+	}
+	if err := c.FlushTags(ctx, "user:42"); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("FlushTags: expected ErrUnsupported, got %v", err)
+	}
+
+	if _, ok := lockerOf(Instrument(mapCache{}, "map", registry)); ok {
+		t.Error("an instrumented cache without locks should not be found as a Locker")
+	}
+
+	var out strings.Builder
+	if err := registry.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		`adele_cache_operation_duration_seconds_count{cache="locking",operation="lock"} 2`,
+		`adele_cache_errors_total{cache="locking",operation="increment"} 1`,
+		`adele_cache_errors_total{cache="locking",operation="flush_tags"} 1`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("metrics do not contain %s:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), `adele_cache_errors_total{cache="locking",operation="lock"}`) {
+		t.Errorf("a lock held by someone else should not be counted as an error:\n%s", out.String())
+	}
+}
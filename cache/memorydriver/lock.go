@@ -0,0 +1,144 @@
+package memorydriver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+)
+
+// A lock held on a key of the cache. The token identifies the holder so a lock that
+// expired and was taken by someone else is not released.
+type memoryLock struct {
+	cache *MemoryCache
+	key   string
+	token string
+}
+
+// Take a lock on the key, held until it is released or expires after the TTL; a TTL of
+// zero holds the lock until it is released. The error is cache.ErrNotAcquired when the
+// key is locked by someone else. Locks are kept apart from the entries of the cache, so
+// they are never evicted.
+func (m *MemoryCache) Lock(ctx context.Context, str string, ttl time.Duration) (cache.Lock, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	l := &memoryLock{cache: m, key: str, token: hex.EncodeToString(token)}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.lock(l.key); ok {
+		return nil, cache.ErrNotAcquired
+	}
+
+	if m.locks == nil {
+		m.locks = make(map[string]*item)
+	}
+	m.locks[l.key] = &item{key: l.key, value: []byte(l.token), expires: m.expiry(ttl)}
+
+	return l, nil
+}
+
+// Add the delta to the counter held by the key. Counters are not evicted by the limits
+// of the cache.
+func (m *MemoryCache) Increment(ctx context.Context, str string, delta int64, ttl time.Duration) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	value := delta
+	expires := m.expiry(ttl)
+
+	if el, ok := m.lookup(str); ok {
+		it := el.Value.(*item)
+		n, err := strconv.ParseInt(string(it.value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cache key %s does not hold a counter: %w", str, err)
+		}
+		value += n
+		if !it.expires.IsZero() {
+			expires = it.expires
+		}
+	}
+
+	m.put(&item{key: str, value: []byte(strconv.FormatInt(value, 10)), expires: expires, counter: true})
+
+	return value, nil
+}
+
+// Subtract the delta from the counter held by the key.
+func (m *MemoryCache) Decrement(ctx context.Context, str string, delta int64, ttl time.Duration) (int64, error) {
+	return m.Increment(ctx, str, -delta, ttl)
+}
+
+// Release the lock when it is still held.
+func (l *memoryLock) Release(ctx context.Context) error {
+	return l.update(ctx, func(it *item) {
+		delete(l.cache.locks, l.key)
+	})
+}
+
+// Reset the TTL of the lock when it is still held; a TTL of zero removes the expiry.
+func (l *memoryLock) Extend(ctx context.Context, ttl time.Duration) error {
+	return l.update(ctx, func(it *item) {
+		it.expires = l.cache.expiry(ttl)
+	})
+}
+
+// Run the function on the entry of the lock once it is known to still hold the token,
+// returning cache.ErrNotHeld when it does not.
+func (l *memoryLock) update(ctx context.Context, fn func(it *item)) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l.cache.mu.Lock()
+	defer l.cache.mu.Unlock()
+
+	it, ok := l.cache.lock(l.key)
+	if !ok || string(it.value) != l.token {
+		return cache.ErrNotHeld
+	}
+
+	fn(it)
+
+	return nil
+}
+
+// Find the lock held on the key, removing it when it has expired. The caller holds the
+// lock of the cache.
+func (m *MemoryCache) lock(str string) (*item, bool) {
+	it, ok := m.locks[str]
+	if !ok {
+		return nil, false
+	}
+
+	if it.expired(m.clock()) {
+		delete(m.locks, str)
+		return nil, false
+	}
+
+	return it, true
+}
+
+// Return the expiry of an entry stored now with the TTL, zero when the TTL is zero.
+func (m *MemoryCache) expiry(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	return m.clock().Add(ttl)
+}
@@ -0,0 +1,311 @@
+package memorydriver
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+)
+
+// Create an in-memory cache holding at most maxEntries entries and maxBytes bytes of keys
+// and values. Example:
+//
+//	mc := memorydriver.New(10000, 64<<20)
+func New(maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		MaxEntries: maxEntries,
+		MaxBytes:   maxBytes,
+	}
+}
+
+func (m *MemoryCache) Has(str string) (bool, error) {
+	return m.HasContext(context.Background(), str)
+}
+
+func (m *MemoryCache) Get(str string) (interface{}, error) {
+	data, err := m.GetContext(context.Background(), str)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := cache.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded[str], nil
+}
+
+func (m *MemoryCache) Set(str string, value interface{}, expires ...int) error {
+	entry := cache.Entry{}
+	entry[str] = value
+
+	encoded, err := cache.Encode(entry)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if len(expires) > 0 {
+		ttl = time.Duration(expires[0]) * time.Second
+	}
+
+	return m.SetContext(context.Background(), str, encoded, ttl)
+}
+
+func (m *MemoryCache) Forget(str string) error {
+	return m.ForgetContext(context.Background(), str)
+}
+
+// Remove every key matching the pattern followed by any characters, where * in the
+// pattern matches any characters and ? matches one, as with the Redis driver.
+func (m *MemoryCache) EmptyByMatch(str string) error {
+	re, err := compilePattern(str + "*")
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for key, el := range m.items {
+		if re.MatchString(key) {
+			m.remove(el)
+		}
+	}
+
+	return nil
+}
+
+func (m *MemoryCache) Empty() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.ll = nil
+	m.counters = nil
+	m.items = nil
+	m.bytes = 0
+
+	return nil
+}
+
+// Get the encoded value stored under the key, returning cache.ErrMiss when the key is
+// not in the cache or has expired.
+func (m *MemoryCache) GetContext(ctx context.Context, str string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	el, ok := m.lookup(str)
+	if !ok {
+		return nil, cache.ErrMiss
+	}
+
+	m.ll.MoveToFront(el)
+
+	return append([]byte{}, el.Value.(*item).value...), nil
+}
+
+// Store the encoded value under the key, evicting the least recently used entries when
+// the cache is full. A TTL of zero stores the value without expiry.
+func (m *MemoryCache) SetContext(ctx context.Context, str string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if m.MaxBytes > 0 && size(str, value) > m.MaxBytes {
+		return errors.New("value exceeds the memory cache size")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	it := &item{key: str, value: append([]byte{}, value...)}
+	if ttl > 0 {
+		it.expires = m.clock().Add(ttl)
+	}
+
+	m.put(it)
+
+	return nil
+}
+
+func (m *MemoryCache) HasContext(ctx context.Context, str string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.lookup(str)
+	return ok, nil
+}
+
+func (m *MemoryCache) ForgetContext(ctx context.Context, str string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if el, ok := m.items[str]; ok {
+		m.remove(el)
+	}
+
+	return nil
+}
+
+// Return the codec values are encoded with, JSON unless set.
+func (m *MemoryCache) ValueCodec() cache.Codec {
+	if m.Codec == nil {
+		return cache.JSON
+	}
+	return m.Codec
+}
+
+// Call the function with the key of every entry evicted to keep the cache within its
+// limits. The function is called while the cache is locked and must not use the cache.
+func (m *MemoryCache) OnEvict(fn func(key string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.onEvict = fn
+}
+
+// Return the number of entries in the cache, including expired entries not yet removed.
+func (m *MemoryCache) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return len(m.items)
+}
+
+// Remove the expired entries. Expired entries are never returned, but without a purge
+// they hold on to memory until they are evicted.
+func (m *MemoryCache) PurgeExpired() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.clock()
+	for _, el := range m.items {
+		if el.Value.(*item).expired(now) {
+			m.remove(el)
+		}
+	}
+}
+
+// Find the entry stored under the key, removing it when it has expired. The caller holds
+// the lock.
+func (m *MemoryCache) lookup(str string) (*list.Element, bool) {
+	el, ok := m.items[str]
+	if !ok {
+		return nil, false
+	}
+
+	if el.Value.(*item).expired(m.clock()) {
+		m.remove(el)
+		return nil, false
+	}
+
+	return el, true
+}
+
+// Store the entry as the most recently used one, evicting entries to stay within the
+// limits. A counter is stored apart and never evicted. The caller holds the lock.
+func (m *MemoryCache) put(it *item) {
+	if m.items == nil {
+		m.ll = list.New()
+		m.counters = list.New()
+		m.items = make(map[string]*list.Element)
+	}
+
+	if el, ok := m.items[it.key]; ok {
+		m.remove(el)
+	}
+
+	if it.counter {
+		m.items[it.key] = m.counters.PushFront(it)
+		return
+	}
+
+	m.items[it.key] = m.ll.PushFront(it)
+	m.bytes += size(it.key, it.value)
+	m.evict()
+}
+
+// Remove the least recently used entries until the cache is within its limits. The
+// caller holds the lock.
+func (m *MemoryCache) evict() {
+	for m.ll.Len() > 0 {
+		overEntries := m.MaxEntries > 0 && m.ll.Len() > m.MaxEntries
+		overBytes := m.MaxBytes > 0 && m.bytes > m.MaxBytes
+		if !overEntries && !overBytes {
+			return
+		}
+		el := m.ll.Back()
+		m.remove(el)
+
+		if m.onEvict != nil {
+			m.onEvict(el.Value.(*item).key)
+		}
+	}
+}
+
+// Remove an entry. The caller holds the lock.
+func (m *MemoryCache) remove(el *list.Element) {
+	it := el.Value.(*item)
+	delete(m.items, it.key)
+
+	if it.counter {
+		m.counters.Remove(el)
+		return
+	}
+
+	m.ll.Remove(el)
+	m.bytes -= size(it.key, it.value)
+}
+
+// Return the current time.
+func (m *MemoryCache) clock() time.Time {
+	if m.now != nil {
+		return m.now()
+	}
+	return time.Now()
+}
+
+// Report whether the entry has expired at the time.
+func (i *item) expired(now time.Time) bool {
+	return !i.expires.IsZero() && !now.Before(i.expires)
+}
+
+// Return the number of bytes an entry accounts for.
+func size(key string, value []byte) int64 {
+	return int64(len(key) + len(value))
+}
+
+// Compile a glob pattern where * matches any characters and ? matches one.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString("^")
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,261 @@
+package memorydriver
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+)
+
+func TestMemoryCache_SetGet(t *testing.T) {
+	m := New(0, 0)
+
+	if err := m.Set("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := m.Get("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x != "bar" {
+		t.Errorf("Get() = %v, want bar", x)
+	}
+
+	inCache, _ := m.Has("foo")
+	if !inCache {
+		t.Error("foo not found in cache")
+	}
+
+	if err := m.Forget("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Get("foo"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected cache.ErrMiss, got %v", err)
+	}
+}
+
+func TestMemoryCache_TTL(t *testing.T) {
+	now := time.Now()
+	m := New(0, 0)
+	m.now = func() time.Time { return now }
+
+	if err := m.Set("foo", "bar", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Set("forever", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(9 * time.Second)
+	if inCache, _ := m.Has("foo"); !inCache {
+		t.Error("foo expired early")
+	}
+
+	now = now.Add(time.Second)
+	if inCache, _ := m.Has("foo"); inCache {
+		t.Error("foo did not expire")
+	}
+
+	m.Set("bar", "baz", 1)
+	now = now.Add(time.Hour)
+	m.PurgeExpired()
+
+	if got := m.Len(); got != 1 {
+		t.Errorf("Len() after purge = %d, want 1", got)
+	}
+}
+
+func TestMemoryCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	m := New(2, 0)
+
+	var evicted []string
+	m.OnEvict(func(key string) {
+		evicted = append(evicted, key)
+	})
+
+	m.SetContext(ctx, "a", []byte("1"), 0)
+	m.SetContext(ctx, "b", []byte("2"), 0)
+
+	// Reading a makes b the least recently used entry.
+	m.GetContext(ctx, "a")
+	m.SetContext(ctx, "c", []byte("3"), 0)
+
+	if ok, _ := m.HasContext(ctx, "b"); ok {
+		t.Error("b should have been evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if ok, _ := m.HasContext(ctx, key); !ok {
+			t.Errorf("%s should be in the cache", key)
+		}
+	}
+	if len(evicted) != 1 || evicted[0] != "b" {
+		t.Errorf("OnEvict() called with %v, want [b]", evicted)
+	}
+
+	m = New(0, 9)
+	m.SetContext(ctx, "a", []byte("1234"), 0)
+	m.SetContext(ctx, "b", []byte("1234"), 0)
+
+	if ok, _ := m.HasContext(ctx, "a"); ok {
+		t.Error("a should have been evicted to stay within the byte limit")
+	}
+
+	if err := m.SetContext(ctx, "big", make([]byte, 20), 0); err == nil {
+		t.Error("expected an error storing a value larger than the cache")
+	}
+}
+
+func TestMemoryCache_EmptyByMatch(t *testing.T) {
+	m := New(0, 0)
+
+	for _, key := range []string{"user:1", "user:2", "users", "post:1"} {
+		m.Set(key, key)
+	}
+
+	if err := m.EmptyByMatch("user:"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"user:1": false, "user:2": false, "users": true, "post:1": true} {
+		if got, _ := m.Has(key); got != want {
+			t.Errorf("Has(%s) = %v, want %v", key, got, want)
+		}
+	}
+
+	m.EmptyByMatch("*:1")
+	if got, _ := m.Has("post:1"); got {
+		t.Error("post:1 should match *:1")
+	}
+
+	m.Empty()
+	if got := m.Len(); got != 0 {
+		t.Errorf("Len() after Empty() = %d, want 0", got)
+	}
+}
+
+func TestMemoryCache_TypedValues(t *testing.T) {
+	ctx := context.Background()
+	m := New(0, 0)
+	m.Codec = cache.Msgpack
+
+	if err := cache.SetAs(ctx, m, "count", 7, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := cache.GetAs[int](ctx, m, "count")
+	if err != nil || count != 7 {
+		t.Errorf("GetAs[int]() = %v, %v; want 7", count, err)
+	}
+}
+
+func TestMemoryCache_Counter(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	m := New(0, 0)
+	m.now = func() time.Time { return now }
+
+	if n, err := m.Increment(ctx, "hits", 5, time.Minute); err != nil || n != 5 {
+		t.Errorf("Increment() = %d, %v; want 5", n, err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if n, err := m.Decrement(ctx, "hits", 2, time.Minute); err != nil || n != 3 {
+		t.Errorf("Decrement() = %d, %v; want 3", n, err)
+	}
+
+	now = now.Add(30 * time.Second)
+	if inCache, _ := m.Has("hits"); inCache {
+		t.Error("the TTL of the counter should not be reset by a later increment")
+	}
+
+	m.Set("name", "adele")
+	if _, err := m.Increment(ctx, "name", 1, 0); err == nil {
+		t.Error("expected an error incrementing a key that is not a counter")
+	}
+}
+
+func TestMemoryCache_Lock(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	m := New(0, 0)
+	m.now = func() time.Time { return now }
+
+	lock, err := m.Lock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.Lock(ctx, "job", time.Minute); !errors.Is(err, cache.ErrNotAcquired) {
+		t.Errorf("expected cache.ErrNotAcquired, got %v", err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if err := lock.Extend(ctx, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(50 * time.Second)
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("Release() of an extended lock error = %v", err)
+	}
+
+	if err := lock.Release(ctx); !errors.Is(err, cache.ErrNotHeld) {
+		t.Errorf("expected cache.ErrNotHeld, got %v", err)
+	}
+
+	lock, _ = m.Lock(ctx, "job", time.Second)
+	now = now.Add(time.Second)
+	if err := lock.Extend(ctx, time.Minute); !errors.Is(err, cache.ErrNotHeld) {
+		t.Errorf("expected cache.ErrNotHeld for an expired lock, got %v", err)
+	}
+
+	// A lock without a TTL is held until it is released.
+	lock, _ = m.Lock(ctx, "job", 0)
+	now = now.Add(24 * time.Hour)
+	if _, err := m.Lock(ctx, "job", time.Minute); !errors.Is(err, cache.ErrNotAcquired) {
+		t.Errorf("expected cache.ErrNotAcquired for a lock without a TTL, got %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("Release() of a lock without a TTL error = %v", err)
+	}
+}
+
+func TestMemoryCache_LocksAndCountersAreNotEvicted(t *testing.T) {
+	ctx := context.Background()
+	m := New(2, 0)
+
+	lock, err := m.Lock(ctx, "job", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Increment(ctx, "hits", 1, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"a", "b", "c", "d"} {
+		m.Set(key, key)
+	}
+
+	if _, err := m.Lock(ctx, "job", time.Minute); !errors.Is(err, cache.ErrNotAcquired) {
+		t.Errorf("expected the lock to survive eviction, got %v", err)
+	}
+	if err := lock.Release(ctx); err != nil {
+		t.Errorf("Release() error = %v", err)
+	}
+
+	if n, err := m.Increment(ctx, "hits", 1, 0); err != nil || n != 2 {
+		t.Errorf("Increment() = %d, %v; want the counter to survive eviction", n, err)
+	}
+
+	if inCache, _ := m.Has("a"); inCache {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	if inCache, _ := m.Has("d"); !inCache {
+		t.Error("expected the most recent entry to be kept")
+	}
+}
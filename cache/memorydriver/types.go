@@ -0,0 +1,43 @@
+package memorydriver
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+)
+
+// MemoryCache keeps the cache in the memory of the process, evicting the least recently
+// used entries once MaxEntries or MaxBytes is exceeded; a limit of zero disables it. The
+// cache does not survive a restart and is not shared between processes, which makes it a
+// fit for tests and single instance deployments.
+type MemoryCache struct {
+	MaxEntries int
+	MaxBytes   int64
+	Codec      cache.Codec
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[string]*list.Element
+	bytes int64
+
+	// Counters are kept apart from the least recently used entries, and locks apart from
+	// every entry, so neither is evicted by the limits.
+	counters *list.List
+	locks    map[string]*item
+
+	// Called with the key of every entry evicted by the limits.
+	onEvict func(key string)
+
+	// Used in place of time.Now by tests.
+	now func() time.Time
+}
+
+// An entry in the cache; a zero expiry never expires.
+type item struct {
+	key     string
+	value   []byte
+	expires time.Time
+	counter bool
+}
@@ -0,0 +1,306 @@
+package redisdriver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// The number of hash slots of a Redis Cluster.
+const slotCount = 16384
+
+// Create a client for the Redis Cluster reachable through any of the addresses of the
+// options. The slots served by every master are read from the cluster when the first
+// key is routed, and again whenever the cluster moves a slot or a node fails. Example:
+//
+//	cluster, err := redisdriver.NewCluster(redisdriver.Options{
+//	    Addrs: []string{"node-1:6379", "node-2:6379"},
+//	})
+//	rc := redisdriver.RedisCache{Cluster: cluster, Prefix: "myapp"}
+func NewCluster(opts Options) (*Cluster, error) {
+	if len(opts.Addrs) == 0 {
+		return nil, errors.New("redis cluster address is required")
+	}
+	if opts.DB != 0 {
+		return nil, errors.New("redis cluster only supports database 0")
+	}
+	if opts.MasterName != "" {
+		return nil, errors.New("redis cluster does not use sentinels")
+	}
+
+	return &Cluster{
+		opts:  opts,
+		slots: make([]string, slotCount),
+		pools: make(map[string]*redis.Pool),
+	}, nil
+}
+
+// Return the hash slot of the key. When the key holds a non-empty hash tag, i.e., the
+// part between the first { and the following }, only the tag is hashed, so keys sharing a
+// tag share a slot and can be used together by a script.
+func Slot(key string) int {
+	if start := strings.IndexByte(key, '{'); start >= 0 {
+		if end := strings.IndexByte(key[start+1:], '}'); end > 0 {
+			key = key[start+1 : start+1+end]
+		}
+	}
+	return int(crc16(key) % slotCount)
+}
+
+// Return the pool of the master serving the key, or of the first address of the options
+// when the slots of the cluster cannot be read. Every node of a cluster serves pub/sub,
+// so the pool of any key will do for it.
+func (c *Cluster) Pool(key string) *redis.Pool {
+	addr, err := c.addr(context.Background(), Slot(key))
+	if err != nil {
+		addr = c.opts.Addrs[0]
+	}
+	return c.pool(addr)
+}
+
+// Check that every master of the cluster is reachable.
+func (c *Cluster) Ping(ctx context.Context) error {
+	if err := c.refresh(ctx); err != nil {
+		return err
+	}
+
+	for _, pool := range c.masters() {
+		conn, err := pool.GetContext(ctx)
+		if err != nil {
+			return err
+		}
+		_, err = redis.DoContext(conn, ctx, "PING")
+		conn.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Close the pools of every node.
+func (c *Cluster) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var errs []error
+	for addr, pool := range c.pools {
+		errs = append(errs, pool.Close())
+		delete(c.pools, addr)
+	}
+
+	return errors.Join(errs...)
+}
+
+// Get a connection to the master serving the key.
+func (c *Cluster) conn(ctx context.Context, key string) (redis.Conn, error) {
+	addr, err := c.addr(ctx, Slot(key))
+	if err != nil {
+		return nil, err
+	}
+	return c.pool(addr).GetContext(ctx)
+}
+
+// Get a connection to the node a key is being migrated to, ready for a command on the
+// key.
+func (c *Cluster) asking(ctx context.Context, addr string) (redis.Conn, error) {
+	conn, err := c.pool(addr).GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := redis.DoContext(conn, ctx, "ASKING"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Record that the slot of the key moved to the node.
+func (c *Cluster) moved(key, addr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.slots[Slot(key)] = addr
+}
+
+// Return the address of the master serving the slot, reading the slots of the cluster
+// when it is not known.
+func (c *Cluster) addr(ctx context.Context, slot int) (string, error) {
+	c.mu.RLock()
+	addr := c.slots[slot]
+	c.mu.RUnlock()
+
+	if addr != "" {
+		return addr, nil
+	}
+
+	if err := c.refresh(ctx); err != nil {
+		return "", err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.slots[slot] == "" {
+		return "", fmt.Errorf("no redis cluster node serves slot %d", slot)
+	}
+
+	return c.slots[slot], nil
+}
+
+// Return the pools of the masters serving a slot, ordered by address.
+func (c *Cluster) masters() []*redis.Pool {
+	c.mu.RLock()
+	seen := make(map[string]bool)
+	var addrs []string
+	for _, addr := range c.slots {
+		if addr != "" && !seen[addr] {
+			seen[addr] = true
+			addrs = append(addrs, addr)
+		}
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(addrs)
+
+	pools := make([]*redis.Pool, 0, len(addrs))
+	for _, addr := range addrs {
+		pools = append(pools, c.pool(addr))
+	}
+
+	return pools
+}
+
+// Return the pool of the node, creating it on first use.
+func (c *Cluster) pool(addr string) *redis.Pool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pool, ok := c.pools[addr]; ok {
+		return pool
+	}
+
+	pool := newPool(c.opts, func(ctx context.Context) (redis.Conn, error) {
+		return dial(ctx, addr, c.opts)
+	}, ping)
+	c.pools[addr] = pool
+
+	return pool
+}
+
+// Read the slots served by every master from the first node that answers, trying the
+// nodes already known before the addresses of the options.
+func (c *Cluster) refresh(ctx context.Context) error {
+	c.mu.RLock()
+	var addrs []string
+	for addr := range c.pools {
+		addrs = append(addrs, addr)
+	}
+	c.mu.RUnlock()
+
+	sort.Strings(addrs)
+	addrs = append(addrs, c.opts.Addrs...)
+
+	var errs []error
+	for _, addr := range addrs {
+		slots, err := c.readSlots(ctx, addr)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", addr, err))
+			continue
+		}
+
+		c.mu.Lock()
+		c.slots = slots
+		c.mu.Unlock()
+
+		return nil
+	}
+
+	return fmt.Errorf("no redis cluster node returned the slots: %w", errors.Join(errs...))
+}
+
+// Read the master serving every slot from the node.
+func (c *Cluster) readSlots(ctx context.Context, addr string) ([]string, error) {
+	conn, err := c.pool(addr).GetContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	ranges, err := redis.Values(redis.DoContext(conn, ctx, "CLUSTER", "SLOTS"))
+	if err != nil {
+		return nil, err
+	}
+
+	host, _, _ := net.SplitHostPort(addr)
+	slots := make([]string, slotCount)
+
+	for _, r := range ranges {
+		// Each range is the first and last slot followed by the master and its replicas,
+		// each given as a host and a port.
+		fields, err := redis.Values(r, nil)
+		if err != nil || len(fields) < 3 {
+			return nil, errors.New("unexpected reply to CLUSTER SLOTS")
+		}
+
+		first, err1 := redis.Int(fields[0], nil)
+		last, err2 := redis.Int(fields[1], nil)
+		master, err3 := redis.Values(fields[2], nil)
+		if err := errors.Join(err1, err2, err3); err != nil || len(master) < 2 || first < 0 || last >= slotCount {
+			return nil, errors.New("unexpected reply to CLUSTER SLOTS")
+		}
+
+		masterHost, _ := redis.String(master[0], nil)
+		masterPort, _ := redis.Int(master[1], nil)
+		if masterHost == "" || masterHost == "?" {
+			masterHost = host
+		}
+
+		for slot := first; slot <= last; slot++ {
+			slots[slot] = net.JoinHostPort(masterHost, strconv.Itoa(masterPort))
+		}
+	}
+
+	return slots, nil
+}
+
+// Return the node named by a MOVED or ASK redirect of the cluster and whether it is an
+// ASK redirect, which only applies to the next command.
+func redirect(err error) (addr string, ask bool, ok bool) {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return "", false, false
+	}
+
+	fields := strings.Fields(string(rerr))
+	if len(fields) != 3 || (fields[0] != "MOVED" && fields[0] != "ASK") {
+		return "", false, false
+	}
+
+	return fields[2], fields[0] == "ASK", true
+}
+
+// Compute the CRC16 checksum Redis Cluster hashes keys with, i.e., CRC-16/XMODEM.
+func crc16(s string) uint16 {
+	var crc uint16
+	for i := 0; i < len(s); i++ {
+		crc ^= uint16(s[i]) << 8
+		for j := 0; j < 8; j++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
@@ -0,0 +1,137 @@
+package redisdriver
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// The number of times a command is sent before its error is returned, whether it was
+// redirected by a cluster or failed during a failover, and the wait before the first
+// retry after a failover, doubled after every attempt.
+const (
+	maxAttempts     = 5
+	failoverBackoff = 100 * time.Millisecond
+)
+
+// Run the function with a connection to the server holding the key, see exec. The
+// function must be safe to run again after a connection was lost while it ran.
+func (c *RedisCache) do(ctx context.Context, key string, fn func(conn redis.Conn) error) error {
+	return c.exec(ctx, key, true, fn)
+}
+
+// Run the function with a connection to the server holding the key. The function is run
+// again on the node a cluster redirects the key to and, after a short wait, when it fails
+// during a failover, e.g., the master turned into a replica or the connection was lost.
+// A function that is not idempotent is only run again when the server refused the
+// command, since a lost connection does not tell whether the command was applied.
+func (c *RedisCache) exec(ctx context.Context, key string, idempotent bool, fn func(conn redis.Conn) error) error {
+	var err error
+	var ask string
+
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		var conn redis.Conn
+		if ask != "" {
+			conn, err = c.Cluster.asking(ctx, ask)
+			ask = ""
+		} else {
+			conn, err = c.conn(ctx, key)
+		}
+
+		sent := false
+		if err == nil {
+			sent = true
+			err = fn(conn)
+			conn.Close()
+		}
+
+		if err == nil || ctx.Err() != nil || attempt == maxAttempts {
+			return err
+		}
+
+		if c.Cluster != nil {
+			if addr, isAsk, ok := redirect(err); ok {
+				if isAsk {
+					ask = addr
+				} else {
+					c.Cluster.moved(key, addr)
+				}
+				continue
+			}
+		}
+
+		if !failover(err) || (sent && !idempotent && !refused(err)) {
+			return err
+		}
+
+		if err := c.recover(ctx, attempt); err != nil {
+			return err
+		}
+	}
+
+	return err
+}
+
+// Wait before the next attempt after a failover, reading the slots of a cluster again
+// since a replica may have replaced a failed master.
+func (c *RedisCache) recover(ctx context.Context, attempt int) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(failoverBackoff << (attempt - 1)):
+	}
+
+	if c.Cluster != nil {
+		c.Cluster.refresh(ctx)
+	}
+
+	return nil
+}
+
+// Get a connection to the server holding the key.
+func (c *RedisCache) conn(ctx context.Context, key string) (redis.Conn, error) {
+	if c.Cluster != nil {
+		return c.Cluster.conn(ctx, key)
+	}
+	return c.Conn.GetContext(ctx)
+}
+
+// Return the pools of the server, or of every master of the cluster.
+func (c *RedisCache) nodes(ctx context.Context) ([]*redis.Pool, error) {
+	if c.Cluster != nil {
+		if err := c.Cluster.refresh(ctx); err != nil {
+			return nil, err
+		}
+		return c.Cluster.masters(), nil
+	}
+	return []*redis.Pool{c.Conn}, nil
+}
+
+// Report whether the error is caused by a failover, whether the server refused the
+// command or the connection to the server was lost.
+func failover(err error) bool {
+	var nerr net.Error
+	return refused(err) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &nerr)
+}
+
+// Report whether the server refused the command because it cannot take writes or serve
+// the key at the moment, e.g., a master turned into a replica.
+func refused(err error) bool {
+	var rerr redis.Error
+	if !errors.As(err, &rerr) {
+		return false
+	}
+
+	for _, prefix := range []string{"READONLY", "MASTERDOWN", "LOADING", "CLUSTERDOWN", "TRYAGAIN"} {
+		if strings.HasPrefix(string(rerr), prefix) {
+			return true
+		}
+	}
+
+	return false
+}
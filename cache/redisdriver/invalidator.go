@@ -0,0 +1,114 @@
+package redisdriver
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/gomodule/redigo/redis"
+)
+
+// The wait before a lost subscription is restored.
+const resubscribeBackoff = time.Second
+
+// Invalidator broadcasts cache invalidations to every process subscribed to a Redis
+// pub/sub channel. OnError, when set, is called with the errors that end a subscription
+// and with messages that cannot be decoded.
+type Invalidator struct {
+	Conn    *redis.Pool
+	Channel string
+	OnError func(error)
+}
+
+// Create an invalidator publishing to the channel. Example:
+//
+//	inv := redisdriver.NewInvalidator(pool, "myapp:invalidate")
+func NewInvalidator(pool *redis.Pool, channel string) *Invalidator {
+	return &Invalidator{
+		Conn:    pool,
+		Channel: channel,
+	}
+}
+
+// Publish the invalidation to every subscribed process, including this one.
+func (i *Invalidator) Publish(ctx context.Context, inv cache.Invalidation) error {
+	payload, err := json.Marshal(inv)
+	if err != nil {
+		return err
+	}
+
+	conn, err := i.Conn.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = redis.DoContext(conn, ctx, "PUBLISH", i.Channel, payload)
+	return err
+}
+
+// Call the function with every invalidation published to the channel until the context
+// is cancelled. A lost subscription is restored after a second; the invalidations
+// published in the meantime are lost, so the function is called with an invalidation of
+// every key once the subscription is restored.
+func (i *Invalidator) Subscribe(ctx context.Context, fn func(cache.Invalidation)) error {
+	restored := false
+
+	for {
+		err := i.subscribe(ctx, fn, restored)
+		if ctx.Err() != nil {
+			return nil
+		}
+		i.error(err)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(resubscribeBackoff):
+		}
+
+		restored = true
+	}
+}
+
+// Subscribe to the channel and pass every invalidation to the function until the
+// connection fails or the context is cancelled.
+func (i *Invalidator) subscribe(ctx context.Context, fn func(cache.Invalidation), restored bool) error {
+	conn, err := i.Conn.GetContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	psc := redis.PubSubConn{Conn: conn}
+	defer psc.Close()
+
+	if err := psc.Subscribe(i.Channel); err != nil {
+		return err
+	}
+
+	for {
+		switch msg := psc.ReceiveContext(ctx).(type) {
+		case redis.Message:
+			var inv cache.Invalidation
+			if err := json.Unmarshal(msg.Data, &inv); err != nil {
+				i.error(err)
+				continue
+			}
+			fn(inv)
+		case redis.Subscription:
+			if msg.Kind == "subscribe" && restored {
+				fn(cache.Invalidation{All: true})
+			}
+		case error:
+			return msg
+		}
+	}
+}
+
+// Report the error to OnError when set.
+func (i *Invalidator) error(err error) {
+	if i.OnError != nil {
+		i.OnError(err)
+	}
+}
@@ -0,0 +1,137 @@
+package redisdriver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/gomodule/redigo/redis"
+)
+
+// A lock held on a key of the cache. The token identifies the holder so a lock that
+// expired and was taken by someone else is not released.
+type redisLock struct {
+	cache *RedisCache
+	key   string
+	token string
+}
+
+// Delete the key only when it still holds the token of the lock.
+var releaseScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// Reset the TTL of the key only when it still holds the token of the lock, removing the
+// expiry when the TTL is zero.
+var extendScript = redis.NewScript(1, `
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	if tonumber(ARGV[2]) > 0 then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	redis.call("PERSIST", KEYS[1])
+	return 1
+end
+return 0
+`)
+
+// Add to the counter, setting the TTL when the counter has no expiry.
+var incrementScript = redis.NewScript(1, `
+local value = redis.call("INCRBY", KEYS[1], ARGV[1])
+if tonumber(ARGV[2]) > 0 and redis.call("PTTL", KEYS[1]) == -1 then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return value
+`)
+
+// Take a lock on the key shared by every process using the cache. The lock is released
+// by its holder or expires after the TTL; a TTL of zero holds the lock until it is
+// released. The error is cache.ErrNotAcquired when the key is locked by someone else.
+// Example:
+//
+//	lock, err := rc.Lock(ctx, "reports", 30*time.Second)
+//	if err != nil {
+//	    return err
+//	}
+//	defer lock.Release(ctx)
+func (c *RedisCache) Lock(ctx context.Context, str string, ttl time.Duration) (cache.Lock, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	l := &redisLock{cache: c, key: c.key(str), token: hex.EncodeToString(token)}
+
+	// Taking the lock again after a lost connection could find the lock taken by the
+	// first attempt, so the command is only retried when the server refused it.
+	args := redis.Args{}.Add(l.key, l.token, "NX")
+	if ttl > 0 {
+		args = args.Add("PX", ttl.Milliseconds())
+	}
+
+	err := c.exec(ctx, l.key, false, func(conn redis.Conn) error {
+		_, err := redis.String(redis.DoContext(conn, ctx, "SET", args...))
+		return err
+	})
+	if errors.Is(err, redis.ErrNil) {
+		return nil, cache.ErrNotAcquired
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Add the delta to the counter held by the key. Example:
+//
+//	hits, err := rc.Increment(ctx, "hits:"+ip, 1, time.Minute)
+func (c *RedisCache) Increment(ctx context.Context, str string, delta int64, ttl time.Duration) (int64, error) {
+	var n int64
+	err := c.exec(ctx, c.key(str), false, func(conn redis.Conn) error {
+		var err error
+		n, err = redis.Int64(incrementScript.DoContext(ctx, conn, c.key(str), delta, ttl.Milliseconds()))
+		return err
+	})
+
+	return n, err
+}
+
+// Subtract the delta from the counter held by the key.
+func (c *RedisCache) Decrement(ctx context.Context, str string, delta int64, ttl time.Duration) (int64, error) {
+	return c.Increment(ctx, str, -delta, ttl)
+}
+
+// Release the lock when it is still held.
+func (l *redisLock) Release(ctx context.Context) error {
+	return l.run(ctx, releaseScript, l.key, l.token)
+}
+
+// Reset the TTL of the lock when it is still held; a TTL of zero removes the expiry.
+func (l *redisLock) Extend(ctx context.Context, ttl time.Duration) error {
+	return l.run(ctx, extendScript, l.key, l.token, ttl.Milliseconds())
+}
+
+// Run a script checking the token of the lock, returning cache.ErrNotHeld when the key
+// no longer holds it.
+func (l *redisLock) run(ctx context.Context, script *redis.Script, args ...interface{}) error {
+	var n int
+	err := l.cache.do(ctx, l.key, func(conn redis.Conn) error {
+		var err error
+		n, err = redis.Int(script.DoContext(ctx, conn, args...))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return cache.ErrNotHeld
+	}
+
+	return nil
+}
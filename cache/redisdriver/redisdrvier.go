@@ -1,6 +1,8 @@
 package redisdriver
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
@@ -9,30 +11,29 @@ import (
 	"github.com/gomodule/redigo/redis"
 )
 
+// RedisCache stores the cache in Redis, either through the connection pool of a single
+// server or Sentinel master, or, when Cluster is set, across the masters of a cluster.
+// Commands are retried after a failover and follow the redirects of a cluster.
 type RedisCache struct {
-	Conn   *redis.Pool
-	Prefix string
+	Conn    *redis.Pool
+	Cluster *Cluster
+	Prefix  string
+	Codec   cache.Codec
 }
 
 func (c *RedisCache) Has(str string) (bool, error) {
-	key := fmt.Sprintf("%s:%s", c.Prefix, str)
-	connection := c.Conn.Get()
-	defer connection.Close()
-
-	ok, err := redis.Bool(connection.Do("EXISTS", key))
-	if err != nil {
-		return false, err
-	}
-	return ok, nil
+	return c.HasContext(context.Background(), str)
 }
 
 func (c *RedisCache) Get(str string) (interface{}, error) {
-	key := fmt.Sprintf("%s:%s", c.Prefix, str)
-	fmt.Println("try to get:", key)
-	conn := c.Conn.Get()
-	defer conn.Close()
+	key := c.key(str)
 
-	cacheEntry, err := redis.Bytes(conn.Do("GET", key))
+	var cacheEntry []byte
+	err := c.do(context.Background(), key, func(conn redis.Conn) error {
+		var err error
+		cacheEntry, err = redis.Bytes(conn.Do("GET", key))
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -48,58 +49,138 @@ func (c *RedisCache) Get(str string) (interface{}, error) {
 }
 
 func (c *RedisCache) Set(str string, value interface{}, expires ...int) error {
-	key := fmt.Sprintf("%s:%s", c.Prefix, str)
-	conn := c.Conn.Get()
+	key := c.key(str)
 
-	defer conn.Close()
-
-	entry := cache.Entry{}
-	entry[key] = value
-	encoded, err := cache.Encode(entry)
+	encoded, err := c.encode(str, value)
 	if err != nil {
 		return err
 	}
 
-	if len(expires) > 0 {
-		_, err := conn.Do("SETEX", key, expires[0], string(encoded))
-		if err != nil {
+	return c.do(context.Background(), key, func(conn redis.Conn) error {
+		if len(expires) > 0 {
+			_, err := conn.Do("SETEX", key, expires[0], string(encoded))
 			return err
 		}
-	} else {
+
 		_, err := conn.Do("SET", key, string(encoded))
-		if err != nil {
+		return err
+	})
+}
+
+func (c *RedisCache) Forget(str string) error {
+	return c.ForgetContext(context.Background(), str)
+}
+
+func (c *RedisCache) EmptyByMatch(str string) error {
+	return c.deleteMatching(context.Background(), c.key(str))
+}
+
+func (c *RedisCache) Empty() error {
+	return c.deleteMatching(context.Background(), fmt.Sprintf("%s:", c.Prefix))
+}
+
+// Get the encoded value stored under the key, returning cache.ErrMiss when the key is
+// not in the cache.
+func (c *RedisCache) GetContext(ctx context.Context, str string) ([]byte, error) {
+	var value []byte
+	err := c.do(ctx, c.key(str), func(conn redis.Conn) error {
+		var err error
+		value, err = redis.Bytes(redis.DoContext(conn, ctx, "GET", c.key(str)))
+		return err
+	})
+	if errors.Is(err, redis.ErrNil) {
+		return nil, cache.ErrMiss
+	}
+
+	return value, err
+}
+
+// Store the encoded value under the key. A TTL of zero stores the value without expiry.
+func (c *RedisCache) SetContext(ctx context.Context, str string, value []byte, ttl time.Duration) error {
+	return c.do(ctx, c.key(str), func(conn redis.Conn) error {
+		if ttl > 0 {
+			_, err := redis.DoContext(conn, ctx, "SET", c.key(str), value, "PX", ttl.Milliseconds())
 			return err
 		}
-	}
 
-	return nil
+		_, err := redis.DoContext(conn, ctx, "SET", c.key(str), value)
+		return err
+	})
 }
 
-func (c *RedisCache) Forget(str string) error {
-	key := fmt.Sprintf("%s:%s", c.Prefix, str)
-	conn := c.Conn.Get()
-	defer conn.Close()
+func (c *RedisCache) HasContext(ctx context.Context, str string) (bool, error) {
+	var ok bool
+	err := c.do(ctx, c.key(str), func(conn redis.Conn) error {
+		var err error
+		ok, err = redis.Bool(redis.DoContext(conn, ctx, "EXISTS", c.key(str)))
+		return err
+	})
 
-	_, err := conn.Do("DEL", key)
-	if err != nil {
+	return ok, err
+}
+
+func (c *RedisCache) ForgetContext(ctx context.Context, str string) error {
+	return c.do(ctx, c.key(str), func(conn redis.Conn) error {
+		_, err := redis.DoContext(conn, ctx, "DEL", c.key(str))
 		return err
+	})
+}
+
+// Check that the server, or every master of the cluster, is reachable.
+func (c *RedisCache) Ping(ctx context.Context) error {
+	if c.Cluster != nil {
+		return c.Cluster.Ping(ctx)
 	}
 
-	return nil
+	return c.do(ctx, "", func(conn redis.Conn) error {
+		_, err := redis.DoContext(conn, ctx, "PING")
+		return err
+	})
 }
 
-func (c *RedisCache) EmptyByMatch(str string) error {
-	key := fmt.Sprintf("%s:%s", c.Prefix, str)
-	conn := c.Conn.Get()
-	defer conn.Close()
+// Return the codec values are encoded with, JSON unless set.
+func (c *RedisCache) ValueCodec() cache.Codec {
+	if c.Codec == nil {
+		return cache.JSON
+	}
+	return c.Codec
+}
+
+// Close the connection pool, or the pools of the cluster, used by the cache.
+func (c *RedisCache) Close() error {
+	if c.Cluster != nil {
+		return c.Cluster.Close()
+	}
+	if c.Conn == nil {
+		return nil
+	}
+	return c.Conn.Close()
+}
 
-	keys, err := c.getKeys(key)
+// Encode a value the way Set stores it.
+func (c *RedisCache) encode(str string, value interface{}) ([]byte, error) {
+	entry := cache.Entry{}
+	entry[c.key(str)] = value
+	return cache.Encode(entry)
+}
+
+// Prefix the key with the cache prefix.
+func (c *RedisCache) key(str string) string {
+	return fmt.Sprintf("%s:%s", c.Prefix, str)
+}
+
+// Delete every key matching the pattern followed by any characters.
+func (c *RedisCache) deleteMatching(ctx context.Context, pattern string) error {
+	keys, err := c.getKeys(ctx, pattern)
 	if err != nil {
 		return err
 	}
 
 	for _, x := range keys {
-		_, err = conn.Do("DEL", x)
+		err = c.do(ctx, x, func(conn redis.Conn) error {
+			_, err := conn.Do("DEL", x)
+			return err
+		})
 		if err != nil {
 			return err
 		}
@@ -107,28 +188,34 @@ func (c *RedisCache) EmptyByMatch(str string) error {
 	return nil
 }
 
-func (c *RedisCache) Empty() error {
-	key := fmt.Sprintf("%s:", c.Prefix)
-	conn := c.Conn.Get()
-	defer conn.Close()
+// Find the keys matching the pattern followed by any characters on the server, or on
+// every master of the cluster, scanning again after a failover.
+func (c *RedisCache) getKeys(ctx context.Context, pattern string) ([]string, error) {
+	for attempt := 1; ; attempt++ {
+		keys := []string{}
+
+		pools, err := c.nodes(ctx)
+		for _, pool := range pools {
+			var found []string
+			if found, err = scan(pool, pattern); err != nil {
+				break
+			}
+			keys = append(keys, found...)
+		}
 
-	keys, err := c.getKeys(key)
-	if err != nil {
-		return err
-	}
+		if err == nil || attempt == maxAttempts || !failover(err) {
+			return keys, err
+		}
 
-	for _, x := range keys {
-		_, err = conn.Do("DEL", x)
-		if err != nil {
-			return err
+		if err := c.recover(ctx, attempt); err != nil {
+			return nil, err
 		}
 	}
-
-	return nil
 }
 
-func (c *RedisCache) getKeys(pattern string) ([]string, error) {
-	conn := c.Conn.Get()
+// Find the keys matching the pattern followed by any characters on the server.
+func scan(pool *redis.Pool, pattern string) ([]string, error) {
+	conn := pool.Get()
 	defer conn.Close()
 
 	iter := 0
@@ -152,6 +239,8 @@ func (c *RedisCache) getKeys(pattern string) ([]string, error) {
 	return keys, nil
 }
 
+// Create a pool connecting to a single server over plain TCP. Use NewPool for TLS, ACL
+// users, databases other than 0 and Sentinel.
 func CreateRedisPool(idel, active, timeout, host, password string) (*redis.Pool, error) {
 
 	maxIdle, err := strconv.Atoi(idel)
@@ -169,18 +258,11 @@ func CreateRedisPool(idel, active, timeout, host, password string) (*redis.Pool,
 		return nil, fmt.Errorf("invalid IdleTimeout value: %v", err)
 	}
 
-	return &redis.Pool{
+	return NewPool(Options{
+		Addrs:       []string{host},
+		Password:    password,
 		MaxIdle:     maxIdle,
 		MaxActive:   maxActive,
 		IdleTimeout: time.Duration(idleTimeout) * time.Second,
-		Dial: func() (redis.Conn, error) {
-			return redis.Dial("tcp", host,
-				redis.DialPassword(password))
-		},
-
-		TestOnBorrow: func(conn redis.Conn, t time.Time) error {
-			_, err := conn.Do("PING")
-			return err
-		},
-	}, nil
+	})
 }
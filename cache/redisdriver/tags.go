@@ -0,0 +1,113 @@
+package redisdriver
+
+import (
+	"context"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Store a value and add its key to the set of every tag in one step, so an entry is never
+// stored without its tags. A tag set expires with the longest lived of its entries, so
+// the keys of expired entries do not pile up in a tag that is never flushed. KEYS[1] is
+// the entry and the remaining keys are the tag sets; ARGV[1] is the value and ARGV[2] the
+// TTL in milliseconds, zero for none.
+var setTaggedScript = redis.NewScript(-1, `
+local ttl = tonumber(ARGV[2])
+if ttl > 0 then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ttl)
+else
+	redis.call("SET", KEYS[1], ARGV[1])
+end
+for i = 2, #KEYS do
+	local existed = redis.call("EXISTS", KEYS[i]) == 1
+	redis.call("SADD", KEYS[i], KEYS[1])
+	if ttl <= 0 then
+		redis.call("PERSIST", KEYS[i])
+	elseif not existed then
+		redis.call("PEXPIRE", KEYS[i], ttl)
+	else
+		local current = redis.call("PTTL", KEYS[i])
+		if current >= 0 and current < ttl then
+			redis.call("PEXPIRE", KEYS[i], ttl)
+		end
+	end
+end
+return 1
+`)
+
+// Delete every entry in the tag sets and the sets themselves in one step, so no write can
+// interleave with the flush.
+var flushTagsScript = redis.NewScript(-1, `
+for i = 1, #KEYS do
+	local members = redis.call("SMEMBERS", KEYS[i])
+	for j = 1, #members, 1000 do
+		redis.call("DEL", unpack(members, j, math.min(j + 999, #members)))
+	end
+	redis.call("DEL", KEYS[i])
+end
+return 1
+`)
+
+// Create a cache storing every entry with the tags. Example:
+//
+//	err := rc.Tags("user:42", "orders").Set("orders:user:42", orders, 3600)
+func (c *RedisCache) Tags(tags ...string) *cache.TaggedCache {
+	return cache.NewTagged(c, tags...)
+}
+
+// Store the value under the key like Set, adding the key to the set of each tag.
+func (c *RedisCache) SetTagged(str string, value interface{}, tags []string, expires ...int) error {
+	encoded, err := c.encode(str, value)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if len(expires) > 0 {
+		ttl = time.Duration(expires[0]) * time.Second
+	}
+
+	return c.SetTaggedContext(context.Background(), str, encoded, ttl, tags)
+}
+
+// Store the encoded value under the key like SetContext, adding the key to the set of
+// each tag. A tag set lives until it is flushed or its entries expire. In a cluster, the
+// key and its tags must share a hash slot, e.g., the key {user:42}:orders tagged
+// {user:42}.
+func (c *RedisCache) SetTaggedContext(ctx context.Context, str string, value []byte, ttl time.Duration, tags []string) error {
+	args := redis.Args{}.Add(1 + len(tags)).Add(c.key(str))
+	for _, tag := range tags {
+		args = args.Add(c.tagKey(tag))
+	}
+	args = args.Add(value, ttl.Milliseconds())
+
+	return c.do(ctx, c.key(str), func(conn redis.Conn) error {
+		_, err := setTaggedScript.DoContext(ctx, conn, args...)
+		return err
+	})
+}
+
+// Remove every entry stored with any of the tags. In a cluster, the tags and their keys
+// must share a hash slot.
+func (c *RedisCache) FlushTags(ctx context.Context, tags ...string) error {
+	if len(tags) == 0 {
+		return nil
+	}
+
+	args := redis.Args{}.Add(len(tags))
+	for _, tag := range tags {
+		args = args.Add(c.tagKey(tag))
+	}
+
+	return c.do(ctx, c.tagKey(tags[0]), func(conn redis.Conn) error {
+		_, err := flushTagsScript.DoContext(ctx, conn, args...)
+		return err
+	})
+}
+
+// Return the key of the set holding the keys stored with the tag.
+func (c *RedisCache) tagKey(tag string) string {
+	return c.key("tag:" + tag)
+}
@@ -0,0 +1,46 @@
+package redisdriver
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+
+	"github.com/gomodule/redigo/redis"
+)
+
+// Options describe how to connect to Redis, whether a single server, the master of a
+// Sentinel deployment or a Redis Cluster. Addrs holds the address of the server, of the
+// sentinels when MasterName is set or of any nodes of the cluster when Cluster is set.
+// A nil TLS config connects over plain TCP.
+type Options struct {
+	Addrs    []string
+	Username string
+	Password string
+	DB       int
+	TLS      *tls.Config
+
+	// The name of the master monitored by the sentinels and the credentials of the
+	// sentinels, when they differ from the credentials of the master.
+	MasterName       string
+	SentinelUsername string
+	SentinelPassword string
+
+	Cluster bool
+
+	MaxIdle      int
+	MaxActive    int
+	IdleTimeout  time.Duration
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+}
+
+// Cluster routes every key to the master of a Redis Cluster serving the hash slot of the
+// key, following the redirects of the cluster as slots move between nodes.
+type Cluster struct {
+	opts Options
+
+	mu    sync.RWMutex
+	slots []string
+	pools map[string]*redis.Pool
+}
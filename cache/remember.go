@@ -0,0 +1,211 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// Calls to Remember computing the same key in this process share one computation.
+var remembering singleflight.Group
+
+// Hold a lock shared by every process using a store implementing Locker while the value
+// is computed, so a single process in the cluster recomputes it. The other processes wait
+// up to wait for the value to appear before computing it themselves. The lock expires
+// after ttl in case its holder goes away.
+func WithLock(ttl, wait time.Duration) RememberOption {
+	return func(o *rememberOptions) {
+		o.lock = true
+		o.lockTTL = ttl
+		o.lockWait = wait
+	}
+}
+
+// Refresh the value before it expires with a probability that grows as the expiry
+// approaches and with the time the value takes to compute, so a busy key is recomputed
+// by one caller ahead of time rather than by every caller once it expires. A beta of 1 is
+// a good default; a higher beta refreshes earlier. Values stored with early refresh carry
+// the data the refresh needs and must only be read through Remember.
+func WithEarlyRefresh(beta float64) RememberOption {
+	return func(o *rememberOptions) {
+		o.beta = beta
+	}
+}
+
+// Get the value stored under the key or, when it is missing, compute it, store it for
+// the TTL and return it. Callers computing the same key as the same type within the
+// process wait for a single computation. Example:
+//
+//	user, err := cache.Remember(ctx, store, "user:42", time.Hour, func() (User, error) {
+//	    return models.Users.Get(42)
+//	})
+func Remember[T any](ctx context.Context, s Store, key string, ttl time.Duration, compute func() (T, error), opts ...RememberOption) (T, error) {
+	var o rememberOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	value, err := lookup[T](ctx, s, key, o, true)
+	if err == nil || !errors.Is(err, ErrMiss) {
+		return value, err
+	}
+
+	// The computation is shared, so it runs detached from the context of the caller that
+	// started it; every caller still stops waiting once its own context is done.
+	detached := context.WithoutCancel(ctx)
+	flight := fmt.Sprintf("%p:%v:%s", s, reflect.TypeFor[T](), key)
+
+	ch := remembering.DoChan(flight, func() (interface{}, error) {
+		if o.lock {
+			if locker, ok := lockerOf(s); ok {
+				return rememberLocked(detached, s, locker, key, ttl, compute, o)
+			}
+		}
+		return store(detached, s, key, ttl, compute, o)
+	})
+
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return zero, res.Err
+		}
+		value, ok := res.Val.(T)
+		if !ok {
+			return zero, fmt.Errorf("cache key %s was computed as %T, not %v", key, res.Val, reflect.TypeFor[T]())
+		}
+		return value, nil
+	}
+}
+
+// Find the Locker of the store or of a cache it wraps. An instrumented cache is only a
+// Locker when the cache it wraps is one.
+func lockerOf(s interface{}) (Locker, bool) {
+	for {
+		if i, ok := s.(*Instrumented); ok {
+			if _, ok := lockerOf(i.cache); ok {
+				return i, true
+			}
+			return nil, false
+		}
+
+		if locker, ok := s.(Locker); ok {
+			return locker, true
+		}
+
+		w, ok := s.(Wrapper)
+		if !ok {
+			return nil, false
+		}
+		s = w.Unwrap()
+	}
+}
+
+// Read the value from the store. With refresh set, a value due for an early refresh is
+// reported as a miss.
+func lookup[T any](ctx context.Context, s Store, key string, o rememberOptions, refresh bool) (T, error) {
+	var value T
+
+	if o.beta <= 0 {
+		return GetAs[T](ctx, s, key)
+	}
+
+	e, err := GetAs[envelope](ctx, s, key)
+	if err != nil {
+		return value, err
+	}
+
+	if refresh && e.Expires > 0 {
+		early := time.Duration(float64(e.Delta) * o.beta * -math.Log(1-rand.Float64()))
+		if !time.Now().Add(early).Before(time.Unix(0, e.Expires)) {
+			return value, ErrMiss
+		}
+	}
+
+	if err := CodecOf(s).Unmarshal(e.Value, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cache key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Compute the value and store it.
+func store[T any](ctx context.Context, s Store, key string, ttl time.Duration, compute func() (T, error), o rememberOptions) (T, error) {
+	start := time.Now()
+
+	value, err := compute()
+	if err != nil {
+		return value, err
+	}
+
+	if o.beta <= 0 {
+		return value, SetAs(ctx, s, key, value, ttl)
+	}
+
+	data, err := CodecOf(s).Marshal(value)
+	if err != nil {
+		return value, fmt.Errorf("failed to encode cache key %s: %w", key, err)
+	}
+
+	e := envelope{Value: data, Delta: int64(time.Since(start))}
+	if ttl > 0 {
+		e.Expires = time.Now().Add(ttl).UnixNano()
+	}
+
+	return value, SetAs(ctx, s, key, e, ttl)
+}
+
+// Compute the value while holding the lock on the key, or wait for the holder of the
+// lock to store it.
+func rememberLocked[T any](ctx context.Context, s Store, locker Locker, key string, ttl time.Duration, compute func() (T, error), o rememberOptions) (T, error) {
+	lockTTL := o.lockTTL
+	if lockTTL <= 0 {
+		lockTTL = 10 * time.Second
+	}
+
+	lock, err := locker.Lock(ctx, "lock:"+key, lockTTL)
+	if err == nil {
+		defer lock.Release(context.WithoutCancel(ctx))
+
+		// The value may have been stored while the lock was taken.
+		if value, err := lookup[T](ctx, s, key, o, true); err == nil {
+			return value, nil
+		}
+
+		return store(ctx, s, key, ttl, compute, o)
+	}
+
+	if !errors.Is(err, ErrNotAcquired) {
+		var zero T
+		return zero, err
+	}
+
+	deadline := time.Now().Add(o.lockWait)
+	for time.Now().Before(deadline) {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		// The holder of the lock is refreshing the value, so any stored value will do.
+		value, err := lookup[T](ctx, s, key, o, false)
+		if err == nil {
+			return value, nil
+		}
+		if !errors.Is(err, ErrMiss) {
+			return value, err
+		}
+	}
+
+	return store(ctx, s, key, ttl, compute, o)
+}
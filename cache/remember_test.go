@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// A store and locker backed by a map, safe for concurrent use.
+type syncStore struct {
+	mu     sync.Mutex
+	values map[string][]byte
+	locked map[string]bool
+}
+
+type syncLock struct {
+	s   *syncStore
+	key string
+}
+
+func newSyncStore() *syncStore {
+	return &syncStore{values: map[string][]byte{}, locked: map[string]bool{}}
+}
+
+func (s *syncStore) GetContext(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.values[key]
+	if !ok {
+		return nil, ErrMiss
+	}
+	return v, nil
+}
+
+func (s *syncStore) SetContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.values[key] = value
+	return nil
+}
+
+func (s *syncStore) HasContext(ctx context.Context, key string) (bool, error) {
+	_, err := s.GetContext(ctx, key)
+	return err == nil, nil
+}
+
+func (s *syncStore) ForgetContext(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.values, key)
+	return nil
+}
+
+func (s *syncStore) Lock(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.locked[key] {
+		return nil, ErrNotAcquired
+	}
+	s.locked[key] = true
+	return &syncLock{s: s, key: key}, nil
+}
+
+func (l *syncLock) Release(ctx context.Context) error {
+	l.s.mu.Lock()
+	defer l.s.mu.Unlock()
+	delete(l.s.locked, l.key)
+	return nil
+}
+
+func (l *syncLock) Extend(ctx context.Context, ttl time.Duration) error {
+	return nil
+}
+
+func TestRemember_SingleComputation(t *testing.T) {
+	ctx := context.Background()
+	s := newSyncStore()
+
+	var calls int32
+	compute := func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(20 * time.Millisecond)
+		return 42, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := Remember(ctx, s, "answer", time.Minute, compute)
+			if err != nil || v != 42 {
+				t.Errorf("Remember() = %v, %v; want 42", v, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("compute called %d times, want 1", calls)
+	}
+
+	// A stored value is returned without computing.
+	if v, _ := Remember(ctx, s, "answer", time.Minute, compute); v != 42 || calls != 1 {
+		t.Errorf("Remember() = %v after %d calls; want the stored value", v, calls)
+	}
+}
+
+func TestRemember_TypesAndCancellation(t *testing.T) {
+	s := newSyncStore()
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The first caller gives up while the value is computed.
+	first := make(chan error, 1)
+	go func() {
+		_, err := Remember(ctx, s, "shared", time.Minute, func() (int, error) {
+			close(started)
+			<-release
+			return 42, nil
+		})
+		first <- err
+	}()
+	<-started
+
+	// A caller of another type on the same key runs its own computation.
+	failure := errors.New("computed as a string")
+	if _, err := Remember(context.Background(), s, "shared", time.Minute, func() (string, error) {
+		return "", failure
+	}); !errors.Is(err, failure) {
+		t.Errorf("Remember[string]() error = %v, want its own computation", err)
+	}
+
+	second := make(chan int, 1)
+	go func() {
+		v, _ := Remember(context.Background(), s, "shared", time.Minute, func() (int, error) {
+			return 0, errors.New("should wait for the first computation")
+		})
+		second <- v
+	}()
+
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller error = %v, want context.Canceled", err)
+	}
+
+	close(release)
+	if v := <-second; v != 42 {
+		t.Errorf("waiting caller = %d, want the computation of the cancelled caller", v)
+	}
+}
+
+func TestRemember_ComputeError(t *testing.T) {
+	s := newSyncStore()
+	failure := errors.New("boom")
+
+	_, err := Remember(context.Background(), s, "key", time.Minute, func() (string, error) {
+		return "", failure
+	})
+	if !errors.Is(err, failure) {
+		t.Errorf("expected the compute error, got %v", err)
+	}
+
+	if ok, _ := s.HasContext(context.Background(), "key"); ok {
+		t.Error("a failed computation should not be stored")
+	}
+}
+
+func TestRemember_WaitsForLockHolder(t *testing.T) {
+	ctx := context.Background()
+	s := newSyncStore()
+
+	// Another process holds the lock and stores the value shortly.
+	lock, _ := s.Lock(ctx, "lock:report", time.Minute)
+	go func() {
+		time.Sleep(60 * time.Millisecond)
+		SetAs(ctx, s, "report", "from another process", time.Minute)
+		lock.Release(ctx)
+	}()
+
+	v, err := Remember(ctx, s, "report", time.Minute, func() (string, error) {
+		return "computed", nil
+	}, WithLock(time.Second, time.Second))
+
+	if err != nil || v != "from another process" {
+		t.Errorf("Remember() = %q, %v; want the value stored by the lock holder", v, err)
+	}
+}
+
+func TestRemember_EarlyRefresh(t *testing.T) {
+	ctx := context.Background()
+	s := newSyncStore()
+
+	compute := func(v int) func() (int, error) {
+		return func() (int, error) { return v, nil }
+	}
+
+	if v, err := Remember(ctx, s, "key", time.Hour, compute(1), WithEarlyRefresh(1)); err != nil || v != 1 {
+		t.Fatalf("Remember() = %v, %v; want 1", v, err)
+	}
+
+	// A value far from expiry is not refreshed.
+	if v, _ := Remember(ctx, s, "key", time.Hour, compute(2), WithEarlyRefresh(1)); v != 1 {
+		t.Errorf("Remember() = %v; want the stored value", v)
+	}
+
+	// A value past its expiry is always refreshed.
+	e, _ := GetAs[envelope](ctx, s, "key")
+	e.Expires = time.Now().Add(-time.Second).UnixNano()
+	SetAs(ctx, s, "key", e, 0)
+
+	if v, _ := Remember(ctx, s, "key", time.Hour, compute(3), WithEarlyRefresh(1)); v != 3 {
+		t.Errorf("Remember() = %v; want the refreshed value", v)
+	}
+}
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS cache_entries (
+    cache_key VARCHAR(255) NOT NULL PRIMARY KEY,
+    cache_value LONGBLOB NOT NULL,
+    expires_at DATETIME(6) NULL,
+    INDEX cache_entries_expires_at_idx (expires_at)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_bin;
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS cache_entries (
+    cache_key VARCHAR(255) PRIMARY KEY,
+    cache_value BYTEA NOT NULL,
+    expires_at TIMESTAMPTZ NULL
+);
+
+CREATE INDEX IF NOT EXISTS cache_entries_expires_at_idx ON cache_entries (expires_at);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS cache_entries (
+    cache_key TEXT NOT NULL PRIMARY KEY,
+    cache_value BLOB NOT NULL,
+    expires_at TIMESTAMP NULL
+);
+
+CREATE INDEX IF NOT EXISTS cache_entries_expires_at_idx ON cache_entries (expires_at);
@@ -0,0 +1,69 @@
+package sqldriver
+
+import (
+	"context"
+	"log"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+var testSQLCache *SQLCache
+
+func TestMain(m *testing.M) {
+	ctx := context.Background()
+
+	// Start Postgres container
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	if err != nil {
+		log.Fatalf("Failed to start Postgres container: %v", err)
+	}
+	defer container.Terminate(ctx)
+
+	// Get connection details
+	host, err := container.Host(ctx)
+	if err != nil {
+		log.Fatalf("Failed to get host: %v", err)
+	}
+
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		log.Fatalf("Failed to get port: %v", err)
+	}
+
+	pool, err := database.OpenDB("postgres", &database.DataSourceName{
+		Host:         host,
+		Port:         port.Port(),
+		User:         "testuser",
+		Password:     "testpass",
+		DatabaseName: "testdb",
+		SslMode:      "disable",
+	})
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer pool.Close()
+
+	testSQLCache = New(&database.Database{DataType: "postgres", Pool: pool})
+
+	if err := testSQLCache.Migrate(ctx); err != nil {
+		log.Fatalf("Failed to migrate cache table: %v", err)
+	}
+
+	os.Exit(m.Run())
+}
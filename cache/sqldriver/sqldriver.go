@@ -0,0 +1,241 @@
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/database"
+)
+
+// The table holding the cache entries.
+const Table = "cache_entries"
+
+// The table the applied cache migrations are recorded in.
+const MigrationsTable = "cache_schema_migrations"
+
+// Migrations creating and dropping the cache table, named
+// <version>_<name>.<postgres|mysql|sqlite>.<up|down>.sql.
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// The character escaping the wildcards of a LIKE pattern; unlike a backslash it means the
+// same to Postgres, MySQL and SQLite.
+const likeEscape = "!"
+
+// Create a cache on the connection pool of the database. Run Migrate, or the migrations
+// in Migrations, before the cache is used. Example:
+//
+//	sc := sqldriver.New(app.DB)
+//	if err := sc.Migrate(ctx); err != nil {
+//	    return err
+//	}
+func New(db *database.Database) *SQLCache {
+	return &SQLCache{
+		Conn:   db.Pool,
+		Driver: database.Driver(db.DataType),
+	}
+}
+
+// Create the cache table by applying the migrations for the database that have not been
+// applied. The applied versions are recorded in the MigrationsTable, apart from the
+// migrations of the application, so those neither list nor revert the cache table.
+func (s *SQLCache) Migrate(ctx context.Context) error {
+	return s.Migrator().Up(ctx)
+}
+
+// Return a migrator applying the migrations in Migrations to the database of the cache.
+// Example:
+//
+//	if err := sc.Migrator().Down(ctx, 1); err != nil {
+//	    return err
+//	}
+func (s *SQLCache) Migrator() *database.Migrator {
+	// The directory is embedded, so it is always there.
+	fsys, _ := fs.Sub(Migrations, "migrations")
+
+	db := &database.Database{DataType: s.Driver, Pool: s.Conn}
+	m := db.Migrator(fsys)
+	m.Table = MigrationsTable
+
+	return m
+}
+
+func (s *SQLCache) Has(str string) (bool, error) {
+	return s.HasContext(context.Background(), str)
+}
+
+func (s *SQLCache) Get(str string) (interface{}, error) {
+	value, err := s.GetContext(context.Background(), str)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := cache.Decode(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return decoded[str], nil
+}
+
+func (s *SQLCache) Set(str string, value interface{}, expires ...int) error {
+	entry := cache.Entry{}
+	entry[str] = value
+
+	encoded, err := cache.Encode(entry)
+	if err != nil {
+		return err
+	}
+
+	var ttl time.Duration
+	if len(expires) > 0 {
+		ttl = time.Duration(expires[0]) * time.Second
+	}
+
+	return s.SetContext(context.Background(), str, encoded, ttl)
+}
+
+func (s *SQLCache) Forget(str string) error {
+	return s.ForgetContext(context.Background(), str)
+}
+
+// Remove every entry whose key starts with the string.
+func (s *SQLCache) EmptyByMatch(str string) error {
+	_, err := s.exec(context.Background(), "DELETE FROM "+Table+" WHERE cache_key LIKE ? ESCAPE '"+likeEscape+"'", escapeLike(str)+"%")
+	return err
+}
+
+func (s *SQLCache) Empty() error {
+	_, err := s.exec(context.Background(), "DELETE FROM "+Table)
+	return err
+}
+
+// Get the encoded value stored under the key, returning cache.ErrMiss when the key is
+// not in the cache or has expired.
+func (s *SQLCache) GetContext(ctx context.Context, str string) ([]byte, error) {
+	query, err := s.bind("SELECT cache_value FROM " + Table + " WHERE cache_key = ? AND (expires_at IS NULL OR expires_at > ?)")
+	if err != nil {
+		return nil, err
+	}
+
+	var value []byte
+	err = s.Conn.QueryRowContext(ctx, query, str, s.clock()).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, cache.ErrMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return value, nil
+}
+
+// Store the encoded value under the key, replacing any value stored before. A TTL of
+// zero stores the value without expiry.
+func (s *SQLCache) SetContext(ctx context.Context, str string, value []byte, ttl time.Duration) error {
+	var expires sql.NullTime
+	if ttl > 0 {
+		expires = sql.NullTime{Time: s.clock().Add(ttl), Valid: true}
+	}
+
+	var upsert string
+	switch s.Driver {
+	case "pgx", "sqlite":
+		upsert = "INSERT INTO " + Table + " (cache_key, cache_value, expires_at) VALUES (?, ?, ?) " +
+			"ON CONFLICT (cache_key) DO UPDATE SET cache_value = EXCLUDED.cache_value, expires_at = EXCLUDED.expires_at"
+	case "mysql":
+		upsert = "INSERT INTO " + Table + " (cache_key, cache_value, expires_at) VALUES (?, ?, ?) " +
+			"ON DUPLICATE KEY UPDATE cache_value = VALUES(cache_value), expires_at = VALUES(expires_at)"
+	}
+
+	_, err := s.exec(ctx, upsert, str, value, expires)
+	return err
+}
+
+func (s *SQLCache) HasContext(ctx context.Context, str string) (bool, error) {
+	_, err := s.GetContext(ctx, str)
+	if errors.Is(err, cache.ErrMiss) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SQLCache) ForgetContext(ctx context.Context, str string) error {
+	_, err := s.exec(ctx, "DELETE FROM "+Table+" WHERE cache_key = ?", str)
+	return err
+}
+
+// Return the codec values are encoded with, JSON unless set.
+func (s *SQLCache) ValueCodec() cache.Codec {
+	if s.Codec == nil {
+		return cache.JSON
+	}
+	return s.Codec
+}
+
+// Delete the expired entries, returning the number deleted. Expired entries are never
+// read, so this only reclaims their space; schedule it to keep the table small. Example:
+//
+//	app.Scheduler.AddFunc("@every 15m", func() {
+//	    sc.PurgeExpired(context.Background())
+//	})
+func (s *SQLCache) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := s.exec(ctx, "DELETE FROM "+Table+" WHERE expires_at IS NOT NULL AND expires_at <= ?", s.clock())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// Run a statement written with ? placeholders.
+func (s *SQLCache) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	query, err := s.bind(query)
+	if err != nil {
+		return nil, err
+	}
+	return s.Conn.ExecContext(ctx, query, args...)
+}
+
+// Rewrite the ? placeholders of the query into the form the driver expects.
+func (s *SQLCache) bind(query string) (string, error) {
+	switch s.Driver {
+	case "mysql", "sqlite":
+		return query, nil
+	case "pgx":
+		var b strings.Builder
+		n := 0
+		for _, r := range query {
+			if r == '?' {
+				n++
+				b.WriteString("$" + strconv.Itoa(n))
+				continue
+			}
+			b.WriteRune(r)
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("%w: %s", database.ErrUnsupportedDriver, s.Driver)
+	}
+}
+
+// Return the current time in UTC, the time zone expiry is stored in.
+func (s *SQLCache) clock() time.Time {
+	if s.now != nil {
+		return s.now().UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Escape the wildcards of a LIKE pattern so the string matches literally.
+func escapeLike(str string) string {
+	return strings.NewReplacer(likeEscape, likeEscape+likeEscape, "%", likeEscape+"%", "_", likeEscape+"_").Replace(str)
+}
@@ -0,0 +1,167 @@
+package sqldriver
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/database"
+)
+
+func TestSQLCache_SetGet(t *testing.T) {
+	if err := testSQLCache.Set("foo", "bar"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Storing the key again replaces the value.
+	if err := testSQLCache.Set("foo", "baz"); err != nil {
+		t.Fatal(err)
+	}
+
+	x, err := testSQLCache.Get("foo")
+	if err != nil || x != "baz" {
+		t.Errorf("Get() = %v, %v; want baz", x, err)
+	}
+
+	if err := testSQLCache.Forget("foo"); err != nil {
+		t.Fatal(err)
+	}
+
+	if inCache, _ := testSQLCache.Has("foo"); inCache {
+		t.Error("foo found in cache after it was forgotten")
+	}
+
+	if _, err := testSQLCache.Get("foo"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected cache.ErrMiss, got %v", err)
+	}
+}
+
+func TestSQLCache_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	testSQLCache.now = func() time.Time { return now }
+	defer func() { testSQLCache.now = nil }()
+
+	testSQLCache.Empty()
+	testSQLCache.SetContext(ctx, "short", []byte("1"), time.Minute)
+	testSQLCache.SetContext(ctx, "forever", []byte("1"), 0)
+
+	now = now.Add(time.Hour)
+
+	if inCache, _ := testSQLCache.HasContext(ctx, "short"); inCache {
+		t.Error("short did not expire")
+	}
+	if inCache, _ := testSQLCache.HasContext(ctx, "forever"); !inCache {
+		t.Error("forever expired")
+	}
+
+	n, err := testSQLCache.PurgeExpired(ctx)
+	if err != nil || n != 1 {
+		t.Errorf("PurgeExpired() = %d, %v; want 1", n, err)
+	}
+}
+
+func TestSQLCache_EmptyByMatch(t *testing.T) {
+	for _, key := range []string{"user:1", "user:2", "users", "user_1", "post:1"} {
+		testSQLCache.Set(key, key)
+	}
+
+	if err := testSQLCache.EmptyByMatch("user:"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, want := range map[string]bool{"user:1": false, "user:2": false, "users": true, "user_1": true, "post:1": true} {
+		if got, _ := testSQLCache.Has(key); got != want {
+			t.Errorf("Has(%s) = %v, want %v", key, got, want)
+		}
+	}
+
+	// An underscore only matches itself.
+	testSQLCache.EmptyByMatch("user_")
+	if got, _ := testSQLCache.Has("users"); !got {
+		t.Error("users should not match user_")
+	}
+
+	testSQLCache.Empty()
+	if got, _ := testSQLCache.Has("post:1"); got {
+		t.Error("post:1 found after Empty()")
+	}
+}
+
+func TestSQLCache_TypedValues(t *testing.T) {
+	ctx := context.Background()
+
+	if err := cache.SetAs(ctx, testSQLCache, "count", 7, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := cache.GetAs[int](ctx, testSQLCache, "count")
+	if err != nil || count != 7 {
+		t.Errorf("GetAs[int]() = %v, %v; want 7", count, err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	s := SQLCache{Driver: "pgx"}
+	if got, _ := s.bind("DELETE FROM t WHERE a = ? AND b > ?"); got != "DELETE FROM t WHERE a = $1 AND b > $2" {
+		t.Errorf("bind() = %s", got)
+	}
+
+	s.Driver = "mysql"
+	if got, _ := s.bind("DELETE FROM t WHERE a = ?"); got != "DELETE FROM t WHERE a = ?" {
+		t.Errorf("bind() = %s", got)
+	}
+
+	s.Driver = "sqlite"
+	if got, _ := s.bind("DELETE FROM t WHERE a = ?"); got != "DELETE FROM t WHERE a = ?" {
+		t.Errorf("bind() = %s", got)
+	}
+
+	s.Driver = "sqlserver"
+	if _, err := s.bind("SELECT 1"); !errors.Is(err, database.ErrUnsupportedDriver) {
+		t.Errorf("expected database.ErrUnsupportedDriver, got %v", err)
+	}
+
+	if got := escapeLike("50%_off!"); got != "50!%!_off!!" {
+		t.Errorf("escapeLike() = %s", got)
+	}
+}
+
+func TestSQLCache_Migrate_Sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	pool, err := database.OpenDB("sqlite", &database.DataSourceName{DatabaseName: filepath.Join(t.TempDir(), "cache.db")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	s := New(&database.Database{DataType: "sqlite", Pool: pool})
+
+	// Migrating again applies nothing.
+	for i := 0; i < 2; i++ {
+		if err := s.Migrate(ctx); err != nil {
+			t.Fatalf("Migrate() error = %v", err)
+		}
+	}
+
+	status, err := s.Migrator().Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(status) != 1 || !status[0].Applied {
+		t.Errorf("Status() = %+v, want the cache table migration applied", status)
+	}
+
+	if err := s.Set("user", "alice", 60); err != nil {
+		t.Fatal(err)
+	}
+
+	var recorded int
+	if err := pool.QueryRow("SELECT COUNT(*) FROM " + MigrationsTable).Scan(&recorded); err != nil || recorded != 1 {
+		t.Errorf("recorded migrations = %d, %v; want 1", recorded, err)
+	}
+}
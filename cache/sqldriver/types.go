@@ -0,0 +1,20 @@
+package sqldriver
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+)
+
+// SQLCache stores the cache in the cache_entries table of the application database, so
+// instances sharing a Postgres or MySQL database share the cache without running Redis.
+// Driver is the database/sql driver of the connection pool, i.e., pgx or mysql. The pool
+// belongs to the database and is not closed by the cache.
+type SQLCache struct {
+	Conn   *sql.DB
+	Driver string
+	Codec  cache.Codec
+
+	now func() time.Time
+}
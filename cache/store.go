@@ -0,0 +1,118 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrMiss is returned when a key is not in the cache.
+var ErrMiss = errors.New("cache miss")
+
+// ErrNotAcquired is returned by Lock when the key is locked by someone else.
+var ErrNotAcquired = errors.New("lock not acquired")
+
+// ErrNotHeld is returned when a lock is released or extended after it expired.
+var ErrNotHeld = errors.New("lock not held")
+
+// ErrUnsupported is returned by a decorator when the cache it wraps does not support the
+// operation, e.g., locks.
+var ErrUnsupported = errors.New("operation not supported by the cache")
+
+// Get the value stored under the key decoded into the type T with the codec of the
+// store. The error wraps ErrMiss when the key is not in the cache. Example:
+//
+//	user, err := cache.GetAs[User](ctx, app.Cache.(cache.Store), "user:42")
+//	if errors.Is(err, cache.ErrMiss) {
+//	    // Load the user from the database.
+//	}
+func GetAs[T any](ctx context.Context, s Store, key string) (T, error) {
+	var value T
+
+	data, err := s.GetContext(ctx, key)
+	if err != nil {
+		return value, err
+	}
+
+	if err := CodecOf(s).Unmarshal(data, &value); err != nil {
+		return value, fmt.Errorf("failed to decode cache key %s: %w", key, err)
+	}
+
+	return value, nil
+}
+
+// Store the value under the key, encoded with the codec of the store. A TTL of zero
+// stores the value without expiry. Example:
+//
+//	err := cache.SetAs(ctx, app.Cache.(cache.Store), "user:42", user, time.Hour)
+func SetAs[T any](ctx context.Context, s Store, key string, value T, ttl time.Duration) error {
+	data, err := CodecOf(s).Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode cache key %s: %w", key, err)
+	}
+
+	return s.SetContext(ctx, key, data, ttl)
+}
+
+// Adapt a cache implementing the original Cache interface to a Store. A cache that is
+// already a Store is returned as is. Encoded values are kept in the cache as strings and
+// the TTL is rounded up to whole seconds.
+func Adapt(c Cache) Store {
+	if s, ok := c.(Store); ok {
+		return s
+	}
+	return &Adapter{Cache: c}
+}
+
+func (a *Adapter) GetContext(ctx context.Context, key string) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	value, err := a.Cache.Get(key)
+	if err != nil {
+		if ok, hasErr := a.Cache.Has(key); hasErr == nil && !ok {
+			return nil, ErrMiss
+		}
+		return nil, err
+	}
+
+	switch v := value.(type) {
+	case string:
+		return []byte(v), nil
+	case []byte:
+		return v, nil
+	case nil:
+		return nil, ErrMiss
+	default:
+		return nil, fmt.Errorf("cache key %s holds a %T rather than an encoded value", key, value)
+	}
+}
+
+func (a *Adapter) SetContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if ttl > 0 {
+		seconds := int((ttl + time.Second - 1) / time.Second)
+		return a.Cache.Set(key, string(value), seconds)
+	}
+
+	return a.Cache.Set(key, string(value))
+}
+
+func (a *Adapter) HasContext(ctx context.Context, key string) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+	return a.Cache.Has(key)
+}
+
+func (a *Adapter) ForgetContext(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.Cache.Forget(key)
+}
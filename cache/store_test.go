@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// A cache implementing the original interface backed by a map.
+type mapCache map[string]interface{}
+
+func (m mapCache) Has(key string) (bool, error) {
+	_, ok := m[key]
+	return ok, nil
+}
+
+func (m mapCache) Get(key string) (interface{}, error) {
+	v, ok := m[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return v, nil
+}
+
+func (m mapCache) Set(key string, value interface{}, expires ...int) error {
+	m[key] = value
+	return nil
+}
+
+func (m mapCache) Forget(key string) error {
+	delete(m, key)
+	return nil
+}
+
+func (m mapCache) EmptyByMatch(string) error { return nil }
+
+func (m mapCache) Empty() error { return nil }
+
+type user struct {
+	ID    int
+	Name  string
+	Roles []string
+}
+
+func TestCodecs_RoundTrip(t *testing.T) {
+	in := user{ID: 42, Name: "Ada", Roles: []string{"admin"}}
+
+	for _, name := range []string{"json", "gob", "msgpack"} {
+		codec, err := CodecByName(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := codec.Marshal(in)
+		if err != nil {
+			t.Fatalf("%s: Marshal() error = %v", name, err)
+		}
+
+		var out user
+		if err := codec.Unmarshal(data, &out); err != nil {
+			t.Fatalf("%s: Unmarshal() error = %v", name, err)
+		}
+
+		if out.ID != in.ID || out.Name != in.Name || len(out.Roles) != 1 {
+			t.Errorf("%s: got %+v, want %+v", name, out, in)
+		}
+	}
+
+	if _, err := CodecByName("xml"); err == nil {
+		t.Error("expected an error for an unsupported codec")
+	}
+}
+
+func TestGetAs_TypedValues(t *testing.T) {
+	ctx := context.Background()
+	s := Adapt(mapCache{})
+
+	if err := SetAs(ctx, s, "count", 7, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetAs(ctx, s, "user", user{ID: 1, Name: "Ada"}, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := GetAs[int](ctx, s, "count")
+	if err != nil || count != 7 {
+		t.Errorf("GetAs[int]() = %v, %v; want 7", count, err)
+	}
+
+	u, err := GetAs[user](ctx, s, "user")
+	if err != nil || u.Name != "Ada" {
+		t.Errorf("GetAs[user]() = %+v, %v; want Ada", u, err)
+	}
+
+	if _, err := GetAs[int](ctx, s, "missing"); !errors.Is(err, ErrMiss) {
+		t.Errorf("expected ErrMiss, got %v", err)
+	}
+}
+
+func TestAdapter_Context(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	s := Adapt(mapCache{})
+	if err := s.SetContext(ctx, "key", []byte("value"), 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+}
@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// Create a cache storing every entry in the store with the tags. Drivers implementing
+// Tagger expose it as their Tags method. Example:
+//
+//	err := rc.Tags("user:42", "orders").Set("orders:user:42", orders, 3600)
+//	...
+//	err = rc.FlushTags(ctx, "user:42")
+func NewTagged(s Tagger, tags ...string) *TaggedCache {
+	return &TaggedCache{store: s, tags: tags}
+}
+
+// Store the value under the key with the tags of the cache.
+func (t *TaggedCache) Set(key string, value interface{}, expires ...int) error {
+	return t.store.SetTagged(key, value, t.tags, expires...)
+}
+
+// Remove every entry stored with any of the tags of the cache.
+func (t *TaggedCache) Flush(ctx context.Context) error {
+	return t.store.FlushTags(ctx, t.tags...)
+}
+
+func (t *TaggedCache) GetContext(ctx context.Context, key string) ([]byte, error) {
+	return t.store.GetContext(ctx, key)
+}
+
+// Store the encoded value under the key with the tags of the cache.
+func (t *TaggedCache) SetContext(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return t.store.SetTaggedContext(ctx, key, value, ttl, t.tags)
+}
+
+func (t *TaggedCache) HasContext(ctx context.Context, key string) (bool, error) {
+	return t.store.HasContext(ctx, key)
+}
+
+func (t *TaggedCache) ForgetContext(ctx context.Context, key string) error {
+	return t.store.ForgetContext(ctx, key)
+}
+
+// Return the codec of the underlying store.
+func (t *TaggedCache) ValueCodec() Codec {
+	return CodecOf(t.store)
+}
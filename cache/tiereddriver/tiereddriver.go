@@ -0,0 +1,319 @@
+package tiereddriver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/cache/memorydriver"
+)
+
+// The TTL of an entry in the L1 when none is given.
+const DefaultTTL = 5 * time.Second
+
+// Create a cache keeping the entries read from the L2 in the L1 for at most the TTL.
+// Example:
+//
+//	tc := tiereddriver.New(memorydriver.New(10000, 64<<20), &rc, 5*time.Second)
+//	tc.Invalidator = redisdriver.NewInvalidator(rc.Conn, "myapp:invalidate")
+//	tc.Listen()
+func New(l1 *memorydriver.MemoryCache, l2 Backend, ttl time.Duration) *TieredCache {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+
+	id := make([]byte, 16)
+	rand.Read(id)
+
+	return &TieredCache{
+		L1:  l1,
+		L2:  l2,
+		TTL: ttl,
+		id:  hex.EncodeToString(id),
+	}
+}
+
+func (t *TieredCache) Has(str string) (bool, error) {
+	return t.HasContext(context.Background(), str)
+}
+
+func (t *TieredCache) Get(str string) (interface{}, error) {
+	if value, err := t.L1.Get(str); err == nil {
+		return value, nil
+	}
+
+	value, err := t.L2.Get(str)
+	if err != nil {
+		return nil, err
+	}
+
+	t.keep(str, value, t.TTL)
+
+	return value, nil
+}
+
+func (t *TieredCache) Set(str string, value interface{}, expires ...int) error {
+	if err := t.L2.Set(str, value, expires...); err != nil {
+		return err
+	}
+
+	ttl := t.TTL
+	if len(expires) > 0 && expires[0] > 0 {
+		ttl = t.ttl(time.Duration(expires[0]) * time.Second)
+	}
+	t.keep(str, value, ttl)
+
+	return t.publish(context.Background(), cache.Invalidation{Keys: []string{str}})
+}
+
+func (t *TieredCache) Forget(str string) error {
+	return t.ForgetContext(context.Background(), str)
+}
+
+func (t *TieredCache) EmptyByMatch(str string) error {
+	if err := t.L2.EmptyByMatch(str); err != nil {
+		return err
+	}
+
+	t.L1.EmptyByMatch(str)
+
+	return t.publish(context.Background(), cache.Invalidation{Pattern: str})
+}
+
+func (t *TieredCache) Empty() error {
+	if err := t.L2.Empty(); err != nil {
+		return err
+	}
+
+	t.L1.Empty()
+
+	return t.publish(context.Background(), cache.Invalidation{All: true})
+}
+
+// Get the encoded value stored under the key from the L1, reading it from the L2 when
+// it is not there.
+func (t *TieredCache) GetContext(ctx context.Context, str string) ([]byte, error) {
+	if value, err := t.L1.GetContext(ctx, str); err == nil {
+		return value, nil
+	}
+
+	value, err := t.L2.GetContext(ctx, str)
+	if err != nil {
+		return nil, err
+	}
+
+	t.L1.SetContext(ctx, str, value, t.TTL)
+
+	return value, nil
+}
+
+// Store the encoded value under the key in both tiers and publish the key so the other
+// processes evict it from their L1.
+func (t *TieredCache) SetContext(ctx context.Context, str string, value []byte, ttl time.Duration) error {
+	if err := t.L2.SetContext(ctx, str, value, ttl); err != nil {
+		return err
+	}
+
+	t.L1.SetContext(ctx, str, value, t.ttl(ttl))
+
+	return t.publish(ctx, cache.Invalidation{Keys: []string{str}})
+}
+
+func (t *TieredCache) HasContext(ctx context.Context, str string) (bool, error) {
+	if ok, err := t.L1.HasContext(ctx, str); err == nil && ok {
+		return true, nil
+	}
+	return t.L2.HasContext(ctx, str)
+}
+
+// Remove the key from both tiers and publish the key so the other processes evict it
+// from their L1.
+func (t *TieredCache) ForgetContext(ctx context.Context, str string) error {
+	if err := t.L2.ForgetContext(ctx, str); err != nil {
+		return err
+	}
+
+	t.L1.ForgetContext(ctx, str)
+
+	return t.publish(ctx, cache.Invalidation{Keys: []string{str}})
+}
+
+// Return the codec values are encoded with by the L2.
+func (t *TieredCache) ValueCodec() cache.Codec {
+	return cache.CodecOf(t.L2)
+}
+
+// Return the L2, which holds the locks and counters of the cache.
+func (t *TieredCache) Unwrap() cache.Cache {
+	return t.L2
+}
+
+// Create a cache storing every entry with the tags, when the L2 supports tags. Example:
+//
+//	err := tc.Tags("user:42").Set("orders:user:42", orders, 3600)
+func (t *TieredCache) Tags(tags ...string) *cache.TaggedCache {
+	return cache.NewTagged(t, tags...)
+}
+
+// Store the value under the key in both tiers like Set, tagging it in the L2.
+func (t *TieredCache) SetTagged(str string, value interface{}, tags []string, expires ...int) error {
+	tagger, err := t.tagger()
+	if err != nil {
+		return err
+	}
+
+	if err := tagger.SetTagged(str, value, tags, expires...); err != nil {
+		return err
+	}
+
+	ttl := t.TTL
+	if len(expires) > 0 && expires[0] > 0 {
+		ttl = t.ttl(time.Duration(expires[0]) * time.Second)
+	}
+	t.keep(str, value, ttl)
+
+	return t.publish(context.Background(), cache.Invalidation{Keys: []string{str}})
+}
+
+// Store the encoded value under the key in both tiers like SetContext, tagging it in the
+// L2.
+func (t *TieredCache) SetTaggedContext(ctx context.Context, str string, value []byte, ttl time.Duration, tags []string) error {
+	tagger, err := t.tagger()
+	if err != nil {
+		return err
+	}
+
+	if err := tagger.SetTaggedContext(ctx, str, value, ttl, tags); err != nil {
+		return err
+	}
+
+	t.L1.SetContext(ctx, str, value, t.ttl(ttl))
+
+	return t.publish(ctx, cache.Invalidation{Keys: []string{str}})
+}
+
+// Remove every entry stored with any of the tags from the L2. The L1 does not know the
+// tags of the entries it copied, so it is emptied, here and in the other processes.
+func (t *TieredCache) FlushTags(ctx context.Context, tags ...string) error {
+	tagger, err := t.tagger()
+	if err != nil {
+		return err
+	}
+
+	if err := tagger.FlushTags(ctx, tags...); err != nil {
+		return err
+	}
+
+	t.L1.Empty()
+
+	return t.publish(ctx, cache.Invalidation{All: true})
+}
+
+// Return the L2 as a Tagger, or an error when it does not support tags.
+func (t *TieredCache) tagger() (cache.Tagger, error) {
+	tagger, ok := t.L2.(cache.Tagger)
+	if !ok {
+		return nil, fmt.Errorf("cache %T does not support tags", t.L2)
+	}
+	return tagger, nil
+}
+
+// Evict the keys invalidated by the other processes from the L1 until the cache is
+// closed. Listen does nothing without an Invalidator or when the cache is already
+// listening.
+func (t *TieredCache) Listen() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.Invalidator == nil || t.cancel != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.cancel = cancel
+	t.done = make(chan struct{})
+
+	go func() {
+		defer close(t.done)
+		t.Invalidator.Subscribe(ctx, t.apply)
+	}()
+}
+
+// Stop listening for invalidations and close the L2.
+func (t *TieredCache) Close() error {
+	t.mu.Lock()
+	if t.cancel != nil {
+		t.cancel()
+		<-t.done
+		t.cancel = nil
+	}
+	t.mu.Unlock()
+
+	if closer, ok := t.L2.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Evict the keys of an invalidation published by another process from the L1.
+func (t *TieredCache) apply(inv cache.Invalidation) {
+	if inv.Origin == t.id {
+		return
+	}
+
+	if inv.All {
+		t.L1.Empty()
+		return
+	}
+
+	if inv.Pattern != "" {
+		t.L1.EmptyByMatch(inv.Pattern)
+	}
+
+	for _, key := range inv.Keys {
+		t.L1.Forget(key)
+	}
+}
+
+// Publish the invalidation to the other processes. The change is already stored in the
+// L2, so the error only means other processes may read a stale entry from their L1 until
+// its TTL passes.
+func (t *TieredCache) publish(ctx context.Context, inv cache.Invalidation) error {
+	if t.Invalidator == nil {
+		return nil
+	}
+
+	inv.Origin = t.id
+	if err := t.Invalidator.Publish(ctx, inv); err != nil {
+		return fmt.Errorf("cache invalidation not published: %w", err)
+	}
+
+	return nil
+}
+
+// Store a value of the original Cache interface in the L1, encoded the way Set encodes
+// it, without rounding the TTL to whole seconds.
+func (t *TieredCache) keep(str string, value interface{}, ttl time.Duration) {
+	entry := cache.Entry{}
+	entry[str] = value
+
+	encoded, err := cache.Encode(entry)
+	if err != nil {
+		return
+	}
+
+	t.L1.SetContext(context.Background(), str, encoded, ttl)
+}
+
+// Return the TTL of an entry in the L1, the TTL of the cache unless the entry expires
+// sooner.
+func (t *TieredCache) ttl(ttl time.Duration) time.Duration {
+	if ttl > 0 && ttl < t.TTL {
+		return ttl
+	}
+	return t.TTL
+}
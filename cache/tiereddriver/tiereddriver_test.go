@@ -0,0 +1,223 @@
+package tiereddriver
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/cache/memorydriver"
+)
+
+// An invalidator delivering every invalidation to the subscribers of the process.
+type testBus struct {
+	mu          sync.Mutex
+	subscribers []func(cache.Invalidation)
+	subscribed  chan struct{}
+}
+
+func (b *testBus) Publish(ctx context.Context, inv cache.Invalidation) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, fn := range b.subscribers {
+		fn(inv)
+	}
+	return nil
+}
+
+func (b *testBus) Subscribe(ctx context.Context, fn func(cache.Invalidation)) error {
+	b.mu.Lock()
+	b.subscribers = append(b.subscribers, fn)
+	b.mu.Unlock()
+
+	b.subscribed <- struct{}{}
+	<-ctx.Done()
+	return nil
+}
+
+// A memory cache tagging its entries, standing in for Redis as the L2.
+type taggingCache struct {
+	*memorydriver.MemoryCache
+	mu   sync.Mutex
+	tags map[string][]string
+}
+
+func (c *taggingCache) SetTagged(key string, value interface{}, tags []string, expires ...int) error {
+	c.tag(key, tags)
+	return c.Set(key, value, expires...)
+}
+
+func (c *taggingCache) SetTaggedContext(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error {
+	c.tag(key, tags)
+	return c.SetContext(ctx, key, value, ttl)
+}
+
+func (c *taggingCache) FlushTags(ctx context.Context, tags ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		for _, key := range c.tags[tag] {
+			c.Forget(key)
+		}
+		delete(c.tags, tag)
+	}
+	return nil
+}
+
+func (c *taggingCache) tag(key string, tags []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, tag := range tags {
+		c.tags[tag] = append(c.tags[tag], key)
+	}
+}
+
+// Create two tiered caches sharing an L2 and an invalidator, as two processes would.
+func newProcesses(t *testing.T) (*TieredCache, *TieredCache, *memorydriver.MemoryCache) {
+	l2 := memorydriver.New(0, 0)
+	a, b := newProcessesWith(t, l2)
+	return a, b, l2
+}
+
+// Create two tiered caches sharing the L2 and an invalidator.
+func newProcessesWith(t *testing.T, l2 Backend) (*TieredCache, *TieredCache) {
+	bus := &testBus{subscribed: make(chan struct{})}
+
+	var processes []*TieredCache
+	for i := 0; i < 2; i++ {
+		tc := New(memorydriver.New(0, 0), l2, time.Minute)
+		tc.Invalidator = bus
+		tc.Listen()
+		<-bus.subscribed
+
+		t.Cleanup(func() { tc.Close() })
+		processes = append(processes, tc)
+	}
+
+	return processes[0], processes[1]
+}
+
+func TestTieredCache_ReadsThroughL1(t *testing.T) {
+	ctx := context.Background()
+	tc := New(memorydriver.New(0, 0), memorydriver.New(0, 0), time.Minute)
+
+	if err := cache.SetAs(ctx, tc, "user", "adele", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changed behind the back of the tiered cache, so only the L1 holds the old value.
+	if err := cache.SetAs(ctx, tc.L2, "user", "changed", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if v, err := cache.GetAs[string](ctx, tc, "user"); err != nil || v != "adele" {
+		t.Errorf("GetAs() = %v, %v; want the value held by the L1", v, err)
+	}
+
+	tc.L1.Empty()
+	if v, _ := cache.GetAs[string](ctx, tc, "user"); v != "changed" {
+		t.Errorf("GetAs() = %v, want the value read from the L2", v)
+	}
+	if ok, _ := tc.L1.Has("user"); !ok {
+		t.Error("the value read from the L2 should be kept in the L1")
+	}
+
+	if _, err := tc.GetContext(ctx, "missing"); !errors.Is(err, cache.ErrMiss) {
+		t.Errorf("expected cache.ErrMiss, got %v", err)
+	}
+}
+
+func TestTieredCache_L1ExpiresFirst(t *testing.T) {
+	ctx := context.Background()
+	tc := New(memorydriver.New(0, 0), memorydriver.New(0, 0), time.Millisecond)
+
+	if err := tc.SetContext(ctx, "user", []byte("adele"), time.Hour); err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+
+	if ok, _ := tc.L1.Has("user"); ok {
+		t.Error("the entry should have expired from the L1")
+	}
+	if ok, _ := tc.Has("user"); !ok {
+		t.Error("the entry should still be in the L2")
+	}
+}
+
+func TestTieredCache_Invalidation(t *testing.T) {
+	ctx := context.Background()
+	a, b, _ := newProcesses(t)
+
+	if err := a.Set("user", "adele"); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := b.Get("user"); v != "adele" {
+		t.Fatalf("Get() = %v, want adele", v)
+	}
+
+	if err := a.Set("user", "changed"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := a.L1.Has("user"); !ok {
+		t.Error("the process setting the key should keep it in its L1")
+	}
+	if v, _ := b.Get("user"); v != "changed" {
+		t.Errorf("Get() = %v, want the value set by the other process", v)
+	}
+
+	if err := a.ForgetContext(ctx, "user"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := b.Has("user"); ok {
+		t.Error("the key forgotten by the other process should be evicted")
+	}
+
+	b.SetContext(ctx, "orders:1", []byte("1"), 0)
+	b.SetContext(ctx, "orders:2", []byte("2"), 0)
+	if err := a.EmptyByMatch("orders:"); err != nil {
+		t.Fatal(err)
+	}
+	if n := b.L1.Len(); n != 0 {
+		t.Errorf("L1 of the other process holds %d entries, want 0", n)
+	}
+}
+
+func TestTieredCache_FlushTags(t *testing.T) {
+	ctx := context.Background()
+	a, b := newProcessesWith(t, &taggingCache{MemoryCache: memorydriver.New(0, 0), tags: map[string][]string{}})
+
+	if err := a.Tags("user:42").Set("orders:42", "orders"); err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.SetAs(ctx, a.Tags("orders"), "orders:7", 7, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := b.Get("orders:42"); v != "orders" {
+		t.Fatalf("Get() = %v, want orders", v)
+	}
+
+	if err := a.FlushTags(ctx, "user:42"); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []*TieredCache{a, b} {
+		if ok, _ := tc.Has("orders:42"); ok {
+			t.Error("the flushed entry is still in the cache")
+		}
+		if n := tc.L1.Len(); n != 0 {
+			t.Errorf("L1 holds %d entries after the flush, want 0", n)
+		}
+	}
+	if n, err := cache.GetAs[int](ctx, b, "orders:7"); err != nil || n != 7 {
+		t.Errorf("GetAs() = %d, %v; want the entry of the other tag", n, err)
+	}
+
+	plain := New(memorydriver.New(0, 0), memorydriver.New(0, 0), time.Minute)
+	if err := plain.FlushTags(ctx, "user:42"); err == nil {
+		t.Error("expected an error flushing tags of an L2 without tags")
+	}
+}
@@ -0,0 +1,35 @@
+package tiereddriver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/cidekar/adele-framework/cache/memorydriver"
+)
+
+// Backend is the shared cache a TieredCache keeps a local copy of, e.g., Redis or Badger.
+type Backend interface {
+	cache.Cache
+	cache.Store
+}
+
+// TieredCache keeps a short lived copy of the entries of a shared cache, the L2, in an
+// in-memory cache, the L1, so a hot key is read from the shared cache once per TTL rather
+// than on every read. Writes go through to the L2 and are published by the Invalidator,
+// when set, so the other processes evict the key from their L1; without an invalidator
+// they may read a stale entry until its TTL passes.
+type TieredCache struct {
+	L1          *memorydriver.MemoryCache
+	L2          Backend
+	TTL         time.Duration
+	Invalidator cache.Invalidator
+
+	// Identifies the invalidations published by this cache.
+	id string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
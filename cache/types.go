@@ -1,5 +1,13 @@
 package cache
 
+import (
+	"context"
+	"time"
+
+	"github.com/cidekar/adele-framework/metrics"
+	"github.com/sirupsen/logrus"
+)
+
 type Cache interface {
 	Has(string) (bool, error)
 	Get(string) (interface{}, error)
@@ -10,3 +18,152 @@ type Cache interface {
 }
 
 type Entry map[string]interface{}
+
+// Store is the context aware, byte level cache implemented by the drivers. Values are
+// encoded by a Codec before they are stored, so typed values survive the round trip; use
+// GetAs and SetAs rather than encoding values by hand. GetContext returns ErrMiss when
+// the key is not in the cache, and a TTL of zero stores the value without expiry.
+type Store interface {
+	GetContext(ctx context.Context, key string) ([]byte, error)
+	SetContext(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	HasContext(ctx context.Context, key string) (bool, error)
+	ForgetContext(ctx context.Context, key string) error
+}
+
+// Codec encodes values to bytes for a Store and decodes them back into typed values.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+// Coded is implemented by a store that chooses the codec its values are encoded with.
+type Coded interface {
+	ValueCodec() Codec
+}
+
+// JSONCodec encodes values as JSON.
+type JSONCodec struct{}
+
+// GobCodec encodes values with encoding/gob. Concrete types stored in interface values
+// must be registered with gob.Register.
+type GobCodec struct{}
+
+// MsgpackCodec encodes values as MessagePack.
+type MsgpackCodec struct{}
+
+// Adapter exposes a cache implementing the original Cache interface as a Store.
+type Adapter struct {
+	Cache Cache
+}
+
+// Locker is implemented by a store that can hold a lock shared by every process using
+// it, e.g., Redis, so a single process recomputes a missing value.
+type Locker interface {
+	Lock(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a lock held on a key until it is released or its TTL passes; a TTL of zero
+// never passes. Extend resets the TTL of a lock that is still held. Both return
+// ErrNotHeld once the lock has expired.
+type Lock interface {
+	Release(ctx context.Context) error
+	Extend(ctx context.Context, ttl time.Duration) error
+}
+
+// Counter is implemented by a store that can atomically change an integer held by a key.
+// A key that does not exist counts from zero and the TTL, when not zero, is set on a
+// counter without an expiry, so a counter created with a TTL keeps its window as it
+// changes. Read a counter by incrementing it by zero.
+type Counter interface {
+	Increment(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+	Decrement(ctx context.Context, key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// RememberOption configures Remember.
+type RememberOption func(*rememberOptions)
+
+// Options set by the RememberOption functions.
+type rememberOptions struct {
+	lock     bool
+	lockTTL  time.Duration
+	lockWait time.Duration
+	beta     float64
+}
+
+// A value stored by Remember with early refresh, along with the time it took to compute
+// and when it expires, both in nanoseconds.
+type envelope struct {
+	Value   []byte
+	Delta   int64
+	Expires int64
+}
+
+// Tagger is implemented by a store that can tag the entries it stores, so every entry
+// with a tag can be removed at once with FlushTags. SetTagged stores a value the way
+// Cache.Set does and SetTaggedContext stores an encoded value the way SetContext does.
+type Tagger interface {
+	Store
+	SetTagged(key string, value interface{}, tags []string, expires ...int) error
+	SetTaggedContext(ctx context.Context, key string, value []byte, ttl time.Duration, tags []string) error
+	FlushTags(ctx context.Context, tags ...string) error
+}
+
+// TaggedCache stores every entry with its tags. It is a Store, so GetAs, SetAs and
+// Remember tag the entries they store through it.
+type TaggedCache struct {
+	store Tagger
+	tags  []string
+}
+
+// Invalidator broadcasts the keys changed in a cache shared by several processes, so
+// every process keeping a local copy of the cache evicts them. Subscribe calls the
+// function with every invalidation published until the context is cancelled.
+type Invalidator interface {
+	Publish(ctx context.Context, inv Invalidation) error
+	Subscribe(ctx context.Context, fn func(Invalidation)) error
+}
+
+// Invalidation names the keys to evict from a local copy of a cache. Pattern evicts every
+// key matching it the way EmptyByMatch does and All evicts every key. Origin identifies
+// the process that published the invalidation.
+type Invalidation struct {
+	Origin  string   `json:"origin"`
+	Keys    []string `json:"keys,omitempty"`
+	Pattern string   `json:"pattern,omitempty"`
+	All     bool     `json:"all,omitempty"`
+}
+
+// Wrapper is implemented by a cache decorating another cache, e.g., with metrics or a
+// local tier, so the cache it wraps can be reached.
+type Wrapper interface {
+	Unwrap() Cache
+}
+
+// Evictor is implemented by a cache evicting entries on its own to stay within its
+// limits, e.g., the memory driver.
+type Evictor interface {
+	OnEvict(fn func(key string))
+}
+
+// Instrumented records the hits, misses, sets, evictions, errors and latency of the
+// operations on a cache in a metrics registry and, when Log is set, logs every operation
+// at the debug level. Locks, counters and tags are forwarded to the cache it wraps and
+// fail with ErrUnsupported when that cache does not implement them.
+type Instrumented struct {
+	Log *logrus.Logger
+
+	cache   Cache
+	store   Store
+	name    string
+	metrics cacheMetrics
+}
+
+// The metrics recorded by Instrumented, labelled with the name of the cache.
+type cacheMetrics struct {
+	hits      *metrics.CounterVec
+	misses    *metrics.CounterVec
+	sets      *metrics.CounterVec
+	evictions *metrics.CounterVec
+	errors    *metrics.CounterVec
+	duration  *metrics.HistogramVec
+}
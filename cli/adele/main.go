@@ -83,6 +83,13 @@ func (c *Cli) Handle() error {
 			return err
 		}
 
+	case "seed":
+		c := NewSeed()
+		err := c.Handle()
+		if err != nil {
+			return err
+		}
+
 	case "version":
 		c := NewVersion()
 		err := c.Handle()
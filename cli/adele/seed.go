@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/fatih/color"
+)
+
+var SeedCommand = &Command{
+	Name:        "seed",
+	Help:        "Seed the database",
+	Description: "Run the seeders of the application, or the named seeders and the seeders they depend on, through the seed command of the application in cmd/seed",
+	Usage:       "adele seed [seeders...]",
+	Examples:    []string{"adele seed", "adele seed users posts"},
+	Options:     map[string]string{},
+}
+
+// The package of the application running its seeders, relative to its root.
+var seedPackage = filepath.Join("cmd", "seed")
+
+// Register command on package init
+func init() {
+	if err := Registry.Register(SeedCommand); err != nil {
+		panic(fmt.Sprintf("Failed to register seed command: %v", err))
+	}
+}
+
+type Seed struct{}
+
+func NewSeed() *Seed {
+	return &Seed{}
+}
+
+// Validate checks the current directory is the root of an application with a seed
+// command.
+func (c *Seed) Validate() error {
+	if !fileExists(filepath.Join(seedPackage, "main.go")) {
+		return errors.New("no seed command found; create cmd/seed/main.go calling seed.Main in the root of the application")
+	}
+	return nil
+}
+
+// Handle runs the seed command of the application with the names of the seeders given
+// after the command name.
+func (c *Seed) Handle() error {
+	if err := c.Validate(); err != nil {
+		return err
+	}
+
+	args := append([]string{"run", "./" + filepath.ToSlash(seedPackage)}, Registry.GetArgs()[1:]...)
+
+	color.Yellow("Seeding the database...")
+
+	cmd := exec.Command("go", args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("seeding failed: %w", err)
+	}
+
+	color.Yellow("Done")
+	return nil
+}
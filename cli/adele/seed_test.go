@@ -0,0 +1,32 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSeedValidate(t *testing.T) {
+	root := t.TempDir()
+	wd, _ := os.Getwd()
+	defer os.Chdir(wd)
+
+	if err := os.Chdir(root); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewSeed().Validate(); err == nil {
+		t.Error("Validate() error = nil outside of an application with a seed command")
+	}
+
+	if err := os.MkdirAll(filepath.Join("cmd", "seed"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join("cmd", "seed", "main.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewSeed().Validate(); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+}
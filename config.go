@@ -0,0 +1,352 @@
+package adele
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache/badgerdriver"
+	"github.com/cidekar/adele-framework/cache/redisdriver"
+	"github.com/cidekar/adele-framework/config"
+	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/filesystem/miniofilesystem"
+	"github.com/cidekar/adele-framework/filesystem/s3filesystem"
+	"github.com/cidekar/adele-framework/filesystem/sftpfilesystem"
+	"github.com/cidekar/adele-framework/filesystem/webdavfilesystem"
+	"github.com/cidekar/adele-framework/metrics"
+	"github.com/sirupsen/logrus"
+)
+
+// Build the application configuration from the environment variables, usually parsed
+// from the .env file at the root of the application. Any value that is missing or
+// malformed is replaced with the framework default.
+func ConfigFromEnv() Config {
+	cfg, _ := decodeConfig(config.ParseEnviron(os.Environ()).Reader())
+	return cfg
+}
+
+// Load the application configuration by merging, from the lowest to the highest
+// precedence, config/app.yml, config/app.<APP_ENV>.yml, the .env file, the environment
+// variables and any --KEY=value flag in args. The result is validated for every enabled
+// subsystem and a single error lists every missing or malformed value.
+// Example:
+//
+//	cfg, err := adele.LoadConfig(rootPath, os.Args[1:]...)
+func LoadConfig(rootPath string, args ...string) (Config, error) {
+	values, err := (&config.Loader{RootPath: rootPath, Args: args}).Load()
+	if err != nil {
+		return Config{}, err
+	}
+
+	return decodeConfig(values.Reader())
+}
+
+// The keys read from the environment by packages not yet configured through Config, i.e.,
+// the logger, the rate limiter, the maintenance and recover middleware and the cache
+// helpers, which New exports from the merged configuration.
+var legacyEnvKeys = []string{
+	"APP_NAME", "DEBUG", "LOG_FORMAT", "LOG_LEVEL",
+	"HTTP_RATE_LIMIT", "HTTP_RATE_DURATION", "MAINTENANCE_URL",
+	"CACHE", "SESSION_TYPE", "QUEUE_TYPE",
+}
+
+// Decode the configuration from a reader and validate it, returning every problem
+// found as one error.
+func decodeConfig(r *config.Reader) (Config, error) {
+	cfg := Config{
+		AppName:          r.String("APP_NAME"),
+		Debug:            r.Bool("APP_DEBUG", false),
+		Renderer:         r.String("RENDERER", "jet"),
+		ViewsTemplateDir: r.String("VIEWS_TEMPLATE_DIR", "resources/views"),
+		Database: DatabaseConfig{
+			Type:     r.String("DATABASE_TYPE"),
+			Host:     r.String("DATABASE_HOST", "localhost"),
+			Port:     r.String("DATABASE_PORT", "5432"),
+			User:     r.String("DATABASE_USER"),
+			Password: r.String("DATABASE_PASSWORD"),
+			Name:     r.String("DATABASE_NAME"),
+			SSLMode:  r.String("DATABASE_SSL_MODE"),
+
+			// An unreachable database is retried DATABASE_CONNECT_RETRIES times at
+			// startup, waiting DATABASE_CONNECT_BACKOFF, doubled after every attempt.
+			ConnectRetries: r.Int("DATABASE_CONNECT_RETRIES", 0),
+			ConnectBackoff: r.Seconds("DATABASE_CONNECT_BACKOFF", time.Second),
+
+			// A warning is logged when DATABASE_WAIT_WARNING queries wait for a free
+			// connection within a minute.
+			MaxOpenConns:    r.Int("DATABASE_MAX_OPEN_CONNS", database.DefaultMaxOpenConns),
+			MaxIdleConns:    r.Int("DATABASE_MAX_IDLE_CONNS", database.DefaultMaxIdleConns),
+			ConnMaxLifetime: r.Seconds("DATABASE_CONN_MAX_LIFETIME", database.DefaultConnMaxLifetime),
+			ConnMaxIdleTime: r.Seconds("DATABASE_CONN_MAX_IDLE_TIME", 0),
+			PingTimeout:     r.Seconds("DATABASE_PING_TIMEOUT", database.DefaultPingTimeout),
+			WaitWarning:     r.Int("DATABASE_WAIT_WARNING", 10),
+
+			// SQLite only; a relative DATABASE_NAME is a path from the application root.
+			BusyTimeout: r.Seconds("DATABASE_BUSY_TIMEOUT", database.DefaultBusyTimeout),
+			JournalMode: r.String("DATABASE_JOURNAL_MODE", "WAL"),
+
+			// Every statement is logged with DATABASE_LOG_QUERIES, and the statements
+			// taking DATABASE_SLOW_QUERY or longer as a warning.
+			LogQueries:           r.Bool("DATABASE_LOG_QUERIES", false),
+			LogQueryArgs:         r.Bool("DATABASE_LOG_QUERY_ARGS", false),
+			SlowQuery:            r.Seconds("DATABASE_SLOW_QUERY", time.Second),
+			RepeatedQueryWarning: r.Int("DATABASE_REPEATED_QUERY_WARNING", 10),
+
+			// Comma separated read replicas, as host or host:port, pinged every
+			// DATABASE_REPLICA_CHECK_INTERVAL to eject the unhealthy ones.
+			Replicas:             r.List("DATABASE_REPLICAS"),
+			ReplicaPolicy:        r.String("DATABASE_REPLICA_POLICY", database.RoundRobin),
+			StickyWindow:         r.Seconds("DATABASE_STICKY_WINDOW", database.DefaultStickyWindow),
+			ReplicaCheckInterval: r.Seconds("DATABASE_REPLICA_CHECK_INTERVAL", 10*time.Second),
+		},
+		Cache: CacheConfig{
+			Driver:   r.String("CACHE"),
+			Codec:    r.String("CACHE_CODEC", "json"),
+			LocalTTL: r.Seconds("CACHE_LOCAL_TTL", 0),
+			Redis: RedisConfig{
+				URL:         r.String("REDIS_URL"),
+				Host:        r.String("REDIS_HOST", "localhost"),
+				Port:        r.String("REDIS_PORT", "6380"),
+				Username:    r.String("REDIS_USERNAME"),
+				Password:    r.String("REDIS_PASSWORD"),
+				DB:          r.Int("REDIS_DB", 0),
+				TLS:         r.Bool("REDIS_TLS", false),
+				Prefix:      r.String("REDIS_PREFIX", r.String("APP_NAME")),
+				MaxIdle:     r.Int("REDIS_MAX_IDLE", r.Int("REDIS_MAX_IDEL", 50)),
+				MaxActive:   r.Int("REDIS_MAX_ACTIVE_CONNECTIONS", 10000),
+				IdleTimeout: r.Seconds("REDIS_TIMEOUT", 240*time.Second),
+			},
+			Badger: BadgerConfig{
+				Path:           r.String("BADGER_PATH", "resources/badger"),
+				InMemory:       r.Bool("BADGER_IN_MEMORY", false),
+				EncryptionKey:  r.String("BADGER_ENCRYPTION_KEY"),
+				GCInterval:     r.Seconds("BADGER_GC_INTERVAL", 24*time.Hour),
+				GCDiscardRatio: r.Float("BADGER_GC_DISCARD_RATIO", badgerdriver.DefaultGCDiscardRatio),
+				MaxSize:        int64(r.Int("BADGER_MAX_SIZE", 0)),
+			},
+			Memory: MemoryConfig{
+				MaxEntries: r.Int("MEMORY_CACHE_MAX_ENTRIES", 10000),
+				MaxBytes:   int64(r.Int("MEMORY_CACHE_MAX_BYTES", 64<<20)),
+			},
+		},
+		Mail: MailConfig{
+			Domain:      r.String("MAIL_DOMAIN"),
+			Host:        r.String("SMTP_HOST"),
+			Port:        r.Int("SMTP_PORT", 1025),
+			Username:    r.String("SMTP_USERNAME"),
+			Password:    r.String("SMTP_PASSWORD"),
+			Encryption:  r.String("SMTP_ENCRYPTION"),
+			FromName:    r.String("MAILER_FROM_NAME"),
+			FromAddress: r.String("MAILER_FROM_ADDRESS"),
+			API:         r.String("MAILER_API"),
+			APIKey:      r.String("MAILER_KEY"),
+			APIURL:      r.String("MAILER_URL"),
+		},
+		Session: SessionConfig{
+			Type:           r.String("SESSION_TYPE"),
+			CookieName:     r.String("COOKIE_NAME", "adele"),
+			CookieDomain:   r.String("COOKIE_DOMAIN", "localhost"),
+			CookieLifetime: r.Int("COOKIE_LIFETIME", 1),
+			CookiePersist:  r.Bool("COOKIE_PERSIST", true),
+			CookieSecure:   r.Bool("COOKIE_SECURE", false),
+		},
+		HTTP: HTTPConfig{
+			Port:         r.String("HTTP_PORT", "4000"),
+			ReadTimeout:  r.Seconds("HTTP_READ_TIMEOUT", 30*time.Second),
+			WriteTimeout: r.Seconds("HTTP_WRITE_TIMEOUT", 600*time.Second),
+			IdleTimeout:  r.Seconds("HTTP_IDLE_TIMEOUT", 30*time.Second),
+			RateLimit:    r.Int("HTTP_RATE_LIMIT", 100),
+			RateDuration: time.Duration(r.Int("HTTP_RATE_DURATION", 1)) * time.Minute,
+		},
+		RPC: RPCConfig{
+			Disabled: r.String("RPC_SERVER_DISABLE") != "",
+			Addr:     r.String("RPC_SERVER_ADDR", "127.0.0.1"),
+			Port:     r.String("RPC_SERVER_PORT", "4040"),
+		},
+		Filesystem: FilesystemConfig{
+			S3: s3filesystem.S3{
+				Key:      r.String("S3_KEY"),
+				Secret:   r.String("S3_SECRET"),
+				Region:   r.String("S3_REGION"),
+				Endpoint: r.String("S3_ENDPOINT"),
+				Bucket:   r.String("S3_BUCKET"),
+			},
+			Minio: miniofilesystem.Minio{
+				Endpoint: r.String("MINIO_ENDPOINT"),
+				Key:      r.String("MINIO_KEY"),
+				Secret:   r.String("MINIO_SECRET"),
+				UseSSL:   r.Bool("MINIO_USESSL", false),
+				Region:   r.String("MINIO_REGION"),
+				Bucket:   r.String("MINIO_BUCKET"),
+			},
+			SFTP: sftpfilesystem.SFTP{
+				Host:     r.String("SFTP_HOST"),
+				User:     r.String("SFTP_USER"),
+				Password: r.String("SFTP_PASSWORD"),
+				Port:     r.String("SFTP_PORT"),
+			},
+			WebDAV: webdavfilesystem.WebDAV{
+				Host:     r.String("WEBDAV_HOST"),
+				User:     r.String("WEBDAV_USER"),
+				Password: r.String("WEBDAV_PASSWORD"),
+			},
+		},
+		Upload: UploadConfig{
+			MaxSize:          int64(r.Int("FILE_MAX_UPLOAD_SIZE", 10<<20)),
+			AllowedMimeTypes: r.List("FILE_TYPES_ALLOWED"),
+		},
+		Health: HealthConfig{
+			Disabled: r.Bool("HEALTH_CHECK_DISABLE", false),
+			Timeout:  r.Seconds("HEALTH_CHECK_TIMEOUT", 5*time.Second),
+		},
+		Metrics: MetricsConfig{
+			Enabled: r.Bool("METRICS_ENABLE", false),
+			Path:    r.String("METRICS_PATH", metrics.Path),
+		},
+		ShutdownTimeout: r.Seconds("SHUTDOWN_TIMEOUT", 30*time.Second),
+	}
+
+	validateConfig(r)
+
+	return cfg, r.Err()
+}
+
+// Validate the keys required by every enabled subsystem and the values that must be one
+// of a known set. Problems are recorded on the reader so they are reported together.
+func validateConfig(r *config.Reader) {
+	r.OneOf("RENDERER", "jet", "go")
+	r.OneOf("CACHE", "redis", "badger", "database", "memory")
+	r.OneOf("CACHE_CODEC", "json", "gob", "msgpack")
+	r.OneOf("SESSION_TYPE", "cookie", "redis", "mysql", "mariadb", "postgres", "postgresql", "sqlite", "sqlite3")
+	r.OneOf("DATABASE_TYPE", "postgres", "postgresql", "pgx", "mysql", "mariadb", "sqlite", "sqlite3")
+	r.OneOf("DATABASE_JOURNAL_MODE", "WAL", "DELETE", "TRUNCATE", "PERSIST", "MEMORY", "OFF")
+	r.OneOf("DATABASE_REPLICA_POLICY", database.RoundRobin, database.LeastConnections)
+
+	for _, key := range []string{"HTTP_PORT", "RPC_SERVER_PORT", "DATABASE_PORT", "REDIS_PORT", "SFTP_PORT"} {
+		validatePort(r, key)
+	}
+
+	if raw := r.String("REDIS_URL"); raw != "" {
+		if _, err := redisdriver.ParseURL(raw); err != nil {
+			r.Problemf("REDIS_URL: %v", err)
+		}
+	}
+
+	if key := r.String("BADGER_ENCRYPTION_KEY"); key != "" && len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		r.Problemf("BADGER_ENCRYPTION_KEY must be 16, 24 or 32 bytes long; got %d", len(key))
+	}
+	if ratio := r.Float("BADGER_GC_DISCARD_RATIO", badgerdriver.DefaultGCDiscardRatio); ratio <= 0 || ratio >= 1 {
+		r.Problemf("BADGER_GC_DISCARD_RATIO must be between 0 and 1; got %v", ratio)
+	}
+
+	if r.String("CACHE") == "database" {
+		r.Require("DATABASE_TYPE", "when CACHE is database")
+	}
+
+	if t := r.String("SESSION_TYPE"); (t == "sqlite" || t == "sqlite3") && database.Driver(r.String("DATABASE_TYPE")) != "sqlite" {
+		r.Problemf("SESSION_TYPE %s requires a SQLite DATABASE_TYPE", t)
+	}
+
+	if dbType := r.String("DATABASE_TYPE"); dbType != "" {
+		// SQLite is a file, named by DATABASE_NAME, without users.
+		if database.Driver(dbType) != "sqlite" {
+			r.Require("DATABASE_USER", "when DATABASE_TYPE is set")
+		}
+		r.Require("DATABASE_NAME", "when DATABASE_TYPE is set")
+	}
+
+	if api := r.String("MAILER_API"); api != "" && api != "smtp" {
+		r.OneOf("MAILER_API", "smtp", "mailgun", "sparkpost", "sendgrid")
+		r.Require("MAILER_KEY", "when MAILER_API is set")
+		r.Require("MAILER_URL", "when MAILER_API is set")
+	}
+
+	if r.String("S3_KEY") != "" {
+		r.Require("S3_SECRET", "when S3_KEY is set")
+		r.Require("S3_REGION", "when S3_KEY is set")
+		r.Require("S3_BUCKET", "when S3_KEY is set")
+	}
+
+	if r.String("MINIO_SECRET") != "" {
+		r.Require("MINIO_ENDPOINT", "when MINIO_SECRET is set")
+		r.Require("MINIO_KEY", "when MINIO_SECRET is set")
+		r.Require("MINIO_BUCKET", "when MINIO_SECRET is set")
+	}
+
+	if r.String("SFTP_HOST") != "" {
+		r.Require("SFTP_USER", "when SFTP_HOST is set")
+		r.Require("SFTP_PORT", "when SFTP_HOST is set")
+	}
+}
+
+// Record a problem when the key holds a value that is not a valid TCP port.
+func validatePort(r *config.Reader, key string) {
+	raw := r.String(key)
+	if raw == "" {
+		return
+	}
+	if port, err := strconv.Atoi(raw); err != nil || port < 1 || port > 65535 {
+		r.Problemf("%s must be a port between 1 and 65535; got %q", key, raw)
+	}
+}
+
+// Return a copy of the configuration the application was bootstrapped with.
+func (a *Adele) Config() Config {
+	return a.config
+}
+
+// Replace the database section of the configuration.
+func WithDatabase(c DatabaseConfig) Option {
+	return func(a *Adele) {
+		a.config.Database = c
+	}
+}
+
+// Replace the cache section of the configuration.
+func WithCache(c CacheConfig) Option {
+	return func(a *Adele) {
+		a.config.Cache = c
+	}
+}
+
+// Replace the mail section of the configuration.
+func WithMail(c MailConfig) Option {
+	return func(a *Adele) {
+		a.config.Mail = c
+	}
+}
+
+// Replace the session section of the configuration.
+func WithSession(c SessionConfig) Option {
+	return func(a *Adele) {
+		a.config.Session = c
+	}
+}
+
+// Replace the HTTP section of the configuration.
+func WithHTTP(c HTTPConfig) Option {
+	return func(a *Adele) {
+		a.config.HTTP = c
+	}
+}
+
+// Replace the RPC section of the configuration.
+func WithRPC(c RPCConfig) Option {
+	return func(a *Adele) {
+		a.config.RPC = c
+	}
+}
+
+// Replace the file system section of the configuration.
+func WithFilesystem(c FilesystemConfig) Option {
+	return func(a *Adele) {
+		a.config.Filesystem = c
+	}
+}
+
+// Use the given logger instead of creating one from the environment.
+func WithLogger(l *logrus.Logger) Option {
+	return func(a *Adele) {
+		a.Log = l
+	}
+}
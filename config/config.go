@@ -0,0 +1,408 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v2"
+)
+
+// Load and merge every configuration source of the application. Missing files are
+// skipped; files that exist but cannot be parsed return an error.
+// Example:
+//
+//	values, err := (&config.Loader{RootPath: rootPath, Args: os.Args[1:]}).Load()
+func (l *Loader) Load() (Values, error) {
+	dotenv, err := ReadDotEnv(filepath.Join(l.RootPath, ".env"))
+	if err != nil {
+		return nil, err
+	}
+
+	environ := l.Environ
+	if environ == nil {
+		environ = os.Environ()
+	}
+	env := ParseEnviron(environ)
+	flags := ParseFlags(l.Args)
+
+	// The environment name decides which YAML file applies, so it is resolved from
+	// the sources that do not depend on it.
+	name := l.Environment
+	if name == "" {
+		name = Merge(dotenv, env, flags).String("APP_ENV")
+	}
+
+	layers := []Values{}
+
+	base, err := ReadYAML(filepath.Join(l.RootPath, "config", "app.yml"))
+	if err != nil {
+		return nil, err
+	}
+	layers = append(layers, base)
+
+	if name != "" {
+		overrides, err := ReadYAML(filepath.Join(l.RootPath, "config", fmt.Sprintf("app.%s.yml", name)))
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, overrides)
+	}
+
+	layers = append(layers, dotenv, env, flags)
+
+	return Merge(layers...), nil
+}
+
+// Read a .env file into a set of values. A missing file yields an empty set.
+func ReadDotEnv(path string) (Values, error) {
+	values := Values{}
+
+	data, err := godotenv.Read(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	for k, v := range data {
+		values[k] = Value{Value: v, Source: SourceDotEnv}
+	}
+
+	return values, nil
+}
+
+// Read a YAML file into a set of values. Nested maps are flattened by joining the keys
+// with an underscore and upper casing the result, so both of these set DATABASE_HOST:
+//
+//	DATABASE_HOST: localhost
+//	database:
+//	  host: localhost
+//
+// Lists are joined with a comma. A missing file yields an empty set.
+func ReadYAML(path string) (Values, error) {
+	values := Values{}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return values, nil
+		}
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	var doc map[interface{}]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+
+	source := filepath.Join(filepath.Base(filepath.Dir(path)), filepath.Base(path))
+	flatten(values, "", doc, source)
+
+	return values, nil
+}
+
+// Parse environment variables in the KEY=value form into a set of values.
+func ParseEnviron(environ []string) Values {
+	values := Values{}
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if !ok || k == "" {
+			continue
+		}
+		values[k] = Value{Value: v, Source: SourceEnvironment}
+	}
+	return values
+}
+
+// Parse command line flags in the --KEY=value form into a set of values. The key is
+// upper cased and dashes or dots are replaced with underscores, e.g., --http-port=8080
+// sets HTTP_PORT. Any other argument is ignored.
+func ParseFlags(args []string) Values {
+	values := Values{}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		k, v, ok := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !ok || k == "" {
+			continue
+		}
+		values[normalizeKey(k)] = Value{Value: v, Source: SourceFlag}
+	}
+	return values
+}
+
+// Merge sets of values where a key found in a later set overrides an earlier one.
+func Merge(layers ...Values) Values {
+	merged := Values{}
+	for _, layer := range layers {
+		for k, v := range layer {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+// Return the value of a key or an empty string when the key is not set.
+func (v Values) String(key string) string {
+	return v[key].Value
+}
+
+// Export the values of the keys to the process environment so packages reading the
+// environment directly see them. The values already went through the precedence of Load,
+// so a variable that exists is overwritten, e.g., by a flag. Keys without a value are
+// left alone. The environment is shared by the whole process, so export only the keys
+// such packages read.
+func (v Values) Export(keys ...string) error {
+	for _, k := range keys {
+		val, ok := v[k]
+		if !ok {
+			continue
+		}
+		if current, ok := os.LookupEnv(k); ok && current == val.Value {
+			continue
+		}
+		if err := os.Setenv(k, val.Value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Create a reader to decode typed values from the set.
+func (v Values) Reader() *Reader {
+	return &Reader{
+		values: v,
+		known:  make(map[string]bool),
+	}
+}
+
+// Return the value of a key or the default when the key is missing or empty.
+func (r *Reader) String(key string, defaultValue ...string) string {
+	r.known[key] = true
+	if value := r.values[key].Value; value != "" {
+		return value
+	}
+	if len(defaultValue) > 0 {
+		return defaultValue[0]
+	}
+	return ""
+}
+
+// Return the integer value of a key or the default when the key is missing. A value
+// that is not an integer is recorded as a problem.
+func (r *Reader) Int(key string, defaultValue int) int {
+	raw := r.String(key)
+	if raw == "" {
+		return defaultValue
+	}
+	v, err := strconv.Atoi(strings.TrimSpace(raw))
+	if err != nil {
+		r.malformed(key, "an integer")
+		return defaultValue
+	}
+	return v
+}
+
+// Return the floating point value of a key or the default when the key is missing. A
+// value that is not a number is recorded as a problem.
+func (r *Reader) Float(key string, defaultValue float64) float64 {
+	raw := r.String(key)
+	if raw == "" {
+		return defaultValue
+	}
+	v, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+	if err != nil {
+		r.malformed(key, "a number")
+		return defaultValue
+	}
+	return v
+}
+
+// Return the boolean value of a key or the default when the key is missing. A value
+// that is not a boolean is recorded as a problem.
+func (r *Reader) Bool(key string, defaultValue bool) bool {
+	raw := r.String(key)
+	if raw == "" {
+		return defaultValue
+	}
+	v, err := strconv.ParseBool(strings.TrimSpace(raw))
+	if err != nil {
+		r.malformed(key, "a boolean")
+		return defaultValue
+	}
+	return v
+}
+
+// Return a duration from a key holding either a whole number of seconds or a Go
+// duration string such as 1m30s.
+func (r *Reader) Seconds(key string, defaultValue time.Duration) time.Duration {
+	raw := strings.TrimSpace(r.String(key))
+	if raw == "" {
+		return defaultValue
+	}
+	if v, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(v) * time.Second
+	}
+	v, err := time.ParseDuration(raw)
+	if err != nil {
+		r.malformed(key, "a number of seconds or a duration")
+		return defaultValue
+	}
+	return v
+}
+
+// Return a comma separated value as a slice, ignoring empty items.
+func (r *Reader) List(key string) []string {
+	var list []string
+	for _, item := range strings.Split(r.String(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// Record a problem when the key has no value. The reason explains why the key is
+// required, e.g., "when DATABASE_TYPE is set".
+func (r *Reader) Require(key string, reason string) {
+	r.known[key] = true
+	if strings.TrimSpace(r.values[key].Value) != "" {
+		return
+	}
+	if reason == "" {
+		r.Problemf("%s is required", key)
+		return
+	}
+	r.Problemf("%s is required %s", key, reason)
+}
+
+// Record a problem when the key has a value that is not one of the allowed values. The
+// comparison is case insensitive and an empty value is always allowed.
+func (r *Reader) OneOf(key string, allowed ...string) {
+	raw := strings.TrimSpace(r.String(key))
+	if raw == "" {
+		return
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(raw, a) {
+			return
+		}
+	}
+	r.Problemf("%s must be one of %s; got %q%s", key, strings.Join(allowed, ", "), raw, r.origin(key))
+}
+
+// Record a problem with the configuration.
+func (r *Reader) Problemf(format string, args ...interface{}) {
+	r.problems = append(r.problems, fmt.Sprintf(format, args...))
+}
+
+// Return a ValidationError listing every problem recorded by the reader, or nil when
+// the configuration is valid.
+func (r *Reader) Err() error {
+	if len(r.problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: append([]string{}, r.problems...)}
+}
+
+// Return the keys set in a file or flag that the reader was never asked for and that
+// look like a misspelling of a key it was asked for, mapped to that key. Environment
+// variables are ignored since the process environment holds many unrelated keys.
+func (r *Reader) Misspelled() map[string]string {
+	suggestions := map[string]string{}
+	for key, v := range r.values {
+		if r.known[key] || v.Source == SourceEnvironment {
+			continue
+		}
+		best, distance := "", 3
+		for known := range r.known {
+			if d := levenshtein(key, known); d < distance {
+				best, distance = known, d
+			}
+		}
+		if best != "" {
+			suggestions[key] = best
+		}
+	}
+	return suggestions
+}
+
+// Record a malformed value, naming the source it was read from.
+func (r *Reader) malformed(key, expected string) {
+	r.Problemf("%s must be %s; got %q%s", key, expected, r.values[key].Value, r.origin(key))
+}
+
+// Describe where a value was read from for use in a problem description.
+func (r *Reader) origin(key string) string {
+	if source := r.values[key].Source; source != "" {
+		return fmt.Sprintf(" (from %s)", source)
+	}
+	return ""
+}
+
+// Error lists every problem on its own line.
+func (e *ValidationError) Error() string {
+	problems := append([]string{}, e.Problems...)
+	sort.Strings(problems)
+	return "invalid configuration:\n  - " + strings.Join(problems, "\n  - ")
+}
+
+// Flatten a YAML document into the set of values.
+func flatten(values Values, prefix string, node interface{}, source string) {
+	switch n := node.(type) {
+	case map[interface{}]interface{}:
+		for k, v := range n {
+			key := normalizeKey(fmt.Sprint(k))
+			if prefix != "" {
+				key = prefix + "_" + key
+			}
+			flatten(values, key, v, source)
+		}
+	case []interface{}:
+		items := make([]string, 0, len(n))
+		for _, item := range n {
+			items = append(items, fmt.Sprint(item))
+		}
+		values[prefix] = Value{Value: strings.Join(items, ","), Source: source}
+	case nil:
+		values[prefix] = Value{Value: "", Source: source}
+	default:
+		values[prefix] = Value{Value: fmt.Sprint(n), Source: source}
+	}
+}
+
+// Normalize a key to the environment variable form, e.g., http-port becomes HTTP_PORT.
+func normalizeKey(key string) string {
+	key = strings.NewReplacer("-", "_", ".", "_").Replace(strings.TrimSpace(key))
+	return strings.ToUpper(key)
+}
+
+// Compute the edit distance between two strings.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
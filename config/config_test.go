@@ -0,0 +1,227 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Write a file relative to the root path, creating any parent directory.
+func writeFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoader_Precedence(t *testing.T) {
+	root := t.TempDir()
+
+	writeFile(t, root, "config/app.yml", "HTTP_PORT: 1000\nDATABASE_HOST: base\nCACHE: badger\nSMTP_HOST: base\n")
+	writeFile(t, root, "config/app.production.yml", "database:\n  host: production\n")
+	writeFile(t, root, ".env", "APP_ENV=production\nCACHE=redis\nSMTP_HOST=dotenv\n")
+
+	l := &Loader{
+		RootPath: root,
+		Environ:  []string{"SMTP_HOST=environment"},
+		Args:     []string{"--http-port=8080", "serve", "-v"},
+	}
+
+	values, err := l.Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	tests := []struct {
+		key    string
+		value  string
+		source string
+	}{
+		{"HTTP_PORT", "8080", SourceFlag},
+		{"DATABASE_HOST", "production", filepath.Join("config", "app.production.yml")},
+		{"CACHE", "redis", SourceDotEnv},
+		{"SMTP_HOST", "environment", SourceEnvironment},
+	}
+
+	for _, tt := range tests {
+		got := values[tt.key]
+		if got.Value != tt.value {
+			t.Errorf("%s = %q, want %q", tt.key, got.Value, tt.value)
+		}
+		if got.Source != tt.source {
+			t.Errorf("%s source = %q, want %q", tt.key, got.Source, tt.source)
+		}
+	}
+}
+
+func TestLoader_MissingFiles(t *testing.T) {
+	values, err := (&Loader{RootPath: t.TempDir(), Environ: []string{}}).Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+
+	if len(values) != 0 {
+		t.Errorf("expected no values, got %v", values)
+	}
+}
+
+func TestLoader_MalformedYAML(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "config/app.yml", "HTTP_PORT: [unclosed\n")
+
+	_, err := (&Loader{RootPath: root, Environ: []string{}}).Load()
+	if err == nil {
+		t.Fatal("expected an error for a malformed YAML file")
+	}
+}
+
+func TestReadYAML_Flatten(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "config/app.yml", "redis:\n  max-idle: 5\nfile_types_allowed:\n  - text/plain\n  - text/csv\n")
+
+	values, err := ReadYAML(filepath.Join(root, "config", "app.yml"))
+	if err != nil {
+		t.Fatalf("ReadYAML() error = %v", err)
+	}
+
+	if got := values.String("REDIS_MAX_IDLE"); got != "5" {
+		t.Errorf("REDIS_MAX_IDLE = %q, want 5", got)
+	}
+
+	if got := values.String("FILE_TYPES_ALLOWED"); got != "text/plain,text/csv" {
+		t.Errorf("FILE_TYPES_ALLOWED = %q, want text/plain,text/csv", got)
+	}
+}
+
+func TestReader_TypedValues(t *testing.T) {
+	r := Values{
+		"PORT":     {Value: "25"},
+		"DEBUG":    {Value: "true"},
+		"TIMEOUT":  {Value: "90"},
+		"INTERVAL": {Value: "1m30s"},
+		"TYPES":    {Value: "a, b,,c"},
+		"RATIO":    {Value: "0.5"},
+	}.Reader()
+
+	if got := r.Int("PORT", 0); got != 25 {
+		t.Errorf("Int() = %d, want 25", got)
+	}
+	if got := r.Int("MISSING", 7); got != 7 {
+		t.Errorf("Int() default = %d, want 7", got)
+	}
+	if got := r.Float("RATIO", 0); got != 0.5 {
+		t.Errorf("Float() = %v, want 0.5", got)
+	}
+	if got := r.Bool("DEBUG", false); !got {
+		t.Error("Bool() = false, want true")
+	}
+	if got := r.Seconds("TIMEOUT", 0); got != 90*time.Second {
+		t.Errorf("Seconds() = %v, want 90s", got)
+	}
+	if got := r.Seconds("INTERVAL", 0); got != 90*time.Second {
+		t.Errorf("Seconds() = %v, want 1m30s", got)
+	}
+	if got := r.List("TYPES"); strings.Join(got, "|") != "a|b|c" {
+		t.Errorf("List() = %v, want [a b c]", got)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() = %v, want nil", err)
+	}
+}
+
+func TestReader_AggregatesProblems(t *testing.T) {
+	r := Values{
+		"SMTP_PORT":     {Value: "abc", Source: SourceDotEnv},
+		"COOKIE_SECURE": {Value: "maybe", Source: SourceFlag},
+		"CACHE":         {Value: "memcached"},
+	}.Reader()
+
+	r.Int("SMTP_PORT", 1025)
+	r.Bool("COOKIE_SECURE", false)
+	r.OneOf("CACHE", "redis", "badger")
+	r.Require("DATABASE_USER", "when DATABASE_TYPE is set")
+
+	err := r.Err()
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	if len(verr.Problems) != 4 {
+		t.Fatalf("expected 4 problems, got %d: %v", len(verr.Problems), verr.Problems)
+	}
+
+	for _, want := range []string{"SMTP_PORT", "(from .env)", "COOKIE_SECURE", "CACHE", "DATABASE_USER is required when DATABASE_TYPE is set"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err.Error(), want)
+		}
+	}
+}
+
+func TestReader_Misspelled(t *testing.T) {
+	r := Values{
+		"REDIS_MAX_IDEL": {Value: "5", Source: SourceDotEnv},
+		"MY_APP_SETTING": {Value: "x", Source: SourceDotEnv},
+		"REDIS_MAX_IDL":  {Value: "5", Source: SourceEnvironment},
+	}.Reader()
+
+	r.Int("REDIS_MAX_IDLE", 50)
+
+	got := r.Misspelled()
+
+	if got["REDIS_MAX_IDEL"] != "REDIS_MAX_IDLE" {
+		t.Errorf("expected REDIS_MAX_IDEL to suggest REDIS_MAX_IDLE, got %v", got)
+	}
+
+	if _, ok := got["MY_APP_SETTING"]; ok {
+		t.Error("unrelated application keys should not be reported")
+	}
+
+	if _, ok := got["REDIS_MAX_IDL"]; ok {
+		t.Error("environment variables should not be reported")
+	}
+}
+
+func TestValues_Export(t *testing.T) {
+	t.Setenv("ADELE_TEST_EXISTING", "environment")
+	t.Setenv("ADELE_TEST_FLAG", "environment")
+
+	values, err := (&Loader{RootPath: t.TempDir(), Args: []string{"--adele-test-flag=flag"}}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	values["ADELE_TEST_NEW"] = Value{Value: "file", Source: SourceDotEnv}
+	values["ADELE_TEST_UNLISTED"] = Value{Value: "file", Source: SourceDotEnv}
+	defer os.Unsetenv("ADELE_TEST_NEW")
+
+	if err := values.Export("ADELE_TEST_EXISTING", "ADELE_TEST_FLAG", "ADELE_TEST_NEW", "ADELE_TEST_MISSING"); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := os.Getenv("ADELE_TEST_EXISTING"); got != "environment" {
+		t.Errorf("existing variable changed: %q", got)
+	}
+
+	if got := os.Getenv("ADELE_TEST_FLAG"); got != "flag" {
+		t.Errorf("variable overridden by a flag not exported: %q", got)
+	}
+
+	if got := os.Getenv("ADELE_TEST_NEW"); got != "file" {
+		t.Errorf("new variable not exported: %q", got)
+	}
+
+	if _, ok := os.LookupEnv("ADELE_TEST_UNLISTED"); ok {
+		t.Error("a key that was not listed was exported")
+	}
+	if _, ok := os.LookupEnv("ADELE_TEST_MISSING"); ok {
+		t.Error("a key without a value was exported")
+	}
+}
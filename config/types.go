@@ -0,0 +1,51 @@
+package config
+
+// Names of the sources a configuration value can be read from other than the YAML
+// files, which are named by their path.
+const (
+	SourceDotEnv      = ".env"
+	SourceEnvironment = "environment"
+	SourceFlag        = "flag"
+)
+
+// Loader merges the configuration sources of an application. Values found in a later
+// source override the ones found in an earlier source:
+//
+//	config/app.yml < config/app.<APP_ENV>.yml < .env < environment variables < flags
+type Loader struct {
+	// Root path of the application holding the .env file and config directory.
+	RootPath string
+
+	// Name of the environment used to select config/app.<environment>.yml. When empty
+	// the APP_ENV value from the other sources is used.
+	Environment string
+
+	// Command line arguments; only arguments in the --KEY=value form are read.
+	Args []string
+
+	// Environment variables in the KEY=value form; defaults to os.Environ.
+	Environ []string
+}
+
+// Value is a single configuration value and the source it was read from.
+type Value struct {
+	Value  string
+	Source string
+}
+
+// Values is the flat set of configuration keys, e.g., DATABASE_HOST, mapped to the value
+// that won after every source was merged.
+type Values map[string]Value
+
+// Reader decodes typed values and records every key it was asked for alongside the
+// problems found while decoding, so they can be reported together.
+type Reader struct {
+	values   Values
+	known    map[string]bool
+	problems []string
+}
+
+// ValidationError lists every missing or malformed configuration value.
+type ValidationError struct {
+	Problems []string
+}
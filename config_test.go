@@ -0,0 +1,206 @@
+package adele
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/config"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLoadConfig_Layers(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.MkdirAll(filepath.Join(root, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "config", "app.yml"), []byte("redis:\n  max_idle: 5\n  timeout: 2m\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte("APP_NAME=adele-test\nCACHE=redis\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(root, "--http-port=8080")
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.AppName != "adele-test" {
+		t.Errorf("AppName = %q, want adele-test", cfg.AppName)
+	}
+	if cfg.Cache.Driver != "redis" {
+		t.Errorf("Cache.Driver = %q, want redis", cfg.Cache.Driver)
+	}
+	if cfg.Cache.Redis.MaxIdle != 5 {
+		t.Errorf("Cache.Redis.MaxIdle = %d, want 5", cfg.Cache.Redis.MaxIdle)
+	}
+	if cfg.Cache.Redis.IdleTimeout != 2*time.Minute {
+		t.Errorf("Cache.Redis.IdleTimeout = %v, want 2m", cfg.Cache.Redis.IdleTimeout)
+	}
+	if cfg.HTTP.Port != "8080" {
+		t.Errorf("HTTP.Port = %q, want 8080", cfg.HTTP.Port)
+	}
+}
+
+func TestLoadConfig_AggregatedError(t *testing.T) {
+	root := t.TempDir()
+
+	env := "DATABASE_TYPE=postgres\nSMTP_PORT=smtp\nS3_KEY=key\nHTTP_PORT=99999\nREDIS_URL=memcached://localhost\nBADGER_ENCRYPTION_KEY=short\nBADGER_GC_DISCARD_RATIO=1.5\nDATABASE_REPLICA_POLICY=random\n"
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadConfig(root)
+
+	var verr *config.ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected a ValidationError, got %v", err)
+	}
+
+	for _, key := range []string{"DATABASE_USER", "DATABASE_NAME", "SMTP_PORT", "S3_SECRET", "S3_REGION", "S3_BUCKET", "HTTP_PORT", "REDIS_URL", "BADGER_ENCRYPTION_KEY", "BADGER_GC_DISCARD_RATIO", "DATABASE_REPLICA_POLICY"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s: %v", key, err)
+		}
+	}
+}
+
+func TestLoadConfig_Sqlite(t *testing.T) {
+	root := t.TempDir()
+
+	env := "DATABASE_TYPE=sqlite\nDATABASE_NAME=storage/app.db\nDATABASE_BUSY_TIMEOUT=2\nSESSION_TYPE=sqlite\n"
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte(env), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(root)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Database.BusyTimeout != 2*time.Second {
+		t.Errorf("Database.BusyTimeout = %v, want 2s", cfg.Database.BusyTimeout)
+	}
+	if cfg.Database.JournalMode != "WAL" {
+		t.Errorf("Database.JournalMode = %q, want WAL", cfg.Database.JournalMode)
+	}
+	if cfg.Session.Type != "sqlite" {
+		t.Errorf("Session.Type = %q, want sqlite", cfg.Session.Type)
+	}
+}
+
+func TestLoadConfig_LegacyRedisMaxIdle(t *testing.T) {
+	root := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(root, ".env"), []byte("REDIS_MAX_IDEL=7\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := LoadConfig(root)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if cfg.Cache.Redis.MaxIdle != 7 {
+		t.Errorf("Cache.Redis.MaxIdle = %d, want 7", cfg.Cache.Redis.MaxIdle)
+	}
+}
+
+func TestRedisOptions(t *testing.T) {
+	opts, err := redisOptions(RedisConfig{Host: "cache", Port: "6380", Username: "app", DB: 2, TLS: true, MaxIdle: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Addrs[0] != "cache:6380" || opts.Username != "app" || opts.DB != 2 || opts.TLS == nil || opts.MaxIdle != 5 {
+		t.Errorf("redisOptions() = %+v, want the host, credentials, database and TLS of the config", opts)
+	}
+
+	opts, err = redisOptions(RedisConfig{URL: "redis+sentinel://s1:26379,s2:26379/mymaster", Host: "cache", Port: "6380", MaxIdle: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.MasterName != "mymaster" || len(opts.Addrs) != 2 || opts.MaxIdle != 5 {
+		t.Errorf("redisOptions() = %+v, want the sentinels of the URL", opts)
+	}
+}
+
+func TestOptions(t *testing.T) {
+	logger := logrus.New()
+
+	tests := []struct {
+		name  string
+		opt   Option
+		check func(a *Adele) bool
+	}{
+		{"database", WithDatabase(DatabaseConfig{Type: "sqlite", Name: "app.db"}), func(a *Adele) bool { return a.config.Database.Name == "app.db" }},
+		{"cache", WithCache(CacheConfig{Driver: "memory"}), func(a *Adele) bool { return a.config.Cache.Driver == "memory" }},
+		{"mail", WithMail(MailConfig{Host: "smtp.example.com"}), func(a *Adele) bool { return a.config.Mail.Host == "smtp.example.com" }},
+		{"session", WithSession(SessionConfig{CookieName: "app"}), func(a *Adele) bool { return a.config.Session.CookieName == "app" }},
+		{"http", WithHTTP(HTTPConfig{Port: "8080"}), func(a *Adele) bool { return a.config.HTTP.Port == "8080" }},
+		{"rpc", WithRPC(RPCConfig{Disabled: true}), func(a *Adele) bool { return a.config.RPC.Disabled }},
+		{"filesystem", WithFilesystem(FilesystemConfig{}), func(a *Adele) bool { return a.config.Filesystem == FilesystemConfig{} }},
+		{"logger", WithLogger(logger), func(a *Adele) bool { return a.Log == logger }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &Adele{config: ConfigFromEnv()}
+			a.config.AppName = "adele-test"
+
+			tt.opt(a)
+
+			if !tt.check(a) {
+				t.Errorf("option was not applied: %+v", a.config)
+			}
+			if a.config.AppName != "adele-test" {
+				t.Errorf("AppName = %q, want the other sections left unchanged", a.config.AppName)
+			}
+		})
+	}
+}
+
+func TestNewWithConfig_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		root func(t *testing.T) string
+		opts []Option
+		want error
+	}{
+		{
+			name: "missing cors configuration",
+			root: func(t *testing.T) string { return t.TempDir() },
+			want: ErrRouterUnavailable,
+		},
+		{
+			name: "option applied before bootstrap",
+			root: testRoot,
+			opts: []Option{WithCache(CacheConfig{Driver: "database"})},
+			want: ErrCacheUnavailable,
+		},
+		{
+			name: "last option wins",
+			root: testRoot,
+			opts: []Option{
+				WithCache(CacheConfig{Driver: "memory"}),
+				WithCache(CacheConfig{Driver: "database"}),
+			},
+			want: ErrCacheUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewWithConfig(tt.root(t), ConfigFromEnv(), tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("NewWithConfig() error = %v, want %v", err, tt.want)
+			}
+			if a != nil {
+				t.Errorf("NewWithConfig() = %v, want nil on error", a)
+			}
+		})
+	}
+}
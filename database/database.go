@@ -1,32 +1,95 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/cidekar/adele-framework/database/mysqldriver"
 	"github.com/cidekar/adele-framework/database/postgresdriver"
+	"github.com/cidekar/adele-framework/database/sqlitedriver"
 	"github.com/upper/db/v4"
 )
 
+// Pool settings used by OpenDB for the settings a data source leaves at zero.
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 5
+	DefaultConnMaxLifetime = 5 * time.Minute
+	DefaultPingTimeout     = 5 * time.Second
+	DefaultBusyTimeout     = 5 * time.Second
+)
+
+// ErrUnsupportedDriver is returned when the database type has no driver in the framework.
+var ErrUnsupportedDriver = errors.New("unsupported database driver")
+
 // NewSession creates a new sqlbuilder.Session instance based on the configured database type.
 // Returns nil if no database is configured.
 func (a *Database) NewSession() db.Session {
-	if a.Pool == nil {
+	return a.session(a.Pool)
+}
+
+// Create a session like NewSession on the pool the context reads from, i.e., a replica
+// unless the database has none or the context wrote to the primary within the sticky
+// window. The session is meant for reads; a session on the primary does not prevent
+// writes. Example:
+//
+//	posts := app.DB.NewReadSession(r.Context()).Collection("posts")
+func (a *Database) NewReadSession(ctx context.Context) db.Session {
+	return a.session(a.Reader(ctx))
+}
+
+// Return the session on the pool shared by the queries of the database, creating it on
+// first use; creating a session pings the database and looks up its name, which is too
+// slow to do for every query. Bind it to a context with WithContext.
+func (a *Database) sharedSession(pool *sql.DB) db.Session {
+	if pool == nil {
 		return nil
 	}
 
-	dbType := getDBDriver(a.DataType)
+	a.sessionsMu.Lock()
+	defer a.sessionsMu.Unlock()
+
+	if sess, ok := a.sessions[pool]; ok {
+		return sess
+	}
+
+	sess := a.session(pool)
+	if sess == nil {
+		return nil
+	}
+	if a.sessions == nil {
+		a.sessions = make(map[*sql.DB]db.Session)
+	}
+	a.sessions[pool] = sess
+
+	return sess
+}
+
+// Create a session on the pool for the database type.
+func (a *Database) session(pool *sql.DB) db.Session {
+	if pool == nil {
+		return nil
+	}
+
+	dbType := Driver(a.DataType)
 
 	switch dbType {
 	case "mysql":
-		if session, err := mysqldriver.Session(a.Pool); err == nil {
+		if session, err := mysqldriver.Session(pool); err == nil {
 			return session
 		}
 	case "pgx":
-		if session, err := postgresdriver.Session(a.Pool); err == nil {
+		if session, err := postgresdriver.Session(pool); err == nil {
+			return session
+		}
+	case "sqlite":
+		if session, err := sqlitedriver.Session(pool); err == nil {
 			return session
 		}
 	}
@@ -34,23 +97,61 @@ func (a *Database) NewSession() db.Session {
 	return nil
 }
 
+// Close the connection pool and the pools of the replicas, waiting for queries that have
+// started to finish.
+func (a *Database) Close() error {
+	a.sessionsMu.Lock()
+	a.sessions = nil
+	a.sessionsMu.Unlock()
+
+	var errs []error
+	if a.Replicas != nil {
+		errs = append(errs, a.Replicas.Close())
+	}
+	if a.Pool != nil {
+		errs = append(errs, a.Pool.Close())
+	}
+	return errors.Join(errs...)
+}
+
 // Get a connection to a database and return connection pool
 func OpenDB(dbType string, config *DataSourceName) (*sql.DB, error) {
 
-	driver := getDBDriver(dbType)
-
-	if driver == "" {
+	if Driver(dbType) == "" {
 		return nil, nil
 	}
 
+	db, err := openPool(dbType, config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test connection
+	if err := ping(db, config); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// Open a connection pool configured with the settings of the data source, without
+// connecting to the database.
+func openPool(dbType string, config *DataSourceName) (*sql.DB, error) {
+	driver := Driver(dbType)
+
 	dsn := ""
 	switch driver {
 	case "pgx":
 		dsn = postgresdriver.BuildDSN(config.Host, config.Port, config.User, config.Password, config.DatabaseName, config.SslMode)
 	case "mysql":
 		dsn = mysqldriver.BuildDSN(config.Host, config.Port, config.User, config.Password, config.DatabaseName)
+	case "sqlite":
+		if config.DatabaseName != "" {
+			dsn = sqlitedriver.BuildDSN(config.DatabaseName, orDefault(config.BusyTimeout, DefaultBusyTimeout), config.JournalMode)
+		}
 	default:
-		return nil, fmt.Errorf("unsupported database driver: %s", dbType)
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDriver, dbType)
 	}
 
 	if dsn == "" {
@@ -62,27 +163,154 @@ func OpenDB(dbType string, config *DataSourceName) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Test connection
-	if err := db.Ping(); err != nil {
+	if config.OnQuery != nil {
+		connector, err := connectorFor(db.Driver(), dsn)
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
 		db.Close()
-		return nil, fmt.Errorf("database ping failed: %w", err)
+		db = sql.OpenDB(instrument(connector, config.OnQuery))
 	}
 
-	// Set reasonable defaults
-	db.SetMaxOpenConns(25)
-	db.SetMaxIdleConns(5)
-	db.SetConnMaxLifetime(5 * time.Minute)
+	configurePool(db, config)
+
+	// Every connection to a database in memory opens a database of its own, so the pool
+	// keeps the one connection for good.
+	if driver == "sqlite" && config.DatabaseName == sqlitedriver.Memory {
+		db.SetMaxOpenConns(1)
+		db.SetMaxIdleConns(1)
+		db.SetConnMaxLifetime(0)
+		db.SetConnMaxIdleTime(0)
+	}
 
 	return db, nil
 }
 
-// Convert database type to driver name
-func getDBDriver(dbType string) string {
+// Return the connector of the driver for the data source name.
+func connectorFor(d driver.Driver, dsn string) (driver.Connector, error) {
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{dsn: dsn, driver: d}, nil
+}
+
+// Ping the database within the ping timeout of the data source.
+func ping(db *sql.DB, config *DataSourceName) error {
+	ctx, cancel := context.WithTimeout(context.Background(), orDefault(config.PingTimeout, DefaultPingTimeout))
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database ping failed: %w", err)
+	}
+	return nil
+}
+
+// Apply the pool settings of the data source to the pool, using the defaults for the
+// settings left at zero.
+func configurePool(db *sql.DB, config *DataSourceName) {
+	db.SetMaxOpenConns(orDefault(config.MaxOpenConns, DefaultMaxOpenConns))
+	db.SetMaxIdleConns(orDefault(config.MaxIdleConns, DefaultMaxIdleConns))
+	db.SetConnMaxLifetime(orDefault(config.ConnMaxLifetime, DefaultConnMaxLifetime))
+	db.SetConnMaxIdleTime(config.ConnMaxIdleTime)
+}
+
+// Return the value, or the default when the value is zero. Negative values are passed
+// on, e.g., to remove the limit on open connections.
+func orDefault[T int | time.Duration](value, defaultValue T) T {
+	if value == 0 {
+		return defaultValue
+	}
+	return value
+}
+
+// Return the statistics of the connection pool, e.g., the number of connections in use
+// and how often a query waited for one.
+func (a *Database) Stats() sql.DBStats {
+	if a.Pool == nil {
+		return sql.DBStats{}
+	}
+	return a.Pool.Stats()
+}
+
+// Return a function reporting the queries that waited for a connection since it was
+// last called, for running on a schedule. The function calls warn when at least
+// threshold queries waited, which means the pool is too small for the load or
+// connections are held too long. Example:
+//
+//	app.Scheduler.AddFunc("@every 1m", app.DB.WaitMonitor(10, func(waits int64, waited time.Duration) {
+//	    app.Log.Warnf("%d queries waited %s for a database connection", waits, waited)
+//	}))
+func (a *Database) WaitMonitor(threshold int64, warn func(waits int64, waited time.Duration)) func() {
+	var mu sync.Mutex
+	last := a.Stats()
+
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		stats := a.Stats()
+		waits, waited := stats.WaitCount-last.WaitCount, stats.WaitDuration-last.WaitDuration
+		last = stats
+
+		if waits > 0 && waits >= threshold {
+			warn(waits, waited)
+		}
+	}
+}
+
+// Get a connection to a database like OpenDB, retrying with an exponential backoff while
+// the database cannot be reached, e.g., when it is still starting up next to the
+// application. An unsupported driver is not retried. Example:
+//
+//	db, err := database.OpenDBWithRetry(ctx, "postgres", dsn, database.Retry{Attempts: 5, Backoff: time.Second})
+func OpenDBWithRetry(ctx context.Context, dbType string, config *DataSourceName, retry Retry) (*sql.DB, error) {
+	wait := retry.Backoff
+	if wait <= 0 {
+		wait = time.Second
+	}
+
+	maxWait := retry.MaxBackoff
+	if maxWait <= 0 {
+		maxWait = 30 * time.Second
+	}
+
+	for attempt := 1; ; attempt++ {
+		db, err := OpenDB(dbType, config)
+		if err == nil {
+			return db, nil
+		}
+
+		if attempt > retry.Attempts || errors.Is(err, ErrUnsupportedDriver) {
+			return nil, err
+		}
+
+		if retry.OnRetry != nil {
+			retry.OnRetry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		wait = min(wait*2, maxWait)
+	}
+}
+
+// Convert a database type, e.g., postgres or mariadb, to the name of its database/sql
+// driver, i.e., pgx, mysql or sqlite. An unknown type is returned unchanged.
+func Driver(dbType string) string {
 	switch strings.ToLower(strings.TrimSpace(dbType)) {
 	case "postgres", "postgresql":
 		return "pgx"
 	case "mysql", "mariadb":
 		return "mysql"
+	case "sqlite", "sqlite3":
+		return "sqlite"
 	default:
 		return dbType
 	}
@@ -3,7 +3,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"log"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -207,3 +210,184 @@ func TestNewSessionSql(t *testing.T) {
 		t.Fatalf("nil returned when session was expected")
 	}
 }
+
+func TestOpenDBWithRetry_RetriesUnreachableDatabase(t *testing.T) {
+	retries := 0
+	db, err := OpenDBWithRetry(context.Background(), "mysql", &DataSourceName{
+		Host:         "127.0.0.1",
+		Port:         "1",
+		User:         "testuser",
+		Password:     "testpass",
+		DatabaseName: "testdb",
+	}, Retry{
+		Attempts: 2,
+		Backoff:  time.Millisecond,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			retries++
+		},
+	})
+
+	if err == nil {
+		db.Close()
+		t.Fatal("expected an error connecting to an unreachable database")
+	}
+
+	if retries != 2 {
+		t.Errorf("retries = %d, want 2", retries)
+	}
+}
+
+func TestOpenDBWithRetry_UnsupportedDriver(t *testing.T) {
+	retries := 0
+	_, err := OpenDBWithRetry(context.Background(), "oracle", &DataSourceName{}, Retry{
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+		OnRetry: func(attempt int, err error, wait time.Duration) {
+			retries++
+		},
+	})
+
+	if !errors.Is(err, ErrUnsupportedDriver) {
+		t.Errorf("expected ErrUnsupportedDriver, got %v", err)
+	}
+
+	if retries != 0 {
+		t.Errorf("an unsupported driver should not be retried; retries = %d", retries)
+	}
+}
+
+// A driver whose connections open without a server, for testing the pool.
+type stubDriver struct{}
+
+type stubConn struct{}
+
+func (stubDriver) Open(name string) (driver.Conn, error) { return stubConn{}, nil }
+
+func (stubConn) Prepare(query string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (stubConn) Close() error                              { return nil }
+func (stubConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+func init() {
+	sql.Register("stub", stubDriver{})
+}
+
+func TestConfigurePool(t *testing.T) {
+	db, _ := sql.Open("stub", "")
+	defer db.Close()
+
+	configurePool(db, &DataSourceName{})
+	if got := db.Stats().MaxOpenConnections; got != DefaultMaxOpenConns {
+		t.Errorf("MaxOpenConnections = %d, want %d", got, DefaultMaxOpenConns)
+	}
+
+	configurePool(db, &DataSourceName{MaxOpenConns: 3})
+	if got := db.Stats().MaxOpenConnections; got != 3 {
+		t.Errorf("MaxOpenConnections = %d, want 3", got)
+	}
+
+	configurePool(db, &DataSourceName{MaxOpenConns: -1})
+	if got := db.Stats().MaxOpenConnections; got != 0 {
+		t.Errorf("MaxOpenConnections = %d, want no limit", got)
+	}
+}
+
+func TestWaitMonitor(t *testing.T) {
+	db, _ := sql.Open("stub", "")
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	d := &Database{DataType: "stub", Pool: db}
+
+	var warned int64
+	check := d.WaitMonitor(1, func(waits int64, waited time.Duration) {
+		warned = waits
+	})
+
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("Conn() error = %v", err)
+	}
+
+	// A second connection waits until the first is returned.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if c, err := db.Conn(ctx); err == nil {
+			c.Close()
+		}
+	}()
+
+	for db.Stats().WaitCount == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	conn.Close()
+	<-done
+
+	check()
+	if warned != 1 {
+		t.Errorf("warned of %d waits, want 1", warned)
+	}
+
+	warned = 0
+	check()
+	if warned != 0 {
+		t.Errorf("warned of %d waits since the last check, want none", warned)
+	}
+}
+
+func TestOpenDatabaseConnectionSqlite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+
+	db, err := OpenDB("sqlite", &DataSourceName{DatabaseName: path})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer db.Close()
+
+	d := &Database{DataType: "sqlite", Pool: db}
+	if _, err := db.Exec("CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.NewSession().Collection("posts").Insert(map[string]interface{}{"title": "Hello"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	// Readers work alongside a writer holding the lock.
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec("INSERT INTO posts (title) VALUES ('Draft')"); err != nil {
+		t.Fatal(err)
+	}
+	if count, err := d.NewSession().Collection("posts").Find().Count(); err != nil || count != 1 {
+		t.Errorf("Count() = %d, %v during a write; want 1", count, err)
+	}
+}
+
+func TestOpenDatabaseConnectionSqliteMemory(t *testing.T) {
+	db, err := OpenDB("sqlite3", &DataSourceName{DatabaseName: ":memory:", MaxOpenConns: 10})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer db.Close()
+
+	// The tables must live on for every query, so the pool keeps a single connection.
+	if got := db.Stats().MaxOpenConnections; got != 1 {
+		t.Errorf("MaxOpenConnections = %d, want 1", got)
+	}
+
+	if _, err := db.Exec("CREATE TABLE posts (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec("INSERT INTO posts DEFAULT VALUES"); err != nil {
+		t.Errorf("table is missing on a later query: %v", err)
+	}
+
+	if _, err := OpenDB("sqlite", &DataSourceName{}); err == nil {
+		t.Error("OpenDB() without a path error = nil")
+	}
+}
@@ -0,0 +1,566 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrIrreversibleMigration is returned when reverting a migration that has no down
+// migration, or that is recorded as applied but no longer exists.
+var ErrIrreversibleMigration = errors.New("migration cannot be reverted")
+
+// The table the applied versions are recorded in unless the migrator names another.
+const DefaultMigrationsTable = "schema_migrations"
+
+// The timestamp layout migration versions are written in.
+const MigrationVersionLayout = "20060102150405"
+
+// Matches the name of a migration file, capturing the version, the name, the database
+// the file is written for, if any, and the direction.
+var migrationFile = regexp.MustCompile(`^(\d+)_(.+?)(?:\.(postgres|mysql|sqlite))?\.(up|down)\.sql$`)
+
+// Create a migrator applying the migration files of the file system, and the migrations
+// written in Go, to the database. Example:
+//
+//	m := app.DB.Migrator(os.DirFS("migrations"))
+//	if err := m.Up(ctx); err != nil {
+//	    return err
+//	}
+func (a *Database) Migrator(fsys fs.FS, migrations ...Migration) *Migrator {
+	return &Migrator{
+		DB:         a,
+		FS:         fsys,
+		Migrations: migrations,
+	}
+}
+
+// Return the version of a migration created at the time, e.g., for the name of a new
+// migration file.
+func MigrationVersion(t time.Time) int64 {
+	version, _ := strconv.ParseInt(t.UTC().Format(MigrationVersionLayout), 10, 64)
+	return version
+}
+
+// Apply the migrations that have not been applied, oldest first, stopping at the first
+// that fails. Migrators on other connections wait for the migrator to finish, so
+// instances deployed at the same time apply each migration once. MySQL commits a schema
+// change as soon as it runs, so a failed migration may leave part of its changes behind.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus) error {
+		for _, migration := range migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Revert the n migrations applied last, newest first.
+func (m *Migrator) Down(ctx context.Context, n int) error {
+	return m.run(ctx, func(conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus) error {
+		_, err := m.revert(ctx, conn, migrations, applied, n)
+		return err
+	})
+}
+
+// Revert the migration applied last and apply it again, e.g., after changing it during
+// development.
+func (m *Migrator) Redo(ctx context.Context) error {
+	return m.run(ctx, func(conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus) error {
+		reverted, err := m.revert(ctx, conn, migrations, applied, 1)
+		if err != nil {
+			return err
+		}
+		for _, migration := range reverted {
+			if err := m.apply(ctx, conn, migration, true); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Revert every applied migration, newest first.
+func (m *Migrator) Reset(ctx context.Context) error {
+	return m.run(ctx, func(conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus) error {
+		_, err := m.revert(ctx, conn, migrations, applied, len(applied))
+		return err
+	})
+}
+
+// Return the status of every migration, oldest first, including the applied migrations
+// which no longer exist.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var status []MigrationStatus
+
+	err := m.run(ctx, func(conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus) error {
+		for _, migration := range migrations {
+			s, ok := applied[migration.Version]
+			if !ok {
+				s = MigrationStatus{Version: migration.Version, Name: migration.Name}
+			}
+			status = append(status, s)
+			delete(applied, migration.Version)
+		}
+		for _, s := range applied {
+			status = append(status, s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(status, func(i, j int) bool { return status[i].Version < status[j].Version })
+
+	return status, nil
+}
+
+// Load the migrations, then hold the migration lock on a connection while creating the
+// migrations table, reading the applied versions and calling the function.
+func (m *Migrator) run(ctx context.Context, fn func(conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus) error) error {
+	if m.DB == nil || m.DB.Pool == nil {
+		return errors.New("migrator has no database connection")
+	}
+
+	migrations, err := m.load()
+	if err != nil {
+		return err
+	}
+
+	// Advisory locks belong to the session, so every statement runs on one connection.
+	conn, err := m.DB.Pool.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := m.lock(ctx, conn); err != nil {
+		return err
+	}
+	defer m.unlock(context.WithoutCancel(ctx), conn)
+
+	if err := m.createTable(ctx, conn); err != nil {
+		return err
+	}
+
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn, migrations, applied)
+}
+
+// Revert up to n applied migrations, newest first, returning them in the order reverted.
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migrations []Migration, applied map[int64]MigrationStatus, n int) ([]Migration, error) {
+	versions := make([]int64, 0, len(applied))
+	for version := range applied {
+		versions = append(versions, version)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+	byVersion := make(map[int64]Migration, len(migrations))
+	for _, migration := range migrations {
+		byVersion[migration.Version] = migration
+	}
+
+	var reverted []Migration
+	for _, version := range versions {
+		if len(reverted) >= n {
+			break
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			return reverted, fmt.Errorf("%w: %d_%s is applied but was not found", ErrIrreversibleMigration, version, applied[version].Name)
+		}
+		if err := m.apply(ctx, conn, migration, false); err != nil {
+			return reverted, err
+		}
+		reverted = append(reverted, migration)
+	}
+
+	return reverted, nil
+}
+
+// Apply or revert the migration in a transaction recording the change.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration, up bool) error {
+	fn, direction := migration.Up, "up"
+	if !up {
+		fn, direction = migration.Down, "down"
+	}
+	if fn == nil {
+		return fmt.Errorf("%w: %d_%s has no down migration", ErrIrreversibleMigration, migration.Version, migration.Name)
+	}
+
+	record := "DELETE FROM " + m.table() + " WHERE version = ?"
+	args := []interface{}{migration.Version}
+	if up {
+		record = "INSERT INTO " + m.table() + " (version, name) VALUES (?, ?)"
+		args = append(args, migration.Name)
+	}
+
+	record, err := bind(m.driver(), record)
+	if err != nil {
+		return err
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(ctx, tx); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("migration %d_%s %s failed: %w", migration.Version, migration.Name, direction, err)
+	}
+
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to record migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+
+	return tx.Commit()
+}
+
+// Read the migration files for the database and merge them with the migrations written
+// in Go, ordered by version.
+func (m *Migrator) load() ([]Migration, error) {
+	dialect, err := m.dialect()
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int64]*Migration{}
+	specific := map[string]bool{}
+
+	if m.FS != nil {
+		entries, err := fs.ReadDir(m.FS, ".")
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migrations: %w", err)
+		}
+
+		for _, entry := range entries {
+			match := migrationFile.FindStringSubmatch(entry.Name())
+			if entry.IsDir() || match == nil {
+				continue
+			}
+
+			version, err := strconv.ParseInt(match[1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+			}
+			name, fileDialect, direction := match[2], match[3], match[4]
+
+			if fileDialect != "" && fileDialect != dialect {
+				continue
+			}
+
+			// A file written for the database replaces the one written for any database.
+			key := match[1] + "_" + name + "." + direction
+			if fileDialect == "" && specific[key] {
+				continue
+			}
+			if fileDialect != "" {
+				specific[key] = true
+			}
+
+			migration, ok := byVersion[version]
+			if !ok {
+				migration = &Migration{Version: version, Name: name}
+				byVersion[version] = migration
+			}
+			if migration.Name != name {
+				return nil, fmt.Errorf("migration version %d is used by %s and %s", version, migration.Name, name)
+			}
+
+			fn, err := m.file(entry.Name(), dialect)
+			if err != nil {
+				return nil, err
+			}
+			if direction == "up" {
+				migration.Up = fn
+			} else {
+				migration.Down = fn
+			}
+		}
+	}
+
+	for i := range m.Migrations {
+		migration := m.Migrations[i]
+		if _, ok := byVersion[migration.Version]; ok {
+			return nil, fmt.Errorf("migration version %d is used more than once", migration.Version)
+		}
+		byVersion[migration.Version] = &migration
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up migration", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+// Read a migration file into a function running its statements one by one, which not
+// every driver does for a string holding several.
+func (m *Migrator) file(name, dialect string) (func(ctx context.Context, tx *sql.Tx) error, error) {
+	contents, err := fs.ReadFile(m.FS, path.Clean(name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+	}
+
+	statements := splitStatements(string(contents), dialect)
+
+	return func(ctx context.Context, tx *sql.Tx) error {
+		for _, stmt := range statements {
+			if _, err := tx.ExecContext(ctx, stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, nil
+}
+
+func (m *Migrator) createTable(ctx context.Context, conn *sql.Conn) error {
+	var stmt string
+	switch m.driver() {
+	case "pgx":
+		stmt = "CREATE TABLE IF NOT EXISTS " + m.table() + " (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, " +
+			"applied_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	case "mysql":
+		stmt = "CREATE TABLE IF NOT EXISTS " + m.table() + " (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, " +
+			"applied_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6)) ENGINE=InnoDB"
+	case "sqlite":
+		stmt = "CREATE TABLE IF NOT EXISTS " + m.table() + " (version INTEGER PRIMARY KEY, name TEXT NOT NULL, " +
+			"applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)"
+	}
+
+	if _, err := conn.ExecContext(ctx, stmt); err != nil {
+		return fmt.Errorf("failed to create migrations table: %w", err)
+	}
+	return nil
+}
+
+// Read the applied migrations from the migrations table.
+func (m *Migrator) applied(ctx context.Context, conn *sql.Conn) (map[int64]MigrationStatus, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM "+m.table())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations table: %w", err)
+	}
+	defer rows.Close()
+
+	applied := map[int64]MigrationStatus{}
+	for rows.Next() {
+		s := MigrationStatus{Applied: true}
+		if err := rows.Scan(&s.Version, &s.Name, &s.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied[s.Version] = s
+	}
+
+	return applied, rows.Err()
+}
+
+// Take the advisory lock of the migrations table, waiting until the migrator holding it
+// releases it or the context is done. SQLite has no advisory locks; a migration and its
+// record are written in one transaction holding the lock of the database file, so a
+// migrator racing another fails on the recorded version rather than applying it twice.
+func (m *Migrator) lock(ctx context.Context, conn *sql.Conn) error {
+	var err error
+	switch m.driver() {
+	case "pgx":
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", m.lockKey())
+	case "mysql":
+		// MySQL locks are held for the whole server, so the name includes the database.
+		var acquired sql.NullInt64
+		err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(LEFT(CONCAT(DATABASE(), '.', ?), 64), -1)", m.table()).Scan(&acquired)
+		if err == nil && acquired.Int64 != 1 {
+			err = errors.New("lock was not granted")
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to lock migrations: %w", err)
+	}
+	return nil
+}
+
+// Release the advisory lock. A connection whose lock cannot be released is discarded
+// rather than returned to the pool still holding the lock.
+func (m *Migrator) unlock(ctx context.Context, conn *sql.Conn) {
+	var err error
+	switch m.driver() {
+	case "pgx":
+		_, err = conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", m.lockKey())
+	case "mysql":
+		_, err = conn.ExecContext(ctx, "SELECT RELEASE_LOCK(LEFT(CONCAT(DATABASE(), '.', ?), 64))", m.table())
+	}
+
+	if err != nil {
+		conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+}
+
+// Return the key of the Postgres advisory lock, derived from the migrations table so
+// migrators recording into different tables do not wait for each other.
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("adele:" + m.table()))
+	return int64(h.Sum64())
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return DefaultMigrationsTable
+	}
+	return m.Table
+}
+
+func (m *Migrator) driver() string {
+	return Driver(m.DB.DataType)
+}
+
+// Return the name migration files for the database are marked with.
+func (m *Migrator) dialect() (string, error) {
+	switch m.driver() {
+	case "pgx":
+		return "postgres", nil
+	case "mysql":
+		return "mysql", nil
+	case "sqlite":
+		return "sqlite", nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDriver, m.DB.DataType)
+	}
+}
+
+// Rewrite the ? placeholders of the query into the form the driver expects.
+func bind(driver, query string) (string, error) {
+	switch driver {
+	case "mysql", "sqlite":
+		return query, nil
+	case "pgx":
+		var b strings.Builder
+		n := 0
+		for _, r := range query {
+			if r == '?' {
+				n++
+				b.WriteString("$" + strconv.Itoa(n))
+				continue
+			}
+			b.WriteRune(r)
+		}
+		return b.String(), nil
+	default:
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedDriver, driver)
+	}
+}
+
+// Split a script into its statements at the semicolons outside of quotes, comments and
+// Postgres dollar quoted strings, e.g., the body of a function. MySQL also escapes quotes
+// with a backslash and starts comments with #.
+func splitStatements(script, dialect string) []string {
+	mysql := dialect == "mysql"
+
+	var statements []string
+	start := 0
+
+	add := func(end int) {
+		if stmt := strings.TrimSpace(script[start:end]); stmt != "" && !onlyComments(stmt, mysql) {
+			statements = append(statements, stmt)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(script); i++ {
+				if mysql && script[i] == '\\' && c != '`' {
+					i++
+					continue
+				}
+				if script[i] == c {
+					// A doubled quote is a quote within the string.
+					if i+1 < len(script) && script[i+1] == c {
+						i++
+						continue
+					}
+					break
+				}
+			}
+		case c == '-' && strings.HasPrefix(script[i:], "--"), mysql && c == '#':
+			if end := strings.IndexByte(script[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(script)
+			}
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			if end := strings.Index(script[i+2:], "*/"); end >= 0 {
+				i += end + 3
+			} else {
+				i = len(script)
+			}
+		case c == '$' && dialect == "postgres":
+			if tag := dollarTag(script[i:]); tag != "" {
+				if end := strings.Index(script[i+len(tag):], tag); end >= 0 {
+					i += len(tag) + end + len(tag) - 1
+				} else {
+					i = len(script)
+				}
+			}
+		case c == ';':
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(script))
+
+	return statements
+}
+
+// Return the tag opening a dollar quoted string, e.g., $$ or $body$, at the start of the
+// string, or an empty string when there is none.
+func dollarTag(s string) string {
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == '$' {
+			return s[:i+1]
+		}
+		if !(c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || i > 1 && c >= '0' && c <= '9') {
+			return ""
+		}
+	}
+	return ""
+}
+
+// Report whether a statement holds nothing but comments, e.g., the end of a script.
+func onlyComments(stmt string, mysql bool) bool {
+	for _, line := range strings.Split(stmt, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "--") && !(mysql && strings.HasPrefix(line, "#")) {
+			return false
+		}
+	}
+	return true
+}
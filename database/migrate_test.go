@@ -0,0 +1,282 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		dialect string
+		want    []string
+	}{
+		{
+			name:    "statements",
+			script:  "CREATE TABLE a (id INT);\n\nCREATE TABLE b (id INT);\n",
+			dialect: "postgres",
+			want:    []string{"CREATE TABLE a (id INT)", "CREATE TABLE b (id INT)"},
+		},
+		{
+			name:    "quotes and comments",
+			script:  "-- a; comment\nINSERT INTO a VALUES ('it''s; fine', \"x;y\"); /* b; */ SELECT 1;\n-- end;",
+			dialect: "postgres",
+			want:    []string{"-- a; comment\nINSERT INTO a VALUES ('it''s; fine', \"x;y\")", "/* b; */ SELECT 1"},
+		},
+		{
+			name:    "dollar quoted function",
+			script:  "CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE SQL;\nSELECT $1;",
+			dialect: "postgres",
+			want:    []string{"CREATE FUNCTION f() RETURNS INT AS $body$ SELECT 1; $body$ LANGUAGE SQL", "SELECT $1"},
+		},
+		{
+			name:    "mysql escapes and comments",
+			script:  "INSERT INTO a VALUES ('it\\'s; fine'); # b; comment\nSELECT `c;d`;",
+			dialect: "mysql",
+			want:    []string{"INSERT INTO a VALUES ('it\\'s; fine')", "# b; comment\nSELECT `c;d`"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitStatements(tt.script, tt.dialect)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrator_Load(t *testing.T) {
+	fsys := fstest.MapFS{
+		"20261018000000_create_users.up.sql":       {Data: []byte("CREATE TABLE users (id INT);")},
+		"20261018000000_create_users.down.sql":     {Data: []byte("DROP TABLE users;")},
+		"20261018000001_add_index.up.sql":          {Data: []byte("CREATE INDEX a ON users (id);")},
+		"20261018000001_add_index.postgres.up.sql": {Data: []byte("CREATE INDEX CONCURRENTLY a ON users (id);")},
+		"20261018000001_add_index.mysql.down.sql":  {Data: []byte("DROP INDEX a ON users;")},
+		"README.md": {Data: []byte("Migrations")},
+	}
+
+	noop := func(ctx context.Context, tx *sql.Tx) error { return nil }
+
+	m := (&Database{DataType: "postgres"}).Migrator(fsys, Migration{Version: 20261018000002, Name: "seed", Up: noop})
+
+	migrations, err := m.load()
+	if err != nil {
+		t.Fatalf("load() error = %v", err)
+	}
+
+	if len(migrations) != 3 {
+		t.Fatalf("load() returned %d migrations, want 3", len(migrations))
+	}
+
+	for i, want := range []string{"create_users", "add_index", "seed"} {
+		if migrations[i].Name != want {
+			t.Errorf("migration %d = %s, want %s", i, migrations[i].Name, want)
+		}
+	}
+
+	if migrations[0].Down == nil {
+		t.Error("create_users has no down migration")
+	}
+	if migrations[1].Down != nil {
+		t.Error("add_index has the down migration written for mysql")
+	}
+
+	m.Migrations = append(m.Migrations, Migration{Version: 20261018000000, Name: "duplicate", Up: noop})
+	if _, err := m.load(); err == nil {
+		t.Error("load() error = nil, want an error for a duplicate version")
+	}
+
+	m = (&Database{DataType: "postgres"}).Migrator(fstest.MapFS{
+		"20261018000000_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+	})
+	if _, err := m.load(); err == nil {
+		t.Error("load() error = nil, want an error for a migration without up")
+	}
+}
+
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+
+	if err != nil {
+		t.Fatalf("Failed to start container: %v", err)
+	}
+	defer container.Terminate(ctx)
+
+	connStr, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("Failed to get connection string: %v", err)
+	}
+
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		t.Fatalf("Failed to connect: %v", err)
+	}
+	defer db.Close()
+
+	d := &Database{DataType: "pgx", Pool: db}
+
+	m := d.Migrator(fstest.MapFS{
+		"20261018000000_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT); INSERT INTO users VALUES (1);")},
+		"20261018000000_create_users.down.sql": {Data: []byte("DROP TABLE users;")},
+		"20261018000001_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INT);")},
+		"20261018000001_create_posts.down.sql": {Data: []byte("DROP TABLE posts;")},
+	})
+
+	// Instances deployed together apply each migration once.
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs <- m.Up(ctx)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Up() error = %v", err)
+		}
+	}
+
+	var users int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users); err != nil || users != 1 {
+		t.Errorf("users = %d, %v; want 1 row", users, err)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(status) != 2 || !status[0].Applied || !status[1].Applied || status[1].AppliedAt.IsZero() {
+		t.Errorf("Status() = %+v, want both migrations applied", status)
+	}
+
+	if err := m.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM posts"); err == nil {
+		t.Error("posts table exists after Down")
+	}
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := m.Redo(ctx); err != nil {
+		t.Fatalf("Redo() error = %v", err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM posts"); err != nil {
+		t.Errorf("posts table is missing after Redo: %v", err)
+	}
+
+	// A failed migration leaves nothing behind.
+	failing := d.Migrator(fstest.MapFS{
+		"20261018000002_broken.up.sql": {Data: []byte("CREATE TABLE broken (id INT); SELECT * FROM missing;")},
+	})
+	if err := failing.Up(ctx); err == nil {
+		t.Error("Up() error = nil, want the error of the broken migration")
+	}
+	if _, err := db.Exec("SELECT 1 FROM broken"); err == nil {
+		t.Error("broken table exists after the migration failed")
+	}
+
+	if err := m.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	status, err = m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	for _, s := range status {
+		if s.Applied {
+			t.Errorf("migration %d is applied after Reset", s.Version)
+		}
+	}
+
+	noDown := d.Migrator(nil, Migration{Version: 1, Name: "one_way", Up: func(ctx context.Context, tx *sql.Tx) error { return nil }})
+	if err := noDown.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+	if err := noDown.Down(ctx, 1); !errors.Is(err, ErrIrreversibleMigration) {
+		t.Errorf("Down() error = %v, want ErrIrreversibleMigration", err)
+	}
+}
+
+func TestMigrator_Sqlite(t *testing.T) {
+	ctx := context.Background()
+
+	db, err := OpenDB("sqlite", &DataSourceName{DatabaseName: filepath.Join(t.TempDir(), "app.db")})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer db.Close()
+
+	d := &Database{DataType: "sqlite", Pool: db}
+
+	m := d.Migrator(fstest.MapFS{
+		"20261018000000_create_users.up.sql":          {Data: []byte("CREATE TABLE users (id INT); INSERT INTO users VALUES (1);")},
+		"20261018000000_create_users.down.sql":        {Data: []byte("DROP TABLE users;")},
+		"20261018000000_create_users.postgres.up.sql": {Data: []byte("CREATE TABLE users (id SERIAL);")},
+		"20261018000001_create_posts.up.sql":          {Data: []byte("CREATE TABLE posts (id INT);")},
+		"20261018000001_create_posts.down.sql":        {Data: []byte("DROP TABLE posts;")},
+	})
+
+	if err := m.Up(ctx); err != nil {
+		t.Fatalf("Up() error = %v", err)
+	}
+
+	var users int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&users); err != nil || users != 1 {
+		t.Errorf("users = %d, %v; want 1 row", users, err)
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	if len(status) != 2 || !status[0].Applied || !status[1].Applied || status[1].AppliedAt.IsZero() {
+		t.Errorf("Status() = %+v, want both migrations applied", status)
+	}
+
+	if err := m.Down(ctx, 1); err != nil {
+		t.Fatalf("Down() error = %v", err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM posts"); err == nil {
+		t.Error("posts table exists after Down")
+	}
+
+	if err := m.Reset(ctx); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if _, err := db.Exec("SELECT 1 FROM users"); err == nil {
+		t.Error("users table exists after Reset")
+	}
+}
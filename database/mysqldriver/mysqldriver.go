@@ -22,3 +22,13 @@ func Session(pool *sql.DB) (db.Session, error) {
 
 	return session, nil
 }
+
+// Create a new MySQL builder session running its queries in the transaction
+func TxSession(tx *sql.Tx) (db.Session, error) {
+	session, err := mysql.NewTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
@@ -23,3 +23,13 @@ func Session(pool *sql.DB) (db.Session, error) {
 
 	return session, nil
 }
+
+// Create a new Postgres builder session running its queries in the transaction
+func TxSession(tx *sql.Tx) (db.Session, error) {
+	session, err := postgresql.NewTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
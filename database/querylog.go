@@ -0,0 +1,294 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// Return the context counting the statements run with it, or any context derived from
+// it, on a database opened with an OnQuery hook. A context already counting is returned
+// unchanged.
+func TrackQueries(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(queriesKey{}).(*QueryStats); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, queriesKey{}, &QueryStats{statements: map[string]int{}})
+}
+
+// Return the statements counted for the context by TrackQueries.
+func QueryStatsFromContext(ctx context.Context) (*QueryStats, bool) {
+	s, ok := ctx.Value(queriesKey{}).(*QueryStats)
+	return s, ok
+}
+
+// Middleware counting the statements run with the context of every request, calling the
+// report function once the request was handled. Example:
+//
+//	mux.Use(database.TrackQueriesHandler(func(r *http.Request, stats *database.QueryStats) {
+//	    log.Printf("%s ran %d queries in %s", r.URL.Path, stats.Count(), stats.Duration())
+//	}))
+func TrackQueriesHandler(report func(r *http.Request, stats *QueryStats)) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := TrackQueries(r.Context())
+			r = r.WithContext(ctx)
+
+			next.ServeHTTP(w, r)
+
+			if stats, ok := QueryStatsFromContext(ctx); ok && report != nil {
+				report(r, stats)
+			}
+		})
+	}
+}
+
+// Return the number of statements run.
+func (s *QueryStats) Count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.count
+}
+
+// Return the time spent running the statements.
+func (s *QueryStats) Duration() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.duration
+}
+
+// Return the statements run at least threshold times, most repeated first, e.g., a query
+// run for every row of another, which is better written as a single query.
+func (s *QueryStats) Repeated(threshold int) []RepeatedQuery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var repeated []RepeatedQuery
+	for statement, count := range s.statements {
+		if count >= threshold {
+			repeated = append(repeated, RepeatedQuery{Statement: statement, Count: count})
+		}
+	}
+
+	sort.Slice(repeated, func(i, j int) bool {
+		if repeated[i].Count != repeated[j].Count {
+			return repeated[i].Count > repeated[j].Count
+		}
+		return repeated[i].Statement < repeated[j].Statement
+	})
+
+	return repeated
+}
+
+func (s *QueryStats) record(q Query) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.count++
+	s.duration += q.Duration
+	s.statements[q.Statement]++
+}
+
+// The context key of the query statistics.
+type queriesKey struct{}
+
+// Report a statement once it finished to the query statistics of the context and the hook.
+func report(ctx context.Context, hook func(ctx context.Context, q Query), statement string, args []driver.NamedValue, start time.Time, err error) {
+	q := Query{
+		Statement: statement,
+		Duration:  time.Since(start),
+		Err:       err,
+	}
+
+	if len(args) > 0 {
+		q.Args = make([]interface{}, len(args))
+		for i, arg := range args {
+			q.Args[i] = arg.Value
+		}
+	}
+
+	if s, ok := QueryStatsFromContext(ctx); ok {
+		s.record(q)
+	}
+	hook(ctx, q)
+}
+
+// Wrap the connector so the statements run on its connections are reported to the hook.
+func instrument(c driver.Connector, hook func(ctx context.Context, q Query)) driver.Connector {
+	return &instrumentedConnector{Connector: c, hook: hook}
+}
+
+// A connector for a driver that opens its connections from a data source name only.
+type dsnConnector struct {
+	dsn    string
+	driver driver.Driver
+}
+
+func (c dsnConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	return c.driver.Open(c.dsn)
+}
+
+func (c dsnConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+type instrumentedConnector struct {
+	driver.Connector
+	hook func(ctx context.Context, q Query)
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedConn{Conn: conn, hook: c.hook}, nil
+}
+
+// A connection reporting its statements. The optional interfaces of the driver are passed
+// through, falling back to what database/sql does for a driver without them.
+type instrumentedConn struct {
+	driver.Conn
+	hook func(ctx context.Context, q Query)
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	var stmt driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		stmt, err = p.PrepareContext(ctx, query)
+	} else {
+		stmt, err = c.Conn.Prepare(query)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &instrumentedStmt{Stmt: stmt, conn: c, query: query}, nil
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return b.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, errors.New("driver does not support transaction options")
+	}
+	return c.Conn.Begin() //nolint:staticcheck
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	if !errors.Is(err, driver.ErrSkip) {
+		report(ctx, c.hook, query, args, start, err)
+	}
+	return res, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+
+	start := time.Now()
+	rows, err := q.QueryContext(ctx, query, args)
+	if !errors.Is(err, driver.ErrSkip) {
+		report(ctx, c.hook, query, args, start, err)
+	}
+	return rows, err
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) ResetSession(ctx context.Context) error {
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		return r.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *instrumentedConn) IsValid() bool {
+	if v, ok := c.Conn.(driver.Validator); ok {
+		return v.IsValid()
+	}
+	return true
+}
+
+func (c *instrumentedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+// A prepared statement reporting every time it runs.
+type instrumentedStmt struct {
+	driver.Stmt
+	conn  *instrumentedConn
+	query string
+}
+
+func (s *instrumentedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	start := time.Now()
+
+	var res driver.Result
+	var err error
+	if e, ok := s.Stmt.(driver.StmtExecContext); ok {
+		res, err = e.ExecContext(ctx, args)
+	} else {
+		res, err = s.Stmt.Exec(values(args)) //nolint:staticcheck
+	}
+
+	report(ctx, s.conn.hook, s.query, args, start, err)
+	return res, err
+}
+
+func (s *instrumentedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	start := time.Now()
+
+	var rows driver.Rows
+	var err error
+	if q, ok := s.Stmt.(driver.StmtQueryContext); ok {
+		rows, err = q.QueryContext(ctx, args)
+	} else {
+		rows, err = s.Stmt.Query(values(args)) //nolint:staticcheck
+	}
+
+	report(ctx, s.conn.hook, s.query, args, start, err)
+	return rows, err
+}
+
+// Check the argument with the checker of the statement, if any, or else of the
+// connection, since database/sql only asks the connection when the statement has none.
+func (s *instrumentedStmt) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := s.Stmt.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return s.conn.CheckNamedValue(nv)
+}
+
+// Return the values of named arguments for the driver methods taking positional ones.
+func values(args []driver.NamedValue) []driver.Value {
+	v := make([]driver.Value, len(args))
+	for i, arg := range args {
+		v[i] = arg.Value
+	}
+	return v
+}
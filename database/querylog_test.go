@@ -0,0 +1,135 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Collects the queries reported to OnQuery.
+type queryLog struct {
+	mu      sync.Mutex
+	queries []Query
+}
+
+func (l *queryLog) hook(ctx context.Context, q Query) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.queries = append(l.queries, q)
+}
+
+func (l *queryLog) all() []Query {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Query(nil), l.queries...)
+}
+
+func TestOnQuery(t *testing.T) {
+	log := &queryLog{}
+
+	pool, err := OpenDB("sqlite", &DataSourceName{DatabaseName: ":memory:", OnQuery: log.hook})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer pool.Close()
+
+	d := &Database{DataType: "sqlite", Pool: pool}
+	ctx := TrackQueries(context.Background())
+
+	if _, err := pool.ExecContext(ctx, "CREATE TABLE posts (id INTEGER PRIMARY KEY, title TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	posts := d.Session(ctx).Collection("posts")
+	for _, title := range []string{"One", "Two", "Three"} {
+		if _, err := posts.Insert(map[string]string{"title": title}); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+	}
+
+	var title string
+	if err := pool.QueryRowContext(ctx, "SELECT title FROM posts WHERE id = ?", 2).Scan(&title); err != nil || title != "Two" {
+		t.Fatalf("title = %q, %v; want Two", title, err)
+	}
+
+	if _, err := pool.ExecContext(ctx, "INSERT INTO missing VALUES (1)"); err == nil {
+		t.Fatal("ExecContext() error = nil, want missing table")
+	}
+
+	var selected, failed bool
+	for _, q := range log.all() {
+		if q.Statement == "SELECT title FROM posts WHERE id = ?" {
+			selected = len(q.Args) == 1 && q.Args[0] == int64(2) && q.Err == nil
+		}
+		if q.Statement == "INSERT INTO missing VALUES (1)" {
+			failed = q.Err != nil
+		}
+	}
+	if !selected {
+		t.Errorf("query with its arguments was not reported: %+v", log.all())
+	}
+	if !failed {
+		t.Errorf("failed statement was not reported with its error: %+v", log.all())
+	}
+
+	stats, ok := QueryStatsFromContext(ctx)
+	if !ok {
+		t.Fatal("QueryStatsFromContext() found no statistics")
+	}
+	if stats.Count() < 6 {
+		t.Errorf("Count() = %d, want at least 6", stats.Count())
+	}
+
+	repeated := stats.Repeated(3)
+	if len(repeated) != 1 || repeated[0].Count != 3 {
+		t.Errorf("Repeated(3) = %+v, want the insert run 3 times", repeated)
+	}
+	if len(stats.Repeated(4)) != 0 {
+		t.Errorf("Repeated(4) = %+v, want none", stats.Repeated(4))
+	}
+}
+
+func TestOnQuery_Fallback(t *testing.T) {
+	log := &queryLog{}
+
+	// The recorder opens connections from a name only and cannot prepare statements.
+	pool := sql.OpenDB(instrument(dsnConnector{driver: txRecorder}, log.hook))
+	defer pool.Close()
+	txRecorder.reset()
+
+	tx, err := pool.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec("INSERT order"); err != nil {
+		t.Fatal(err)
+	}
+	tx.Commit()
+
+	if queries := log.all(); len(queries) != 1 || queries[0].Statement != "INSERT order" {
+		t.Errorf("queries = %+v, want the insert", queries)
+	}
+	if got := txRecorder.reset(); len(got) != 3 {
+		t.Errorf("statements = %q, want the insert in a transaction", got)
+	}
+}
+
+func TestTrackQueriesHandler(t *testing.T) {
+	var count int
+	handler := TrackQueriesHandler(func(r *http.Request, stats *QueryStats) {
+		count = stats.Count()
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		stats, _ := QueryStatsFromContext(r.Context())
+		stats.record(Query{Statement: "SELECT 1"})
+		stats.record(Query{Statement: "SELECT 1"})
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if count != 2 {
+		t.Errorf("reported count = %d, want 2", count)
+	}
+}
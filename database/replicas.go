@@ -0,0 +1,211 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// Policies selecting the replica a query runs on.
+const (
+	RoundRobin       = "round-robin"
+	LeastConnections = "least-connections"
+)
+
+// The sticky window used when the replicas do not set one.
+const DefaultStickyWindow = 5 * time.Second
+
+// Create replicas selected by the policy, i.e., round-robin or least-connections.
+func NewReplicas(policy string, pools ...*sql.DB) *Replicas {
+	return &Replicas{
+		Pools:  pools,
+		Policy: policy,
+	}
+}
+
+// Open a pool for each replica host, given as host or host:port, with the settings of
+// the data source of the primary. A replica that cannot be reached does not fail the
+// call; it starts ejected and is admitted once CheckReplicas reaches it. Example:
+//
+//	replicas, err := database.OpenReplicas("postgres", dsn, []string{"replica-1", "replica-2:5433"}, database.RoundRobin)
+func OpenReplicas(dbType string, config *DataSourceName, hosts []string, policy string) (*Replicas, error) {
+	switch policy {
+	case "", RoundRobin, LeastConnections:
+	default:
+		return nil, fmt.Errorf("unknown replica policy %q", policy)
+	}
+
+	pools := make([]*sql.DB, 0, len(hosts))
+	for _, host := range hosts {
+		replica := *config
+		replica.Host = host
+		if h, port, err := net.SplitHostPort(host); err == nil {
+			replica.Host, replica.Port = h, port
+		}
+
+		pool, err := openPool(dbType, &replica)
+		if err != nil {
+			for _, p := range pools {
+				p.Close()
+			}
+			return nil, fmt.Errorf("failed to open replica %s: %w", host, err)
+		}
+		pools = append(pools, pool)
+	}
+
+	r := NewReplicas(policy, pools...)
+	for i, pool := range pools {
+		if err := ping(pool, config); err != nil {
+			r.eject(i, true)
+		}
+	}
+
+	return r, nil
+}
+
+// Ping every replica, ejecting the replicas that fail until a later check reaches them
+// again. Run it on a schedule. Example:
+//
+//	app.Scheduler.AddFunc("@every 10s", func() {
+//	    app.DB.Replicas.CheckReplicas(context.Background())
+//	})
+func (r *Replicas) CheckReplicas(ctx context.Context) {
+	for i, pool := range r.Pools {
+		err := pool.PingContext(ctx)
+
+		if r.eject(i, err != nil) && r.OnChange != nil {
+			r.OnChange(i, err)
+		}
+	}
+}
+
+// Report whether the replica is ejected.
+func (r *Replicas) Down(replica int) bool {
+	_, down := r.down.Load(replica)
+	return down
+}
+
+// Eject or admit the replica, reporting whether that changed its state.
+func (r *Replicas) eject(replica int, down bool) bool {
+	if down {
+		_, loaded := r.down.LoadOrStore(replica, struct{}{})
+		return !loaded
+	}
+	_, loaded := r.down.LoadAndDelete(replica)
+	return loaded
+}
+
+// Close the pools of the replicas.
+func (r *Replicas) Close() error {
+	var errs []error
+	for _, pool := range r.Pools {
+		errs = append(errs, pool.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// Select a replica that is not ejected, or return nil when every replica is.
+func (r *Replicas) pick() *sql.DB {
+	n := len(r.Pools)
+	if n == 0 {
+		return nil
+	}
+
+	if r.Policy == LeastConnections {
+		var best *sql.DB
+		inUse := 0
+		for i, pool := range r.Pools {
+			if r.Down(i) {
+				continue
+			}
+			if used := pool.Stats().InUse; best == nil || used < inUse {
+				best, inUse = pool, used
+			}
+		}
+		return best
+	}
+
+	// Take turns among the replicas that are not ejected, so the load of an ejected
+	// replica is spread evenly over the others.
+	up := make([]*sql.DB, 0, n)
+	for i, pool := range r.Pools {
+		if !r.Down(i) {
+			up = append(up, pool)
+		}
+	}
+	if len(up) == 0 {
+		return nil
+	}
+	return up[(r.next.Add(1)-1)%uint64(len(up))]
+}
+
+func (r *Replicas) stickyWindow() time.Duration {
+	if r.StickyWindow == 0 {
+		return DefaultStickyWindow
+	}
+	return r.StickyWindow
+}
+
+// Return the pool for reading: a replica, or the primary when there is no replica to read
+// from or the context wrote to the primary within the sticky window.
+func (a *Database) Reader(ctx context.Context) *sql.DB {
+	r := a.Replicas
+	if r == nil {
+		return a.Pool
+	}
+
+	if t, ok := ctx.Value(writesKey{}).(*writes); ok && time.Since(time.Unix(0, t.last.Load())) < r.stickyWindow() {
+		return a.Pool
+	}
+
+	if pool := r.pick(); pool != nil {
+		return pool
+	}
+	return a.Pool
+}
+
+// Return the primary pool for writing, marking the context as having written so it reads
+// from the primary for the sticky window. Example:
+//
+//	_, err := app.DB.Writer(r.Context()).ExecContext(r.Context(), "UPDATE posts SET title = $1 WHERE id = $2", title, id)
+func (a *Database) Writer(ctx context.Context) *sql.DB {
+	MarkWritten(ctx)
+	return a.Pool
+}
+
+// The time of the last write made with a context, in Unix nanoseconds.
+type writes struct {
+	last atomic.Int64
+}
+
+// The context key of the writes.
+type writesKey struct{}
+
+// Return a context tracking its writes, which Reader needs to keep a request reading
+// from the primary after it wrote.
+func TrackWrites(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(writesKey{}).(*writes); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, writesKey{}, &writes{})
+}
+
+// Record a write to the primary made with a context tracking its writes, e.g., through a
+// session from NewSession rather than from Writer.
+func MarkWritten(ctx context.Context) {
+	if t, ok := ctx.Value(writesKey{}).(*writes); ok {
+		t.last.Store(time.Now().UnixNano())
+	}
+}
+
+// Middleware tracking the writes of each request; see TrackWrites.
+func TrackWritesHandler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(TrackWrites(r.Context())))
+	})
+}
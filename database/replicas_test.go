@@ -0,0 +1,146 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// A driver whose database cannot be reached, for testing ejected replicas.
+type unreachableDriver struct{}
+
+func (unreachableDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("connection refused")
+}
+
+func init() {
+	sql.Register("unreachable", unreachableDriver{})
+}
+
+func TestReplicas_RoundRobin(t *testing.T) {
+	primary, _ := sql.Open("stub", "primary")
+	one, _ := sql.Open("stub", "one")
+	two, _ := sql.Open("stub", "two")
+	down, _ := sql.Open("unreachable", "")
+
+	d := &Database{DataType: "postgres", Pool: primary, Replicas: NewReplicas(RoundRobin, one, down, two)}
+	defer d.Close()
+
+	var changes []error
+	d.Replicas.OnChange = func(replica int, err error) {
+		if replica != 1 {
+			t.Errorf("OnChange() replica = %d, want 1", replica)
+		}
+		changes = append(changes, err)
+	}
+
+	d.Replicas.CheckReplicas(context.Background())
+	d.Replicas.CheckReplicas(context.Background())
+
+	if len(changes) != 1 || changes[0] == nil {
+		t.Fatalf("changes = %v, want the replica ejected once", changes)
+	}
+
+	ctx := context.Background()
+	got := []*sql.DB{d.Reader(ctx), d.Reader(ctx), d.Reader(ctx), d.Reader(ctx)}
+	want := []*sql.DB{one, two, one, two}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("read %d went to the wrong pool", i)
+		}
+	}
+
+	// Admit the replica again once it can be reached.
+	down.Close()
+	d.Replicas.Pools[1], _ = sql.Open("stub", "recovered")
+	d.Replicas.CheckReplicas(context.Background())
+	if d.Replicas.Down(1) || len(changes) != 2 || changes[1] != nil {
+		t.Errorf("replica is down = %v, changes = %v; want it admitted", d.Replicas.Down(1), changes)
+	}
+}
+
+func TestReplicas_Literal(t *testing.T) {
+	one, _ := sql.Open("stub", "one")
+	down, _ := sql.Open("unreachable", "")
+	defer one.Close()
+	defer down.Close()
+
+	// Replicas built without NewReplicas, or given more pools later, work the same.
+	r := &Replicas{Pools: []*sql.DB{one}}
+	if r.Down(0) || r.pick() != one {
+		t.Fatal("a replica in a literal should start admitted")
+	}
+
+	r.Pools = append(r.Pools, down)
+	r.CheckReplicas(context.Background())
+	if r.Down(0) || !r.Down(1) {
+		t.Errorf("Down() = %v, %v; want the added replica ejected", r.Down(0), r.Down(1))
+	}
+	for i := 0; i < 3; i++ {
+		if r.pick() != one {
+			t.Errorf("read %d went to an ejected replica", i)
+		}
+	}
+}
+
+func TestReplicas_LeastConnections(t *testing.T) {
+	primary, _ := sql.Open("stub", "primary")
+	busy, _ := sql.Open("stub", "busy")
+	idle, _ := sql.Open("stub", "idle")
+
+	d := &Database{DataType: "postgres", Pool: primary, Replicas: NewReplicas(LeastConnections, busy, idle)}
+	defer d.Close()
+
+	conn, err := busy.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	if d.Reader(context.Background()) != idle {
+		t.Error("read did not go to the replica with the fewest connections in use")
+	}
+}
+
+func TestReplicas_Sticky(t *testing.T) {
+	primary, _ := sql.Open("stub", "primary")
+	replica, _ := sql.Open("stub", "replica")
+
+	d := &Database{DataType: "postgres", Pool: primary, Replicas: NewReplicas(RoundRobin, replica)}
+	d.Replicas.StickyWindow = 50 * time.Millisecond
+	defer replica.Close()
+	defer d.Close()
+
+	var before, after *sql.DB
+	h := TrackWritesHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		before = d.Reader(r.Context())
+		d.Writer(r.Context())
+		after = d.Reader(r.Context())
+	}))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	if before != replica {
+		t.Error("read before the write did not go to the replica")
+	}
+	if after != primary {
+		t.Error("read after the write did not go to the primary")
+	}
+
+	ctx := TrackWrites(context.Background())
+	MarkWritten(ctx)
+	time.Sleep(60 * time.Millisecond)
+	if d.Reader(ctx) != replica {
+		t.Error("read after the sticky window did not go to the replica")
+	}
+
+	// Without replicas every read goes to the primary.
+	d.Replicas = nil
+	if d.Reader(context.Background()) != primary {
+		t.Error("read without replicas did not go to the primary")
+	}
+}
@@ -0,0 +1,372 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/upper/db/v4"
+)
+
+// ErrNotFound is returned by a repository when no row matches, including a row that was
+// soft deleted.
+var ErrNotFound = errors.New("record not found")
+
+// Columns a repository manages on the models that have them.
+const (
+	CreatedAtColumn = "created_at"
+	UpdatedAtColumn = "updated_at"
+	DeletedAtColumn = "deleted_at"
+)
+
+// Create a repository of the model stored in the table of the database. The queries of
+// the repository run in the transaction of their context, if any, and read from a
+// replica otherwise. Example:
+//
+//	type User struct {
+//	    ID        int64        `db:"id,omitempty"`
+//	    Email     string       `db:"email"`
+//	    CreatedAt time.Time    `db:"created_at"`
+//	    UpdatedAt time.Time    `db:"updated_at"`
+//	    DeletedAt sql.NullTime `db:"deleted_at"`
+//	}
+//
+//	users := database.NewRepository[User](app.DB, "users")
+//	users.SoftDelete = true
+//
+//	user, err := users.Find(r.Context(), 42)
+func NewRepository[T any](d *Database, table string) *Repository[T] {
+	return &Repository[T]{DB: d, Table: table}
+}
+
+// Return the row with the primary key, or ErrNotFound.
+func (r *Repository[T]) Find(ctx context.Context, id interface{}) (*T, error) {
+	return r.First(ctx, db.Cond{r.pk(): id})
+}
+
+// Return the first row matching the condition in the order of the primary key, or
+// ErrNotFound.
+func (r *Repository[T]) First(ctx context.Context, cond db.Cond) (*T, error) {
+	res, err := r.find(r.reader(ctx), cond)
+	if err != nil {
+		return nil, err
+	}
+
+	var item T
+	if err := res.OrderBy(r.pk()).One(&item); err != nil {
+		if errors.Is(err, db.ErrNoMoreRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &item, nil
+}
+
+// Return every row matching the condition in the order of the primary key. Example:
+//
+//	admins, err := users.FindBy(ctx, db.Cond{"role": "admin", "active": true})
+func (r *Repository[T]) FindBy(ctx context.Context, cond db.Cond) ([]T, error) {
+	res, err := r.find(r.reader(ctx), cond)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []T
+	if err := res.OrderBy(r.pk()).All(&items); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// Return a page of the rows matching the condition, or of every row when the condition
+// is nil, in the order of the primary key. Pages are numbered from 1. Example:
+//
+//	page, err := users.Paginate(ctx, nil, 2, 25)
+func (r *Repository[T]) Paginate(ctx context.Context, cond db.Cond, page, perPage int) (*Page[T], error) {
+	if page < 1 || perPage < 1 {
+		return nil, fmt.Errorf("invalid page %d of %d rows", page, perPage)
+	}
+
+	res, err := r.find(r.reader(ctx), cond)
+	if err != nil {
+		return nil, err
+	}
+	res = res.OrderBy(r.pk()).Paginate(uint(perPage)).Page(uint(page))
+
+	p := &Page[T]{Page: page, PerPage: perPage}
+	if err := res.All(&p.Items); err != nil {
+		return nil, err
+	}
+	if p.Total, err = res.TotalEntries(); err != nil {
+		return nil, err
+	}
+	pages, err := res.TotalPages()
+	if err != nil {
+		return nil, err
+	}
+	p.Pages = uint64(pages)
+
+	return p, nil
+}
+
+// Insert the model, setting its created_at and updated_at fields to the current time,
+// and update it with the row as stored, e.g., with its primary key.
+func (r *Repository[T]) Insert(ctx context.Context, item *T) error {
+	now := r.clock()
+	if err := setTime(item, CreatedAtColumn, now, false); err != nil {
+		return err
+	}
+	if err := setTime(item, UpdatedAtColumn, now, true); err != nil {
+		return err
+	}
+
+	sess, err := r.writer(ctx)
+	if err != nil {
+		return err
+	}
+	return sess.Collection(r.Table).InsertReturning(item)
+}
+
+// Update the row of the model with its fields, setting its updated_at field to the
+// current time. ErrNotFound is returned when the row does not exist.
+func (r *Repository[T]) Update(ctx context.Context, item *T) error {
+	id, err := columnValue(item, r.pk())
+	if err != nil {
+		return err
+	}
+
+	if err := setTime(item, UpdatedAtColumn, r.clock(), true); err != nil {
+		return err
+	}
+
+	sess, err := r.writer(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.update(ctx, sess, r.live(id), item)
+}
+
+// Delete the row with the primary key, or set its deleted_at column to the current time
+// with SoftDelete. ErrNotFound is returned when the row does not exist.
+func (r *Repository[T]) Delete(ctx context.Context, id interface{}) error {
+	if !r.SoftDelete {
+		return r.ForceDelete(ctx, id)
+	}
+
+	sess, err := r.writer(ctx)
+	if err != nil {
+		return err
+	}
+
+	return r.update(ctx, sess, r.live(id), map[string]interface{}{DeletedAtColumn: r.clock()})
+}
+
+// Delete the row with the primary key, whether soft deleted or not.
+func (r *Repository[T]) ForceDelete(ctx context.Context, id interface{}) error {
+	sess, err := r.writer(ctx)
+	if err != nil {
+		return err
+	}
+
+	where := db.Cond{r.pk(): id}
+	res, err := sess.SQL().DeleteFrom(r.Table).Where(where).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	return r.written(sess, res, where)
+}
+
+// Restore the soft deleted row with the primary key.
+func (r *Repository[T]) Restore(ctx context.Context, id interface{}) error {
+	sess, err := r.writer(ctx)
+	if err != nil {
+		return err
+	}
+
+	where := db.Cond{r.pk(): id, DeletedAtColumn: db.IsNotNull()}
+	return r.update(ctx, sess, where, map[string]interface{}{DeletedAtColumn: nil})
+}
+
+// Return the rows matching the condition that are not soft deleted.
+func (r *Repository[T]) find(sess db.Session, cond db.Cond) (db.Result, error) {
+	if sess == nil {
+		return nil, errors.New("database is not configured")
+	}
+
+	var conds []interface{}
+	if len(cond) > 0 {
+		conds = append(conds, cond)
+	}
+	if r.SoftDelete {
+		conds = append(conds, db.Cond{DeletedAtColumn: db.IsNull()})
+	}
+
+	return sess.Collection(r.Table).Find(conds...), nil
+}
+
+// Return the condition matching the row with the primary key unless it is soft deleted.
+func (r *Repository[T]) live(id interface{}) db.Cond {
+	where := db.Cond{r.pk(): id}
+	if r.SoftDelete {
+		where[DeletedAtColumn] = db.IsNull()
+	}
+	return where
+}
+
+// Update the rows matching the condition with the values, returning ErrNotFound when
+// there is none.
+func (r *Repository[T]) update(ctx context.Context, sess db.Session, where db.Cond, values interface{}) error {
+	res, err := sess.SQL().Update(r.Table).Set(values).Where(where).ExecContext(ctx)
+	if err != nil {
+		return err
+	}
+	return r.written(sess, res, where)
+}
+
+// Return ErrNotFound when a write affected no row. The count comes from the write itself,
+// so a row deleted concurrently is not reported as written. MySQL counts the rows changed
+// rather than matched, so when it reports none the row is looked up.
+func (r *Repository[T]) written(sess db.Session, res sql.Result, where db.Cond) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return nil
+	}
+
+	if Driver(r.DB.DataType) == "mysql" {
+		exists, err := sess.Collection(r.Table).Find(where).Exists()
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+	}
+
+	return ErrNotFound
+}
+
+// Return a session for reads, joining the transaction of the context or reading from a
+// replica.
+func (r *Repository[T]) reader(ctx context.Context) db.Session {
+	if r.DB == nil {
+		return nil
+	}
+	if _, ok := TxFromContext(ctx); ok || r.DB.Replicas == nil {
+		return r.DB.Session(ctx)
+	}
+
+	sess := r.DB.sharedSession(r.DB.Reader(ctx))
+	if sess == nil {
+		return nil
+	}
+	return sess.WithContext(ctx)
+}
+
+// Return a session for writes on the primary, joining the transaction of the context.
+func (r *Repository[T]) writer(ctx context.Context) (db.Session, error) {
+	if r.DB == nil {
+		return nil, errors.New("database is not configured")
+	}
+
+	sess := r.DB.Session(ctx)
+	if sess == nil {
+		return nil, errors.New("database is not configured")
+	}
+
+	MarkWritten(ctx)
+	return sess, nil
+}
+
+func (r *Repository[T]) pk() string {
+	if r.PrimaryKey == "" {
+		return "id"
+	}
+	return r.PrimaryKey
+}
+
+// Return the current time in UTC to the microsecond, the precision Postgres and MySQL
+// store, so a model holds the time as read back.
+func (r *Repository[T]) clock() time.Time {
+	now := time.Now
+	if r.now != nil {
+		now = r.now
+	}
+	return now().UTC().Truncate(time.Microsecond)
+}
+
+// Return the value of the field of the model mapped to the column.
+func columnValue(item interface{}, column string) (interface{}, error) {
+	v, ok := fieldByColumn(reflect.ValueOf(item).Elem(), column)
+	if !ok {
+		return nil, fmt.Errorf("%T has no field for column %s", item, column)
+	}
+	return v.Interface(), nil
+}
+
+// Set the field of the model mapped to the column to the time, if the model has one, and
+// unless it is set already and overwrite is false. The field may be a time.Time, a
+// *time.Time or a sql.NullTime.
+func setTime(item interface{}, column string, t time.Time, overwrite bool) error {
+	v, ok := fieldByColumn(reflect.ValueOf(item).Elem(), column)
+	if !ok {
+		return nil
+	}
+
+	switch f := v.Addr().Interface().(type) {
+	case *time.Time:
+		if overwrite || f.IsZero() {
+			*f = t
+		}
+	case **time.Time:
+		if overwrite || *f == nil || (*f).IsZero() {
+			*f = &t
+		}
+	case *sql.NullTime:
+		if overwrite || !f.Valid {
+			*f = sql.NullTime{Time: t, Valid: true}
+		}
+	default:
+		return fmt.Errorf("%T field for column %s must be a time.Time, *time.Time or sql.NullTime", item, column)
+	}
+
+	return nil
+}
+
+// Find the field of the struct mapped to the column by its db tag, looking into the
+// embedded and inline structs as upper/db does.
+func fieldByColumn(v reflect.Value, column string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() && !sf.Anonymous {
+			continue
+		}
+
+		tag := sf.Tag.Get("db")
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "-" {
+			continue
+		}
+
+		if sf.Type.Kind() == reflect.Struct && (strings.Contains(opts, "inline") || (sf.Anonymous && name == "")) {
+			if f, ok := fieldByColumn(v.Field(i), column); ok {
+				return f, true
+			}
+			continue
+		}
+
+		if name == column && sf.IsExported() {
+			return v.Field(i), true
+		}
+	}
+
+	return reflect.Value{}, false
+}
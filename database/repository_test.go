@@ -0,0 +1,250 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+	"github.com/testcontainers/testcontainers-go/wait"
+	"github.com/upper/db/v4"
+)
+
+type testAuditable struct {
+	CreatedAt time.Time  `db:"created_at"`
+	UpdatedAt *time.Time `db:"updated_at"`
+}
+
+type testUser struct {
+	ID        int64        `db:"id,omitempty"`
+	Email     string       `db:"email"`
+	Role      string       `db:"role"`
+	DeletedAt sql.NullTime `db:"deleted_at"`
+
+	testAuditable `db:",inline"`
+}
+
+// Run the same checks of the repository on every database, whose users table is created
+// by the statement.
+func testRepository(t *testing.T, d *Database, createTable string) {
+	ctx := context.Background()
+
+	if _, err := d.Pool.Exec(createTable); err != nil {
+		t.Fatal(err)
+	}
+
+	users := NewRepository[testUser](d, "users")
+	users.SoftDelete = true
+
+	now := time.Date(2026, 10, 18, 12, 0, 0, 123456789, time.UTC)
+	users.now = func() time.Time { return now }
+
+	for _, email := range []string{"ada@example.com", "grace@example.com", "linus@example.com"} {
+		u := testUser{Email: email, Role: "user"}
+		if err := users.Insert(ctx, &u); err != nil {
+			t.Fatalf("Insert() error = %v", err)
+		}
+		if u.ID == 0 {
+			t.Errorf("Insert() did not set the ID of %s", email)
+		}
+	}
+
+	u, err := users.Find(ctx, 1)
+	if err != nil {
+		t.Fatalf("Find() error = %v", err)
+	}
+	if want := now.Truncate(time.Microsecond); !u.CreatedAt.Equal(want) || u.UpdatedAt == nil || !u.UpdatedAt.Equal(want) {
+		t.Errorf("timestamps = %v, %v; want %v", u.CreatedAt, u.UpdatedAt, want)
+	}
+
+	now = now.Add(time.Hour)
+	u.Role = "admin"
+	if err := users.Update(ctx, u); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+
+	admin, err := users.First(ctx, db.Cond{"role": "admin"})
+	if err != nil {
+		t.Fatalf("First() error = %v", err)
+	}
+	if admin.Email != "ada@example.com" || !admin.UpdatedAt.Equal(now.Truncate(time.Microsecond)) || admin.CreatedAt.Equal(*admin.UpdatedAt) {
+		t.Errorf("First() = %+v, want ada updated an hour after creation", admin)
+	}
+
+	// Writing the values the row holds still finds it, though MySQL changes no row.
+	if err := users.Update(ctx, admin); err != nil {
+		t.Errorf("Update() of an unchanged row error = %v", err)
+	}
+
+	if err := users.Update(ctx, &testUser{ID: 99}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Update() of a missing row error = %v, want ErrNotFound", err)
+	}
+
+	// Soft deleted rows are left out of every query.
+	if err := users.Delete(ctx, 2); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := users.Find(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Find() of a deleted row error = %v, want ErrNotFound", err)
+	}
+	if err := users.Delete(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Delete() of a deleted row error = %v, want ErrNotFound", err)
+	}
+
+	found, err := users.FindBy(ctx, db.Cond{"role": "user"})
+	if err != nil || len(found) != 1 || found[0].Email != "linus@example.com" {
+		t.Errorf("FindBy() = %+v, %v; want linus", found, err)
+	}
+
+	page, err := users.Paginate(ctx, nil, 2, 1)
+	if err != nil {
+		t.Fatalf("Paginate() error = %v", err)
+	}
+	if page.Total != 2 || page.Pages != 2 || len(page.Items) != 1 || page.Items[0].ID != 3 {
+		t.Errorf("Paginate() = %+v, want the second of 2 pages", page)
+	}
+
+	if err := users.Restore(ctx, 2); err != nil {
+		t.Fatalf("Restore() error = %v", err)
+	}
+	if err := users.Restore(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Restore() of a row not deleted error = %v, want ErrNotFound", err)
+	}
+	if _, err := users.Find(ctx, 2); err != nil {
+		t.Errorf("Find() of a restored row error = %v", err)
+	}
+
+	if err := users.ForceDelete(ctx, 2); err != nil {
+		t.Fatalf("ForceDelete() error = %v", err)
+	}
+	if count, _ := d.NewSession().Collection("users").Find().Count(); count != 2 {
+		t.Errorf("rows = %d after ForceDelete(), want 2", count)
+	}
+	if err := users.ForceDelete(ctx, 2); !errors.Is(err, ErrNotFound) {
+		t.Errorf("ForceDelete() of a missing row error = %v, want ErrNotFound", err)
+	}
+
+	// The queries share one session on the pool.
+	if d.sharedSession(d.Pool) != d.sharedSession(d.Pool) {
+		t.Error("sharedSession() created a session for every query")
+	}
+
+	// The queries join the transaction of the context.
+	err = d.WithTx(ctx, nil, func(tx db.Session) error {
+		if err := users.Insert(tx.Context(), &testUser{Email: "rob@example.com"}); err != nil {
+			return err
+		}
+		if _, err := users.First(tx.Context(), db.Cond{"email": "rob@example.com"}); err != nil {
+			return err
+		}
+		return errors.New("rolled back")
+	})
+	if err == nil || err.Error() != "rolled back" {
+		t.Errorf("WithTx() error = %v", err)
+	}
+	if _, err := users.First(ctx, db.Cond{"email": "rob@example.com"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("First() error = %v after the rollback, want ErrNotFound", err)
+	}
+}
+
+func TestRepository_Sqlite(t *testing.T) {
+	pool, err := OpenDB("sqlite", &DataSourceName{DatabaseName: ":memory:"})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer pool.Close()
+
+	testRepository(t, &Database{DataType: "sqlite", Pool: pool},
+		"CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL UNIQUE, role TEXT NOT NULL DEFAULT '', "+
+			"created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL, deleted_at TIMESTAMP NULL)")
+}
+
+func TestRepository_Postgres(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx,
+		"postgres:15",
+		postgres.WithDatabase("testdb"),
+		postgres.WithUsername("testuser"),
+		postgres.WithPassword("testpass"),
+		testcontainers.WithWaitStrategy(
+			wait.ForLog("database system is ready to accept connections").
+				WithOccurrence(2).
+				WithStartupTimeout(60*time.Second),
+		),
+	)
+	if err != nil {
+		t.Fatalf("Failed to start container: %v", err)
+	}
+	defer container.Terminate(ctx)
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := OpenDB("postgres", &DataSourceName{
+		Host:         host,
+		Port:         port.Port(),
+		User:         "testuser",
+		Password:     "testpass",
+		DatabaseName: "testdb",
+		SslMode:      "disable",
+	})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer pool.Close()
+
+	testRepository(t, &Database{DataType: "postgres", Pool: pool},
+		"CREATE TABLE users (id BIGSERIAL PRIMARY KEY, email VARCHAR(255) NOT NULL UNIQUE, role VARCHAR(255) NOT NULL DEFAULT '', "+
+			"created_at TIMESTAMPTZ NOT NULL, updated_at TIMESTAMPTZ NOT NULL, deleted_at TIMESTAMPTZ NULL)")
+}
+
+func TestRepository_MySQL(t *testing.T) {
+	ctx := context.Background()
+
+	container, err := mysql.Run(ctx,
+		"mysql:8.0",
+		mysql.WithDatabase("testdb"),
+		mysql.WithUsername("testuser"),
+		mysql.WithPassword("testpass"),
+	)
+	if err != nil {
+		t.Fatalf("Failed to start container: %v", err)
+	}
+	defer container.Terminate(ctx)
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := container.MappedPort(ctx, "3306/tcp")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pool, err := OpenDB("mysql", &DataSourceName{
+		Host:         host,
+		Port:         port.Port(),
+		User:         "testuser",
+		Password:     "testpass",
+		DatabaseName: "testdb",
+	})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer pool.Close()
+
+	testRepository(t, &Database{DataType: "mysql", Pool: pool},
+		"CREATE TABLE users (id BIGINT AUTO_INCREMENT PRIMARY KEY, email VARCHAR(255) NOT NULL UNIQUE, role VARCHAR(255) NOT NULL DEFAULT '', "+
+			"created_at DATETIME(6) NOT NULL, updated_at DATETIME(6) NOT NULL, deleted_at DATETIME(6) NULL) ENGINE=InnoDB")
+}
@@ -0,0 +1,125 @@
+package factory
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/upper/db/v4"
+)
+
+// Define a factory of the model stored in the table, building every value with the
+// function. Example:
+//
+//	var User = factory.Define("users", func(f *factory.Faker) models.User {
+//	    return models.User{
+//	        FirstName: f.FirstName(),
+//	        LastName:  f.LastName(),
+//	        Email:     f.Email(),
+//	        Active:    f.Bool(),
+//	    }
+//	})
+//
+//	admin, err := User.Create(tx, func(u *models.User) { u.Role = "admin" })
+func Define[T any](table string, build func(f *Faker) T) *Factory[T] {
+	return &Factory[T]{Table: table, build: build}
+}
+
+// Build a value without persisting it, applying the overrides in order.
+func (f *Factory[T]) Make(overrides ...func(*T)) T {
+	faker := f.Faker
+	if faker == nil {
+		faker = defaultFaker
+	}
+
+	v := f.build(faker)
+	for _, override := range overrides {
+		override(&v)
+	}
+	return v
+}
+
+// Build n values without persisting them.
+func (f *Factory[T]) MakeMany(n int, overrides ...func(*T)) []T {
+	values := make([]T, n)
+	for i := range values {
+		values[i] = f.Make(overrides...)
+	}
+	return values
+}
+
+// Build a value and insert it into the table of the factory, returning the value as
+// stored, e.g., with the ID the database assigned. The created_at and updated_at fields
+// left unset are set to the current time, the way a Repository inserts a model.
+func (f *Factory[T]) Create(sess db.Session, overrides ...func(*T)) (T, error) {
+	v := f.Make(overrides...)
+	if err := setTimestamps(reflect.ValueOf(&v).Elem(), time.Now().UTC().Truncate(time.Microsecond)); err != nil {
+		var zero T
+		return zero, fmt.Errorf("failed to create %s: %w", f.Table, err)
+	}
+	if err := sess.Collection(f.Table).InsertReturning(&v); err != nil {
+		var zero T
+		return zero, fmt.Errorf("failed to create %s: %w", f.Table, err)
+	}
+	return v, nil
+}
+
+// Build n values and insert them, stopping at the first that fails.
+func (f *Factory[T]) CreateMany(sess db.Session, n int, overrides ...func(*T)) ([]T, error) {
+	values := make([]T, 0, n)
+	for i := 0; i < n; i++ {
+		v, err := f.Create(sess, overrides...)
+		if err != nil {
+			return values, err
+		}
+		values = append(values, v)
+	}
+	return values, nil
+}
+
+// Set the created_at and updated_at fields of the struct left unset to the time. The
+// fields of inline and embedded structs are set too. A field may be a time.Time, a
+// *time.Time or a sql.NullTime.
+func setTimestamps(v reflect.Value, t time.Time) error {
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		name, opts, _ := strings.Cut(sf.Tag.Get("db"), ",")
+
+		if sf.Type.Kind() == reflect.Struct && (strings.Contains(opts, "inline") || (sf.Anonymous && name == "")) {
+			if err := setTimestamps(v.Field(i), t); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if !sf.IsExported() || (name != database.CreatedAtColumn && name != database.UpdatedAtColumn) {
+			continue
+		}
+
+		switch f := v.Field(i).Addr().Interface().(type) {
+		case *time.Time:
+			if f.IsZero() {
+				*f = t
+			}
+		case **time.Time:
+			if *f == nil || (*f).IsZero() {
+				*f = &t
+			}
+		case *sql.NullTime:
+			if !f.Valid {
+				*f = sql.NullTime{Time: t, Valid: true}
+			}
+		default:
+			return fmt.Errorf("%s field for column %s must be a time.Time, *time.Time or sql.NullTime", sf.Name, name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,99 @@
+package factory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+type user struct {
+	ID        int64     `db:"id,omitempty"`
+	Name      string    `db:"name"`
+	Email     string    `db:"email"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+var users = Define("users", func(f *Faker) user {
+	return user{
+		Name:      f.Name(),
+		Email:     f.Email(),
+		Active:    f.Bool(),
+		CreatedAt: f.Time(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)),
+	}
+})
+
+func TestMake(t *testing.T) {
+	users.Faker = NewFaker(42)
+	first := users.MakeMany(3)
+
+	// Seeding again builds the same values but new emails, which may be stored already.
+	users.Faker.Seed(42)
+	again := users.MakeMany(3)
+	for i := range again {
+		if again[i].Email == first[i].Email {
+			t.Errorf("email %d = %s after seeding again, want a new address", i, again[i].Email)
+		}
+		again[i].Email = first[i].Email
+	}
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("MakeMany() = %+v after seeding again, want %+v", again, first)
+	}
+
+	if first[0].Email == first[1].Email || !strings.HasSuffix(first[0].Email, "@example.com") {
+		t.Errorf("emails = %s, %s; want unique addresses", first[0].Email, first[1].Email)
+	}
+
+	inactive := users.Make(func(u *user) { u.Active = false })
+	if inactive.Active {
+		t.Error("Make() did not apply the override")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	pool, err := database.OpenDB("sqlite", &database.DataSourceName{DatabaseName: ":memory:"})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer pool.Close()
+
+	if _, err := pool.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, email TEXT UNIQUE, active BOOLEAN, created_at TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+
+	sess := (&database.Database{DataType: "sqlite", Pool: pool}).NewSession()
+
+	created, err := users.CreateMany(sess, 5, func(u *user) { u.Active = true })
+	if err != nil {
+		t.Fatalf("CreateMany() error = %v", err)
+	}
+	if len(created) != 5 || created[4].ID != 5 {
+		t.Errorf("CreateMany() = %+v, want 5 users with their IDs", created)
+	}
+
+	var stored user
+	if err := sess.Collection("users").Find(created[2].ID).One(&stored); err != nil {
+		t.Fatal(err)
+	}
+	if stored.Email != created[2].Email || !stored.Active || !stored.CreatedAt.Equal(created[2].CreatedAt) {
+		t.Errorf("stored = %+v, want %+v", stored, created[2])
+	}
+
+	// An unset created_at is set the way a Repository sets it.
+	before := time.Now().Add(-time.Second)
+	unset, err := users.Create(sess, func(u *user) { u.CreatedAt = time.Time{} })
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if unset.CreatedAt.Before(before) {
+		t.Errorf("CreatedAt = %v, want the current time", unset.CreatedAt)
+	}
+
+	// The email is taken.
+	if _, err := users.Create(sess, func(u *user) { u.Email = stored.Email }); err == nil {
+		t.Error("Create() error = nil, want the unique constraint violation")
+	}
+}
@@ -0,0 +1,152 @@
+package factory
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+var firstNames = []string{
+	"Ada", "Alan", "Barbara", "Charles", "Donald", "Edsger", "Frances", "Grace", "Hedy", "Ivan",
+	"John", "Karen", "Linus", "Margaret", "Niklaus", "Radia", "Rob", "Shafi", "Tim", "Whitfield",
+}
+
+var lastNames = []string{
+	"Allen", "Berners-Lee", "Cerf", "Dijkstra", "Engelbart", "Goldwasser", "Hamilton", "Hopper",
+	"Kernighan", "Knuth", "Lamarr", "Liskov", "Lovelace", "Perlman", "Pike", "Ritchie",
+	"Spärck Jones", "Sutherland", "Thompson", "Turing",
+}
+
+var words = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do",
+	"eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim",
+	"ad", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi",
+	"aliquip", "ex", "ea", "commodo", "consequat",
+}
+
+// The faker factories use unless they set one of their own.
+var defaultFaker = NewFaker(time.Now().UnixNano())
+
+// Create a faker generating the same values for the same seed.
+func NewFaker(seed int64) *Faker {
+	return &Faker{rand: rand.New(rand.NewSource(seed)), run: newRun()}
+}
+
+// Seed the faker factories use by default, so they build the same values on every run
+// but for the emails, e.g., for a demo database or a test reproducing a failure.
+func Seed(seed int64) {
+	defaultFaker.Seed(seed)
+}
+
+// Seed the faker, restarting its values and its sequence. The emails of the faker are
+// unique to each seeding, so seeding again does not build the emails already stored.
+func (f *Faker) Seed(seed int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rand = rand.New(rand.NewSource(seed))
+	f.seq = 0
+	f.run = newRun()
+}
+
+// Return the next number of a sequence starting at 1, for fields that must be unique.
+func (f *Faker) Sequence() int64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seq++
+	return f.seq
+}
+
+// Return a random int between min and max, both included.
+func (f *Faker) Int(min, max int) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return min + f.rand.Intn(max-min+1)
+}
+
+// Return a random float between min and max.
+func (f *Faker) Float(min, max float64) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return min + f.rand.Float64()*(max-min)
+}
+
+func (f *Faker) Bool() bool {
+	return f.Int(0, 1) == 1
+}
+
+// Return one of the values at random.
+func (f *Faker) Pick(values ...string) string {
+	return values[f.Int(0, len(values)-1)]
+}
+
+// Return a random time between from and to.
+func (f *Faker) Time(from, to time.Time) time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return from.Add(time.Duration(f.rand.Int63n(int64(to.Sub(from)) + 1)))
+}
+
+func (f *Faker) FirstName() string {
+	return f.Pick(firstNames...)
+}
+
+func (f *Faker) LastName() string {
+	return f.Pick(lastNames...)
+}
+
+func (f *Faker) Name() string {
+	return f.FirstName() + " " + f.LastName()
+}
+
+// Return an email address at example.com, unique by its sequence number and a token of
+// the run, so the emails of earlier runs stored in the database are not built again.
+func (f *Faker) Email() string {
+	name := strings.ToLower(f.FirstName() + "." + f.LastName())
+	name = strings.NewReplacer(" ", "", "ä", "a").Replace(name)
+	seq := f.Sequence()
+
+	f.mu.Lock()
+	run := f.run
+	f.mu.Unlock()
+
+	return fmt.Sprintf("%s%d.%s@example.com", name, seq, run)
+}
+
+// Return a random token for the run, drawn outside the seeded source so it differs on
+// every run.
+func newRun() string {
+	b := make([]byte, 4)
+	crand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func (f *Faker) Word() string {
+	return f.Pick(words...)
+}
+
+// Return n random words separated by spaces.
+func (f *Faker) Words(n int) string {
+	w := make([]string, n)
+	for i := range w {
+		w[i] = f.Word()
+	}
+	return strings.Join(w, " ")
+}
+
+// Return a sentence of 4 to 12 words.
+func (f *Faker) Sentence() string {
+	s := f.Words(f.Int(4, 12))
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
+
+// Return a paragraph of 3 to 6 sentences.
+func (f *Faker) Paragraph() string {
+	s := make([]string, f.Int(3, 6))
+	for i := range s {
+		s[i] = f.Sentence()
+	}
+	return strings.Join(s, " ")
+}
@@ -0,0 +1,28 @@
+package factory
+
+import (
+	"math/rand"
+	"sync"
+)
+
+// Factory builds values of a model with randomized fields and persists them to the table
+// of the model.
+type Factory[T any] struct {
+	Table string
+
+	// The faker the values are built with, the package default unless set.
+	Faker *Faker
+
+	build func(f *Faker) T
+}
+
+// Faker generates random values for the fields of a model, e.g., names, emails and
+// sentences. It is safe for concurrent use.
+type Faker struct {
+	mu   sync.Mutex
+	rand *rand.Rand
+	seq  int64
+
+	// A token of the run making the emails unique across runs.
+	run string
+}
@@ -0,0 +1,175 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/database"
+)
+
+// ErrUnknownSeeder is returned when running or depending on a seeder that is not
+// registered.
+var ErrUnknownSeeder = errors.New("unknown seeder")
+
+// ErrDependencyCycle is returned when seeders depend on each other.
+var ErrDependencyCycle = errors.New("seeder dependency cycle")
+
+// The registry the package functions use, which the seeders of an application register
+// with from the init functions of their files.
+var Default = New()
+
+// Create an empty registry, e.g., for the seeders of a single test.
+func New() *Registry {
+	return &Registry{seeders: map[string]Seeder{}}
+}
+
+// Register a seeder with the default registry, panicking when the name is empty or taken.
+// Example:
+//
+//	func init() {
+//	    seed.Register(seed.Seeder{
+//	        Name:    "posts",
+//	        Depends: []string{"users"},
+//	        Run: func(tx db.Session) error {
+//	            _, err := factories.Post.CreateMany(tx, 20)
+//	            return err
+//	        },
+//	    })
+//	}
+func Register(s Seeder) {
+	Default.Register(s)
+}
+
+// Run the named seeders of the default registry, or every seeder when no name is given,
+// after the seeders they depend on.
+func Run(ctx context.Context, db *database.Database, names ...string) error {
+	return Default.Run(ctx, db, names...)
+}
+
+// Register a seeder, panicking when the name is empty or taken.
+func (r *Registry) Register(s Seeder) {
+	if s.Name == "" || s.Run == nil {
+		panic("seed: Register seeder without a name or Run function")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.seeders[s.Name]; ok {
+		panic("seed: Register called twice for seeder " + s.Name)
+	}
+	r.seeders[s.Name] = s
+	r.names = append(r.names, s.Name)
+}
+
+// Return the named seeders, or every seeder when no name is given, and the seeders they
+// depend on, each after its dependencies and otherwise in the order registered.
+func (r *Registry) Order(names ...string) ([]Seeder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(names) == 0 {
+		names = r.names
+	}
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := map[string]int{}
+	var order []Seeder
+
+	var visit func(name string, path []string) error
+	visit = func(name string, path []string) error {
+		switch state[name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(append(path, name), " -> "))
+		}
+
+		s, ok := r.seeders[name]
+		if !ok {
+			if len(path) > 0 {
+				return fmt.Errorf("%w: %s, required by %s", ErrUnknownSeeder, name, path[len(path)-1])
+			}
+			return fmt.Errorf("%w: %s", ErrUnknownSeeder, name)
+		}
+
+		state[name] = visiting
+		for _, dep := range s.Depends {
+			if err := visit(dep, append(path, name)); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+
+		order = append(order, s)
+		return nil
+	}
+
+	for _, name := range names {
+		if err := visit(name, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return order, nil
+}
+
+// Run the named seeders, or every seeder when no name is given, after the seeders they
+// depend on. Every seeder runs in a transaction of its own, so one that fails leaves
+// nothing behind and stops the run. With a context holding a transaction of WithTx, the
+// seeders run in savepoints of it instead, e.g., in a test rolling back its data.
+func (r *Registry) Run(ctx context.Context, db *database.Database, names ...string) error {
+	return r.run(ctx, db, names, nil)
+}
+
+func (r *Registry) run(ctx context.Context, db *database.Database, names []string, done func(s Seeder, took time.Duration)) error {
+	if db == nil || db.Pool == nil {
+		return errors.New("seeders have no database connection")
+	}
+
+	seeders, err := r.Order(names...)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range seeders {
+		start := time.Now()
+		if err := db.WithTx(ctx, nil, s.Run); err != nil {
+			return fmt.Errorf("seeder %s failed: %w", s.Name, err)
+		}
+		if done != nil {
+			done(s, time.Since(start))
+		}
+	}
+
+	return nil
+}
+
+// Run the seeders of the default registry named by the arguments, or every seeder when
+// there are none, printing each seeder run and exiting with status 1 when one fails. It
+// is the body of the seed command of an application, cmd/seed/main.go, run by adele
+// seed. Example:
+//
+//	func main() {
+//	    app := &adele.Adele{}
+//	    if err := app.New(root); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	    seed.Main(app.DB, os.Args[1:])
+//	}
+func Main(db *database.Database, args []string) {
+	err := Default.run(context.Background(), db, args, func(s Seeder, took time.Duration) {
+		fmt.Printf("Seeded %s (%s)\n", s.Name, took.Round(time.Millisecond))
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
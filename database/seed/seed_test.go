@@ -0,0 +1,118 @@
+package seed
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/cidekar/adele-framework/database"
+	"github.com/upper/db/v4"
+)
+
+func sqliteDatabase(t *testing.T) *database.Database {
+	pool, err := database.OpenDB("sqlite", &database.DataSourceName{DatabaseName: ":memory:"})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	if _, err := pool.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); CREATE TABLE posts (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id))"); err != nil {
+		t.Fatal(err)
+	}
+
+	return &database.Database{DataType: "sqlite", Pool: pool}
+}
+
+func names(seeders []Seeder) []string {
+	n := make([]string, len(seeders))
+	for i, s := range seeders {
+		n[i] = s.Name
+	}
+	return n
+}
+
+func TestOrder(t *testing.T) {
+	noop := func(tx db.Session) error { return nil }
+
+	r := New()
+	r.Register(Seeder{Name: "comments", Depends: []string{"posts", "users"}, Run: noop})
+	r.Register(Seeder{Name: "posts", Depends: []string{"users"}, Run: noop})
+	r.Register(Seeder{Name: "users", Run: noop})
+	r.Register(Seeder{Name: "settings", Run: noop})
+
+	order, err := r.Order()
+	if err != nil {
+		t.Fatalf("Order() error = %v", err)
+	}
+	if want := []string{"users", "posts", "comments", "settings"}; !reflect.DeepEqual(names(order), want) {
+		t.Errorf("Order() = %v, want %v", names(order), want)
+	}
+
+	order, err = r.Order("posts")
+	if err != nil {
+		t.Fatalf("Order() error = %v", err)
+	}
+	if want := []string{"users", "posts"}; !reflect.DeepEqual(names(order), want) {
+		t.Errorf("Order(posts) = %v, want %v", names(order), want)
+	}
+
+	if _, err := r.Order("tags"); !errors.Is(err, ErrUnknownSeeder) {
+		t.Errorf("Order(tags) error = %v, want ErrUnknownSeeder", err)
+	}
+
+	r.Register(Seeder{Name: "a", Depends: []string{"b"}, Run: noop})
+	r.Register(Seeder{Name: "b", Depends: []string{"a"}, Run: noop})
+	if _, err := r.Order("a"); !errors.Is(err, ErrDependencyCycle) || err.Error() != "seeder dependency cycle: a -> b -> a" {
+		t.Errorf("Order(a) error = %v, want ErrDependencyCycle", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Register() of a taken name did not panic")
+		}
+	}()
+	r.Register(Seeder{Name: "users", Run: noop})
+}
+
+func TestRun(t *testing.T) {
+	d := sqliteDatabase(t)
+	ctx := context.Background()
+
+	r := New()
+	r.Register(Seeder{Name: "posts", Depends: []string{"users"}, Run: func(tx db.Session) error {
+		_, err := tx.Collection("posts").Insert(map[string]int{"user_id": 1})
+		return err
+	}})
+	r.Register(Seeder{Name: "users", Run: func(tx db.Session) error {
+		_, err := tx.Collection("users").Insert(map[string]string{"name": "Ada"})
+		return err
+	}})
+	r.Register(Seeder{Name: "broken", Run: func(tx db.Session) error {
+		if _, err := tx.Collection("users").Insert(map[string]string{"name": "Grace"}); err != nil {
+			return err
+		}
+		return errors.New("out of fixtures")
+	}})
+
+	if err := r.Run(ctx, d, "posts"); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+
+	// A failed seeder leaves nothing behind.
+	if err := r.Run(ctx, d, "broken"); err == nil || err.Error() != "seeder broken failed: out of fixtures" {
+		t.Errorf("Run(broken) error = %v", err)
+	}
+
+	sess := d.NewSession()
+	if count, _ := sess.Collection("users").Find().Count(); count != 1 {
+		t.Errorf("users = %d, want 1", count)
+	}
+	if count, _ := sess.Collection("posts").Find().Count(); count != 1 {
+		t.Errorf("posts = %d, want 1", count)
+	}
+
+	if err := r.Run(ctx, &database.Database{}); err == nil {
+		t.Error("Run() without a database error = nil")
+	}
+}
@@ -0,0 +1,23 @@
+package seed
+
+import (
+	"sync"
+
+	"github.com/upper/db/v4"
+)
+
+// Seeder fills the database with fixture or demo data. Run is called in a transaction
+// with a session joining it, and Depends names the seeders that must run first, e.g.,
+// the users a seeder of posts writes them for.
+type Seeder struct {
+	Name    string
+	Depends []string
+	Run     func(tx db.Session) error
+}
+
+// Registry holds the seeders of an application by name.
+type Registry struct {
+	mu      sync.RWMutex
+	seeders map[string]Seeder
+	names   []string
+}
@@ -0,0 +1,64 @@
+// Package sqlitedriver opens SQLite databases with the pure Go modernc.org/sqlite driver
+// and builds their upper sessions. The upper SQLite adapter also imports the cgo based
+// github.com/mattn/go-sqlite3, which is linked into every binary using this package;
+// without cgo it compiles to a stub that is never opened here, but with cgo enabled the
+// build needs a C compiler.
+package sqlitedriver
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/upper/db/v4"
+	"github.com/upper/db/v4/adapter/sqlite"
+	_ "modernc.org/sqlite"
+)
+
+// The path opening a database that lives in memory for as long as its connection.
+const Memory = ":memory:"
+
+// The name of the pure Go database/sql driver.
+const DriverName = "sqlite"
+
+// Build a data source name string for the database file at the path, or for a database in
+// memory. The busy timeout is how long a connection waits for another to release its lock,
+// and the journal mode is WAL unless set, letting readers work alongside a writer. Foreign
+// keys are enforced and transactions take the write lock when they begin.
+func BuildDSN(path string, busyTimeout time.Duration, journalMode string) string {
+	if journalMode == "" {
+		journalMode = "WAL"
+	}
+
+	q := url.Values{}
+	q.Add("_pragma", fmt.Sprintf("busy_timeout(%d)", busyTimeout.Milliseconds()))
+	if path != Memory {
+		q.Add("_pragma", fmt.Sprintf("journal_mode(%s)", journalMode))
+	}
+	q.Add("_pragma", "foreign_keys(1)")
+	q.Set("_time_format", "sqlite")
+	q.Set("_txlock", "immediate")
+
+	return "file:" + path + "?" + q.Encode()
+}
+
+// Create a new SQLite builder session
+func Session(pool *sql.DB) (db.Session, error) {
+	session, err := sqlite.New(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// Create a new SQLite builder session running its queries in the transaction
+func TxSession(tx *sql.Tx) (db.Session, error) {
+	session, err := sqlite.NewTx(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
@@ -0,0 +1,111 @@
+package sqlitedriver
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBuildDSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		path        string
+		busyTimeout time.Duration
+		journalMode string
+		expected    string
+	}{
+		{
+			name:        "file with default journal mode",
+			path:        "storage/app.db",
+			busyTimeout: 5 * time.Second,
+			expected:    "file:storage/app.db?_pragma=busy_timeout%285000%29&_pragma=journal_mode%28WAL%29&_pragma=foreign_keys%281%29&_time_format=sqlite&_txlock=immediate",
+		},
+		{
+			name:        "file with journal mode",
+			path:        "app.db",
+			busyTimeout: time.Second,
+			journalMode: "DELETE",
+			expected:    "file:app.db?_pragma=busy_timeout%281000%29&_pragma=journal_mode%28DELETE%29&_pragma=foreign_keys%281%29&_time_format=sqlite&_txlock=immediate",
+		},
+		{
+			name:        "memory",
+			path:        Memory,
+			busyTimeout: 5 * time.Second,
+			expected:    "file::memory:?_pragma=busy_timeout%285000%29&_pragma=foreign_keys%281%29&_time_format=sqlite&_txlock=immediate",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BuildDSN(tt.path, tt.busyTimeout, tt.journalMode); got != tt.expected {
+				t.Errorf("BuildDSN() = %s, want %s", got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSession(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.db")
+
+	pool, err := sql.Open(DriverName, BuildDSN(path, 5*time.Second, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	var mode string
+	if err := pool.QueryRow("PRAGMA journal_mode").Scan(&mode); err != nil || mode != "wal" {
+		t.Errorf("journal_mode = %s, %v; want wal", mode, err)
+	}
+
+	var timeout int
+	if err := pool.QueryRow("PRAGMA busy_timeout").Scan(&timeout); err != nil || timeout != 5000 {
+		t.Errorf("busy_timeout = %d, %v; want 5000", timeout, err)
+	}
+
+	if _, err := pool.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT, created_at TIMESTAMP)"); err != nil {
+		t.Fatal(err)
+	}
+
+	session, err := Session(pool)
+	if err != nil {
+		t.Fatalf("Session() error = %v", err)
+	}
+
+	type user struct {
+		ID        int       `db:"id,omitempty"`
+		Name      string    `db:"name"`
+		CreatedAt time.Time `db:"created_at"`
+	}
+
+	created := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	if _, err := session.Collection("users").Insert(user{Name: "Ada", CreatedAt: created}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+
+	var u user
+	if err := session.Collection("users").Find("name", "Ada").One(&u); err != nil {
+		t.Fatalf("One() error = %v", err)
+	}
+	if u.ID != 1 || !u.CreatedAt.Equal(created) {
+		t.Errorf("user = %+v", u)
+	}
+
+	tx, err := pool.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	txSession, err := TxSession(tx)
+	if err != nil {
+		t.Fatalf("TxSession() error = %v", err)
+	}
+	if _, err := txSession.Collection("users").Insert(user{Name: "Grace"}); err != nil {
+		t.Fatalf("Insert() error = %v", err)
+	}
+	tx.Rollback()
+
+	if count, _ := session.Collection("users").Find().Count(); count != 1 {
+		t.Errorf("Count() = %d after rollback, want 1", count)
+	}
+}
@@ -0,0 +1,224 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cidekar/adele-framework/database/mysqldriver"
+	"github.com/cidekar/adele-framework/database/postgresdriver"
+	"github.com/cidekar/adele-framework/database/sqlitedriver"
+	"github.com/go-sql-driver/mysql"
+	"github.com/upper/db/v4"
+)
+
+// The retries of a transaction that failed to serialize or deadlocked, used unless the
+// database sets TxRetry.
+var DefaultTxRetry = Retry{
+	Attempts:   3,
+	Backoff:    20 * time.Millisecond,
+	MaxBackoff: time.Second,
+}
+
+// MySQL error numbers of the transactions worth retrying.
+const (
+	mysqlLockDeadlock    = 1213
+	mysqlLockWaitTimeout = 1205
+)
+
+// Run the function in a transaction, committing it when the function returns nil and
+// rolling it back when the function returns an error or panics. A transaction that failed
+// to serialize or deadlocked is retried from the start with the backoff of TxRetry, so
+// the function must be safe to run again.
+//
+// The session given to the function carries the transaction in its context; Session
+// returns it for that context, so repository code given the context joins the
+// transaction. WithTx called with such a context runs the function in a savepoint of the
+// transaction instead, ignoring the options, and rolls back to the savepoint when the
+// function fails, leaving the rest of the transaction to the outer call. Example:
+//
+//	err := app.DB.WithTx(ctx, nil, func(tx db.Session) error {
+//	    if _, err := tx.Collection("orders").Insert(order); err != nil {
+//	        return err
+//	    }
+//	    return inventory.Reserve(tx.Context(), order.Items)
+//	})
+func (a *Database) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx db.Session) error) error {
+	if t, ok := ctx.Value(txKey{}).(*activeTx); ok {
+		return t.savepoint(ctx, fn)
+	}
+
+	if a.Pool == nil {
+		return errors.New("database is not configured")
+	}
+
+	retry := DefaultTxRetry
+	if a.TxRetry != nil {
+		retry = *a.TxRetry
+	}
+
+	wait := retry.Backoff
+	if wait <= 0 {
+		wait = DefaultTxRetry.Backoff
+	}
+	maxWait := retry.MaxBackoff
+	if maxWait <= 0 {
+		maxWait = DefaultTxRetry.MaxBackoff
+	}
+
+	for attempt := 1; ; attempt++ {
+		err := a.runTx(ctx, opts, fn)
+		if err == nil || attempt > retry.Attempts || !Retryable(err) {
+			return err
+		}
+
+		if retry.OnRetry != nil {
+			retry.OnRetry(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+
+		wait = min(wait*2, maxWait)
+	}
+}
+
+// Return a session joining the transaction of the context, if any, or a session on the
+// primary otherwise, in both cases bound to the context. Example:
+//
+//	func (r *Orders) Save(ctx context.Context, order *Order) error {
+//	    _, err := r.DB.Session(ctx).Collection("orders").Insert(order)
+//	    return err
+//	}
+func (a *Database) Session(ctx context.Context) db.Session {
+	if t, ok := ctx.Value(txKey{}).(*activeTx); ok {
+		return t.session
+	}
+
+	session := a.sharedSession(a.Pool)
+	if session == nil {
+		return nil
+	}
+	return session.WithContext(ctx)
+}
+
+// Return the transaction of the context started by WithTx, for queries written without
+// a session.
+func TxFromContext(ctx context.Context) (*sql.Tx, bool) {
+	t, ok := ctx.Value(txKey{}).(*activeTx)
+	if !ok {
+		return nil, false
+	}
+	return t.tx, true
+}
+
+// Report whether an error is a failure to serialize a transaction or a deadlock, after
+// which the transaction succeeds when run again, i.e., Postgres SQLSTATE 40001 or 40P01
+// and MySQL errors 1213 or 1205.
+func Retryable(err error) bool {
+	var state interface{ SQLState() string }
+	if errors.As(err, &state) {
+		switch state.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+	}
+
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return myErr.Number == mysqlLockDeadlock || myErr.Number == mysqlLockWaitTimeout
+	}
+
+	return false
+}
+
+// The transaction started by WithTx, stored in the context of its session.
+type activeTx struct {
+	tx         *sql.Tx
+	session    db.Session
+	savepoints int
+}
+
+// The context key of the transaction.
+type txKey struct{}
+
+// Run the function once in a new transaction.
+func (a *Database) runTx(ctx context.Context, opts *sql.TxOptions, fn func(tx db.Session) error) error {
+	tx, err := a.Pool.BeginTx(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	session, err := a.txSession(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	t := &activeTx{tx: tx}
+	t.session = session.WithContext(context.WithValue(ctx, txKey{}, t))
+
+	if opts == nil || !opts.ReadOnly {
+		MarkWritten(ctx)
+	}
+
+	defer func() {
+		if rec := recover(); rec != nil {
+			tx.Rollback()
+			panic(rec)
+		}
+	}()
+
+	if err := fn(t.session); err != nil {
+		if rollbackErr := tx.Rollback(); rollbackErr != nil && !errors.Is(rollbackErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("failed to roll back transaction: %w", rollbackErr))
+		}
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Create a session on the transaction for the database type.
+func (a *Database) txSession(tx *sql.Tx) (db.Session, error) {
+	switch Driver(a.DataType) {
+	case "mysql":
+		return mysqldriver.TxSession(tx)
+	case "pgx":
+		return postgresdriver.TxSession(tx)
+	case "sqlite":
+		return sqlitedriver.TxSession(tx)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedDriver, a.DataType)
+	}
+}
+
+// Run the function in a savepoint of the transaction, rolling back to the savepoint when
+// the function fails.
+func (t *activeTx) savepoint(ctx context.Context, fn func(tx db.Session) error) error {
+	t.savepoints++
+	name := fmt.Sprintf("adele_savepoint_%d", t.savepoints)
+
+	if _, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	if err := fn(t.session); err != nil {
+		if _, rollbackErr := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rollbackErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to roll back to savepoint: %w", rollbackErr))
+		}
+		return err
+	}
+
+	if _, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/upper/db/v4"
+)
+
+// A driver recording the statements it runs, for testing transactions without a server.
+// Statements starting with FAIL return the error of the recorder.
+type recorder struct {
+	mu         sync.Mutex
+	statements []string
+	err        error
+}
+
+type recorderConn struct{ r *recorder }
+
+type recorderTx struct{ r *recorder }
+
+// A Postgres error with its SQLSTATE.
+type stateError string
+
+func (e stateError) Error() string    { return "sqlstate " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func (r *recorder) Open(name string) (driver.Conn, error) { return recorderConn{r}, nil }
+
+func (r *recorder) record(stmt string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.statements = append(r.statements, stmt)
+}
+
+func (r *recorder) reset() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	statements := r.statements
+	r.statements = nil
+	return statements
+}
+
+func (c recorderConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c recorderConn) Close() error {
+	return nil
+}
+
+func (c recorderConn) Begin() (driver.Tx, error) {
+	c.r.record("BEGIN")
+	return recorderTx(c), nil
+}
+
+func (c recorderConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.r.record(query)
+	if strings.HasPrefix(query, "FAIL") {
+		return nil, c.r.err
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (t recorderTx) Commit() error {
+	t.r.record("COMMIT")
+	return nil
+}
+
+func (t recorderTx) Rollback() error {
+	t.r.record("ROLLBACK")
+	return nil
+}
+
+var txRecorder = &recorder{}
+
+func init() {
+	sql.Register("recorder", txRecorder)
+}
+
+func recorderDatabase(t *testing.T) *Database {
+	pool, err := sql.Open("recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+	txRecorder.reset()
+
+	return &Database{
+		DataType: "postgres",
+		Pool:     pool,
+		TxRetry:  &Retry{Attempts: 2, Backoff: time.Millisecond},
+	}
+}
+
+func exec(ctx context.Context, query string) error {
+	tx, _ := TxFromContext(ctx)
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
+
+func TestWithTx_Savepoints(t *testing.T) {
+	d := recorderDatabase(t)
+	txRecorder.err = errors.New("constraint violated")
+	ctx := context.Background()
+
+	err := d.WithTx(ctx, nil, func(tx db.Session) error {
+		if d.Session(tx.Context()) != tx {
+			t.Error("Session() did not join the transaction of the context")
+		}
+
+		exec(tx.Context(), "INSERT order")
+
+		// The failed inner call rolls back to its savepoint only.
+		err := d.WithTx(tx.Context(), nil, func(tx db.Session) error {
+			return exec(tx.Context(), "FAIL reserve")
+		})
+		if err == nil {
+			t.Error("inner WithTx() error = nil, want the error of the function")
+		}
+
+		return d.WithTx(tx.Context(), nil, func(tx db.Session) error {
+			return exec(tx.Context(), "INSERT audit")
+		})
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	want := []string{
+		"BEGIN",
+		"INSERT order",
+		"SAVEPOINT adele_savepoint_1", "FAIL reserve", "ROLLBACK TO SAVEPOINT adele_savepoint_1",
+		"SAVEPOINT adele_savepoint_2", "INSERT audit", "RELEASE SAVEPOINT adele_savepoint_2",
+		"COMMIT",
+	}
+	if got := txRecorder.reset(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func TestWithTx_Rollback(t *testing.T) {
+	d := recorderDatabase(t)
+	ctx := context.Background()
+
+	err := d.WithTx(ctx, nil, func(tx db.Session) error {
+		return errors.New("invalid order")
+	})
+	if err == nil || err.Error() != "invalid order" {
+		t.Errorf("WithTx() error = %v, want the error of the function", err)
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("WithTx() did not pass on the panic of the function")
+			}
+		}()
+		d.WithTx(ctx, nil, func(tx db.Session) error {
+			panic("broken")
+		})
+	}()
+
+	want := []string{"BEGIN", "ROLLBACK", "BEGIN", "ROLLBACK"}
+	if got := txRecorder.reset(); !reflect.DeepEqual(got, want) {
+		t.Errorf("statements = %q, want %q", got, want)
+	}
+}
+
+func TestWithTx_Retry(t *testing.T) {
+	d := recorderDatabase(t)
+	txRecorder.err = stateError("40001")
+	ctx := context.Background()
+
+	calls := 0
+	err := d.WithTx(ctx, nil, func(tx db.Session) error {
+		calls++
+		if calls < 3 {
+			return exec(tx.Context(), "FAIL update")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("function ran %d times, want 3", calls)
+	}
+
+	// The attempts run out.
+	calls = 0
+	err = d.WithTx(ctx, nil, func(tx db.Session) error {
+		calls++
+		return exec(tx.Context(), "FAIL update")
+	})
+	if !Retryable(err) || calls != 3 {
+		t.Errorf("WithTx() error = %v after %d calls, want the serialization failure after 3", err, calls)
+	}
+
+	// Other errors are not retried.
+	calls = 0
+	d.WithTx(ctx, nil, func(tx db.Session) error {
+		calls++
+		return errors.New("invalid order")
+	})
+	if calls != 1 {
+		t.Errorf("function ran %d times, want 1", calls)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{stateError("40001"), true},
+		{stateError("40P01"), true},
+		{stateError("23505"), false},
+		{&mysql.MySQLError{Number: 1213}, true},
+		{&mysql.MySQLError{Number: 1062}, false},
+		{errors.New("serialization failure"), false},
+	}
+
+	for _, tt := range tests {
+		if got := Retryable(tt.err); got != tt.want {
+			t.Errorf("Retryable(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWithTx_Sqlite(t *testing.T) {
+	pool, err := OpenDB("sqlite", &DataSourceName{DatabaseName: ":memory:"})
+	if err != nil {
+		t.Fatalf("OpenDB() error = %v", err)
+	}
+	defer pool.Close()
+
+	d := &Database{DataType: "sqlite", Pool: pool}
+	if _, err := pool.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY, item TEXT UNIQUE)"); err != nil {
+		t.Fatal(err)
+	}
+
+	err = d.WithTx(context.Background(), nil, func(tx db.Session) error {
+		if _, err := tx.Collection("orders").Insert(map[string]string{"item": "book"}); err != nil {
+			return err
+		}
+
+		// The duplicate rolls back to its savepoint, keeping the first order.
+		err := d.WithTx(tx.Context(), nil, func(tx db.Session) error {
+			_, err := d.Session(tx.Context()).Collection("orders").Insert(map[string]string{"item": "book"})
+			return err
+		})
+		if err == nil {
+			t.Error("inner WithTx() error = nil, want the unique constraint violation")
+		}
+
+		_, err = tx.Collection("orders").Insert(map[string]string{"item": "pen"})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WithTx() error = %v", err)
+	}
+
+	if count, _ := d.NewSession().Collection("orders").Find().Count(); count != 2 {
+		t.Errorf("Count() = %d, want 2", count)
+	}
+}
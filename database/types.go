@@ -1,10 +1,48 @@
 package database
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"io/fs"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/upper/db/v4"
+)
 
 type Database struct {
 	DataType string
 	Pool     *sql.DB
+
+	// The read replicas of the database, if any; see Reader.
+	Replicas *Replicas
+
+	// How WithTx retries a transaction that failed to serialize or deadlocked;
+	// DefaultTxRetry unless set.
+	TxRetry *Retry
+
+	// The session of each pool shared by Session and the repositories.
+	sessionsMu sync.Mutex
+	sessions   map[*sql.DB]db.Session
+}
+
+// Replicas selects the read replica a query runs on according to Policy, skipping the
+// replicas ejected by CheckReplicas. StickyWindow is how long a context that wrote to
+// the primary keeps reading from it, so a request reads its own writes despite the lag
+// of the replicas.
+type Replicas struct {
+	Pools        []*sql.DB
+	Policy       string
+	StickyWindow time.Duration
+
+	// Called when CheckReplicas ejects a replica, with the error of its check, or admits
+	// it again, with a nil error.
+	OnChange func(replica int, err error)
+
+	// The indexes of the ejected replicas, so replicas added to Pools start admitted.
+	down sync.Map
+	next atomic.Uint64
 }
 
 type DataSourceName struct {
@@ -14,4 +52,120 @@ type DataSourceName struct {
 	Password     string
 	DatabaseName string
 	SslMode      string
+
+	// Settings of the connection pool. Zero uses the default of OpenDB, a negative
+	// MaxOpenConns removes the limit and a negative MaxIdleConns keeps no idle connections.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// How long OpenDB waits for the database to answer the first ping.
+	PingTimeout time.Duration
+
+	// Settings of SQLite, whose DatabaseName is the path of the database file or :memory:.
+	// BusyTimeout is how long a connection waits for the lock of another, DefaultBusyTimeout
+	// unless set, and JournalMode is WAL unless set.
+	BusyTimeout time.Duration
+	JournalMode string
+
+	// Called with every statement run on the pool once it finished, e.g., to log it. The
+	// statements are counted for the contexts tracked with TrackQueries.
+	OnQuery func(ctx context.Context, q Query)
+}
+
+// Query is a statement run on the database as reported to OnQuery. Duration is the time
+// the database took to answer, which for a query ends when the first rows are returned.
+type Query struct {
+	Statement string
+	Args      []interface{}
+	Duration  time.Duration
+	Err       error
+}
+
+// QueryStats counts the statements run with a context tracked by TrackQueries, e.g., the
+// statements of a request.
+type QueryStats struct {
+	mu         sync.Mutex
+	count      int
+	duration   time.Duration
+	statements map[string]int
+}
+
+// RepeatedQuery is a statement run Count times with one context.
+type RepeatedQuery struct {
+	Statement string
+	Count     int
+}
+
+// Repository reads and writes the rows of Table as values of the model T, a struct
+// mapped with db tags, whose primary key is the id column unless PrimaryKey names
+// another. The created_at and updated_at fields of the model, when it has them, are set
+// on insert and update. With SoftDelete, Delete sets the deleted_at column of the row
+// instead of deleting it, and the rows so deleted are left out of every query.
+type Repository[T any] struct {
+	DB         *Database
+	Table      string
+	PrimaryKey string
+	SoftDelete bool
+
+	now func() time.Time
+}
+
+// Page is a page of the rows of a repository, numbered from 1, with the number of rows
+// and pages of the whole query.
+type Page[T any] struct {
+	Items   []T
+	Page    int
+	PerPage int
+	Total   uint64
+	Pages   uint64
+}
+
+// Retry controls how OpenDBWithRetry waits for a database that is not reachable yet.
+type Retry struct {
+	// The number of attempts made after the first one fails; zero disables retrying.
+	Attempts int
+
+	// The wait before the first retry, doubled after every failed attempt.
+	Backoff time.Duration
+
+	// The longest wait between two attempts.
+	MaxBackoff time.Duration
+
+	// Called before waiting for the next attempt, e.g., to log the failure.
+	OnRetry func(attempt int, err error, wait time.Duration)
+}
+
+// Migrator applies the migrations of the application to the database and records the
+// applied versions in a table. Migrations are read from the files of FS, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql, where the version is a
+// timestamp, e.g., 20261018120000_create_users_table.up.sql. A file named for the
+// database, e.g., 20261018120000_create_users_table.postgres.up.sql, is used in place of
+// one that is not. Migrations written in Go are added to Migrations.
+type Migrator struct {
+	DB         *Database
+	FS         fs.FS
+	Migrations []Migration
+
+	// The table the applied versions are recorded in, schema_migrations unless set.
+	Table string
+}
+
+// Migration changes the schema of the database with Up and reverts the change with Down.
+// Each runs in a transaction which also records the version as applied or reverted. A
+// migration without Down cannot be reverted.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, tx *sql.Tx) error
+	Down    func(ctx context.Context, tx *sql.Tx) error
+}
+
+// MigrationStatus reports whether a migration has been applied, and when.
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
 }
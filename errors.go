@@ -0,0 +1,25 @@
+package adele
+
+import "errors"
+
+// Errors returned while bootstrapping the application. The underlying cause is wrapped,
+// so both can be tested with errors.Is. Example:
+//
+//	if errors.Is(err, adele.ErrDatabaseUnavailable) {
+//	    // Run without the database or retry later.
+//	}
+var (
+	ErrDatabaseUnavailable = errors.New("database unavailable")
+	ErrCacheUnavailable    = errors.New("cache unavailable")
+	ErrRouterUnavailable   = errors.New("router unavailable")
+	ErrSessionUnavailable  = errors.New("session store unavailable")
+)
+
+// Errors returned by the service registry.
+var (
+	ErrNotProvided           = errors.New("service not provided")
+	ErrProviderDependency    = errors.New("provider dependency")
+	ErrApplicationRunning    = errors.New("application is running")
+	ErrApplicationStopped    = errors.New("application is stopped")
+	ErrFilesystemNotProvided = errors.New("file system not configured")
+)
@@ -0,0 +1,77 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+
+	"github.com/dgraph-io/badger/v3"
+	"github.com/gomodule/redigo/redis"
+)
+
+// Check the database by pinging a connection from the pool.
+func DB(pool *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return pool.PingContext(ctx)
+	}
+}
+
+// Report the statistics of the database connection pool with its check. Example:
+//
+//	registry.Add("database", health.DB(pool), health.DBStats(pool))
+func DBStats(pool *sql.DB) Option {
+	return WithDetails(func() map[string]interface{} {
+		s := pool.Stats()
+		return map[string]interface{}{
+			"max_open_connections": s.MaxOpenConnections,
+			"open_connections":     s.OpenConnections,
+			"in_use":               s.InUse,
+			"idle":                 s.Idle,
+			"wait_count":           s.WaitCount,
+			"wait_duration":        s.WaitDuration.String(),
+			"max_idle_closed":      s.MaxIdleClosed,
+			"max_idle_time_closed": s.MaxIdleTimeClosed,
+			"max_lifetime_closed":  s.MaxLifetimeClosed,
+		}
+	})
+}
+
+// Check redis by sending a PING on a connection from the pool.
+func Redis(pool *redis.Pool) Check {
+	return func(ctx context.Context) error {
+		conn, err := pool.GetContext(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		_, err = redis.DoContext(conn, ctx, "PING")
+		return err
+	}
+}
+
+// Check the Badger database is open and readable.
+func Badger(db *badger.DB) Check {
+	return func(ctx context.Context) error {
+		if db == nil || db.IsClosed() {
+			return errors.New("badger database is closed")
+		}
+		return db.View(func(txn *badger.Txn) error {
+			return nil
+		})
+	}
+}
+
+// Check a remote host, e.g., the SMTP server or a remote file system, accepts TCP
+// connections on the address.
+func Dial(address string) Check {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,199 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Create a registry whose checks time out after the timeout unless added with a timeout
+// of their own. Example:
+//
+//	registry := health.New(5 * time.Second)
+//	registry.Add("database", health.DB(pool))
+func New(timeout time.Duration) *Registry {
+	return &Registry{Timeout: timeout}
+}
+
+// Give the check its own timeout.
+func WithTimeout(d time.Duration) Option {
+	return func(e *entry) {
+		e.timeout = d
+	}
+}
+
+// Run the check on the liveness endpoint too. Checks only run on the readiness endpoint
+// by default since a subsystem that is down should take the application out of rotation
+// rather than have it restarted.
+func Liveness() Option {
+	return func(e *entry) {
+		e.liveness = true
+	}
+}
+
+// Report the details returned by the function alongside the result of the check, e.g.,
+// the statistics of a connection pool.
+func WithDetails(fn func() map[string]interface{}) Option {
+	return func(e *entry) {
+		e.details = fn
+	}
+}
+
+// Add a check to the registry under a name, replacing any check with the same name.
+func (r *Registry) Add(name string, check Check, opts ...Option) {
+	e := entry{name: name, check: check}
+	for _, opt := range opts {
+		opt(&e)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i := range r.checks {
+		if r.checks[i].name == name {
+			r.checks[i] = e
+			return
+		}
+	}
+
+	r.checks = append(r.checks, e)
+}
+
+// Run the checks concurrently, each within its timeout, and aggregate the results. Only
+// the liveness checks run when readiness is false. The report is down when any check
+// failed.
+func (r *Registry) Run(ctx context.Context, readiness bool) Report {
+	r.mu.RLock()
+	checks := make([]entry, 0, len(r.checks))
+	for _, e := range r.checks {
+		if readiness || e.liveness {
+			checks = append(checks, e)
+		}
+	}
+	r.mu.RUnlock()
+
+	report := Report{
+		Status: StatusUp,
+		Checks: make(map[string]Result, len(checks)),
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, e := range checks {
+		wg.Add(1)
+		go func(e entry) {
+			defer wg.Done()
+
+			result := r.run(ctx, e)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[e.name] = result
+			if result.Status != StatusUp {
+				report.Status = StatusDown
+			}
+		}(e)
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+// Create the handler for the liveness endpoint. The maintenance function, when given,
+// reports whether the application is in maintenance mode.
+func (r *Registry) LivenessHandler(maintenance func() bool) http.HandlerFunc {
+	return r.handler(false, maintenance)
+}
+
+// Create the handler for the readiness endpoint. The maintenance function, when given,
+// reports whether the application is in maintenance mode.
+func (r *Registry) ReadinessHandler(maintenance func() bool) http.HandlerFunc {
+	return r.handler(true, maintenance)
+}
+
+// Create a handler writing the report as JSON. The status code is 503 when a check
+// failed or the application is in maintenance mode, otherwise 200. The errors of the
+// checks are left out unless the registry is verbose.
+func (r *Registry) handler(readiness bool, maintenance func() bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		report := r.Run(req.Context(), readiness)
+
+		if maintenance != nil && maintenance() {
+			report.Status = StatusMaintenance
+		}
+
+		if !r.Verbose {
+			for name, result := range report.Checks {
+				result.Error = ""
+				report.Checks[name] = result
+			}
+		}
+
+		status := http.StatusOK
+		if report.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "no-store")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(report)
+	}
+}
+
+// Run a single check within its timeout, recovering from a panic in the check.
+func (r *Registry) run(ctx context.Context, e entry) (result Result) {
+	timeout := e.timeout
+	if timeout <= 0 {
+		timeout = r.Timeout
+	}
+	if timeout <= 0 {
+		timeout = 5 * time.Second
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				done <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		done <- e.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	result.Duration = time.Since(start).Round(time.Microsecond).String()
+
+	if e.details != nil {
+		result.Details = e.details()
+	}
+
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			result.Error = fmt.Sprintf("timed out after %s", timeout)
+		}
+		return result
+	}
+
+	result.Status = StatusUp
+	return result
+}
@@ -0,0 +1,120 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRegistry_Run(t *testing.T) {
+	r := New(time.Second)
+
+	r.Add("up", func(ctx context.Context) error { return nil }, Liveness(), WithDetails(func() map[string]interface{} {
+		return map[string]interface{}{"in_use": 1}
+	}))
+	r.Add("down", func(ctx context.Context) error { return errors.New("unreachable") })
+	r.Add("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}, WithTimeout(10*time.Millisecond))
+
+	report := r.Run(context.Background(), true)
+
+	if report.Status != StatusDown {
+		t.Errorf("Status = %s, want %s", report.Status, StatusDown)
+	}
+	if got := report.Checks["up"].Status; got != StatusUp {
+		t.Errorf("up check = %s, want %s", got, StatusUp)
+	}
+	if got := report.Checks["up"].Details["in_use"]; got != 1 {
+		t.Errorf("up check details = %v, want in_use 1", report.Checks["up"].Details)
+	}
+	if got := report.Checks["down"].Error; got != "unreachable" {
+		t.Errorf("down check error = %q, want unreachable", got)
+	}
+	if got := report.Checks["slow"].Error; got != "timed out after 10ms" {
+		t.Errorf("slow check error = %q, want a timeout", got)
+	}
+
+	liveness := r.Run(context.Background(), false)
+	if liveness.Status != StatusUp || len(liveness.Checks) != 1 {
+		t.Errorf("liveness report = %+v, want only the up check", liveness)
+	}
+}
+
+func TestRegistry_Handlers(t *testing.T) {
+	r := New(time.Second)
+	r.Add("down", func(ctx context.Context) error { return errors.New("unreachable") })
+
+	maintenance := false
+	inMaintenance := func() bool { return maintenance }
+
+	tests := []struct {
+		name        string
+		handler     http.HandlerFunc
+		maintenance bool
+		code        int
+		status      string
+	}{
+		{"liveness", r.LivenessHandler(inMaintenance), false, http.StatusOK, StatusUp},
+		{"readiness", r.ReadinessHandler(inMaintenance), false, http.StatusServiceUnavailable, StatusDown},
+		{"liveness in maintenance", r.LivenessHandler(inMaintenance), true, http.StatusServiceUnavailable, StatusMaintenance},
+		{"readiness in maintenance", r.ReadinessHandler(inMaintenance), true, http.StatusServiceUnavailable, StatusMaintenance},
+	}
+
+	for _, tt := range tests {
+		maintenance = tt.maintenance
+
+		rec := httptest.NewRecorder()
+		tt.handler(rec, httptest.NewRequest("GET", "/", nil))
+
+		if rec.Code != tt.code {
+			t.Errorf("%s: code = %d, want %d", tt.name, rec.Code, tt.code)
+		}
+
+		var report Report
+		if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if report.Status != tt.status {
+			t.Errorf("%s: status = %s, want %s", tt.name, report.Status, tt.status)
+		}
+		if got := report.Checks["down"].Error; got != "" {
+			t.Errorf("%s: error = %q, want it hidden", tt.name, got)
+		}
+	}
+
+	r.Verbose = true
+
+	rec := httptest.NewRecorder()
+	r.ReadinessHandler(nil)(rec, httptest.NewRequest("GET", "/", nil))
+
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if got := report.Checks["down"].Error; got != "unreachable" {
+		t.Errorf("verbose error = %q, want unreachable", got)
+	}
+}
+
+func TestDial(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if err := Dial(l.Addr().String())(context.Background()); err != nil {
+		t.Errorf("Dial() error = %v", err)
+	}
+
+	if err := Dial("127.0.0.1:1")(context.Background()); err == nil {
+		t.Error("expected an error dialing a closed port")
+	}
+}
@@ -0,0 +1,60 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Paths the framework registers the health handlers on.
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+)
+
+// Status of a check or of a report.
+const (
+	StatusUp          = "up"
+	StatusDown        = "down"
+	StatusMaintenance = "maintenance"
+)
+
+// Check reports whether a subsystem is reachable, returning an error when it is not. The
+// context is cancelled once the timeout of the check has passed.
+type Check func(ctx context.Context) error
+
+// Registry holds the checks run by the health handlers. Timeout is used for every check
+// added without a timeout of its own. The handlers only report the errors of the checks,
+// which may name internal hosts and ports, when Verbose is set.
+type Registry struct {
+	Timeout time.Duration
+	Verbose bool
+	mu      sync.RWMutex
+	checks  []entry
+}
+
+// A check added to the registry.
+type entry struct {
+	name     string
+	check    Check
+	timeout  time.Duration
+	liveness bool
+	details  func() map[string]interface{}
+}
+
+// Option configures a check added to the registry.
+type Option func(*entry)
+
+// Result of a single check.
+type Result struct {
+	Status   string                 `json:"status"`
+	Error    string                 `json:"error,omitempty"`
+	Duration string                 `json:"duration"`
+	Details  map[string]interface{} `json:"details,omitempty"`
+}
+
+// Report aggregates the result of every check run by a handler.
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
@@ -1,25 +1,15 @@
 package httpserver
 
 import (
-	"fmt"
-	"log"
 	"net/http"
-	"time"
 
 	"github.com/cidekar/adele-framework"
-	"github.com/sirupsen/logrus"
 )
 
-// Create a new http server for use with the adele skeleton application.
+// Create a new http server for use with the adele skeleton application. Values missing
+// from the application configuration fall back to the framework defaults.
 func NewServer(adele *adele.Adele) *http.Server {
-	return &http.Server{
-		Addr:         fmt.Sprintf(":%s", adele.Helpers.Getenv("HTTP_PORT", "4000")),
-		ErrorLog:     log.New(adele.Log.WriterLevel(logrus.ErrorLevel), "", 0),
-		Handler:      adele.Routes,
-		IdleTimeout:  30 * time.Second,
-		ReadTimeout:  30 * time.Second,
-		WriteTimeout: 600 * time.Second,
-	}
+	return adele.NewHTTPServer()
 }
 
 // Creates a new http server, listens on the TCP network address srv.Addr and then calls
@@ -28,15 +28,12 @@ func TestNewServer_DefaultPort(t *testing.T) {
 }
 
 func TestNewServer_CustomPort(t *testing.T) {
-	// Set custom port
-	os.Setenv("HTTP_PORT", "8080")
-	defer os.Unsetenv("PORT")
-
-	// Create minimal Adele instance
+	// Create minimal Adele instance with a custom port
 	app := &adele.Adele{
 		Routes: mux.NewRouter(),
 		Log:    logrus.New(),
 	}
+	adele.WithHTTP(adele.HTTPConfig{Port: "8080"})(app)
 
 	server := NewServer(app)
 	if server.Addr != ":8080" {
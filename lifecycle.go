@@ -0,0 +1,328 @@
+package adele
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Add a hook to the application lifecycle. Hooks start after the framework subsystems
+// they may depend on—the database, cache, mailer, scheduler and providers—and before the
+// HTTP server begins accepting requests. Example:
+//
+//	app.AddHook(adele.Hook{
+//	    Name:    "queue",
+//	    OnStart: queue.Start,
+//	    OnStop:  queue.Stop,
+//	})
+func (a *Adele) AddHook(h Hook) {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	a.lifecycle.hooks = append(a.lifecycle.hooks, h)
+}
+
+// Start every configured subsystem and return once the HTTP server is listening. When
+// a component fails to start, the components already started are stopped in reverse
+// order and the error is returned. The subsystems stopped by a failed start or by
+// Shutdown, e.g., the database pool and the mail jobs channel, are not reopened, so
+// starting the application again returns ErrApplicationStopped.
+func (a *Adele) Start(ctx context.Context) error {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	if a.lifecycle.running {
+		return errors.New("application is already running")
+	}
+	if a.lifecycle.stopped {
+		return ErrApplicationStopped
+	}
+
+	a.lifecycle.serveErr = make(chan error, 1)
+	a.lifecycle.started = nil
+
+	// The providers are booted below, so no provider can be added until shutdown.
+	a.setProvidersStarted(true)
+
+	for _, h := range a.hooks() {
+		if h.OnStart != nil {
+			if err := h.OnStart(ctx); err != nil {
+				stopErr := a.stop(ctx, a.lifecycle.started)
+				a.lifecycle.started = nil
+				a.lifecycle.stopped = true
+				a.setProvidersStarted(false)
+				return errors.Join(&ComponentError{Component: h.Name, Err: err}, stopErr)
+			}
+		}
+		a.lifecycle.started = append(a.lifecycle.started, h)
+	}
+
+	a.lifecycle.running = true
+
+	return nil
+}
+
+// Start the application and block until the context is cancelled, SIGINT or SIGTERM is
+// received, or the HTTP server fails. The application is then shut down within the
+// configured shutdown timeout. Example:
+//
+//	if err := app.Run(context.Background()); err != nil {
+//	    app.Log.Fatal(err)
+//	}
+func (a *Adele) Run(ctx context.Context) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := a.Start(ctx); err != nil {
+		return err
+	}
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+		a.Log.Info("shutting down")
+	case err := <-a.lifecycle.serveErr:
+		runErr = &ComponentError{Component: "http", Err: err}
+	}
+
+	timeout := a.config.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	return errors.Join(runErr, a.Shutdown(shutdownCtx))
+}
+
+// Stop every component in the reverse order it was started. Each component gets the
+// remainder of the context deadline and every failure is reported as a ComponentError
+// joined into the returned error. When the application was never started, only the
+// resources opened during bootstrap, e.g., the database pool and cache, are closed. The
+// application cannot be started again.
+func (a *Adele) Shutdown(ctx context.Context) error {
+	a.lifecycle.mu.Lock()
+	defer a.lifecycle.mu.Unlock()
+
+	hooks := a.lifecycle.started
+	if !a.lifecycle.running {
+		hooks = nil
+		for _, h := range a.hooks() {
+			if h.OnStart == nil {
+				hooks = append(hooks, h)
+			}
+		}
+	}
+
+	err := a.stop(ctx, hooks)
+
+	// An RPC server started outside of the lifecycle, e.g., by rpcserver.Start, is
+	// stopped too.
+	if rpcErr := a.StopRPC(); rpcErr != nil {
+		err = errors.Join(err, &ComponentError{Component: "rpc", Err: rpcErr})
+	}
+
+	a.lifecycle.started = nil
+	a.lifecycle.running = false
+	a.lifecycle.stopped = true
+	a.setProvidersStarted(false)
+
+	return err
+}
+
+// Create the HTTP server for the application. Values missing from the configuration
+// fall back to the framework defaults.
+func (a *Adele) NewHTTPServer() *http.Server {
+	c := a.config.HTTP
+
+	port := c.Port
+	if port == "" {
+		port = "4000"
+	}
+
+	return &http.Server{
+		Addr:         fmt.Sprintf(":%s", port),
+		ErrorLog:     log.New(a.Log.WriterLevel(logrus.ErrorLevel), "", 0),
+		Handler:      a.Routes,
+		IdleTimeout:  durationOrDefault(c.IdleTimeout, 30*time.Second),
+		ReadTimeout:  durationOrDefault(c.ReadTimeout, 30*time.Second),
+		WriteTimeout: durationOrDefault(c.WriteTimeout, 600*time.Second),
+	}
+}
+
+// Stop the hooks in reverse order, collecting every error.
+func (a *Adele) stop(ctx context.Context, hooks []Hook) error {
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		h := hooks[i]
+		if h.OnStop == nil {
+			continue
+		}
+		if err := h.OnStop(ctx); err != nil {
+			errs = append(errs, &ComponentError{Component: h.Name, Err: err})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Build the ordered list of lifecycle hooks: the framework subsystems that others
+// depend on, the providers, the hooks added by the application, the RPC server, and the
+// HTTP server last.
+func (a *Adele) hooks() []Hook {
+	var hooks []Hook
+
+	if a.DB != nil && a.DB.Pool != nil {
+		hooks = append(hooks, Hook{
+			Name: "database",
+			OnStop: func(ctx context.Context) error {
+				return a.DB.Close()
+			},
+		})
+	}
+
+	// A session store cleaning up expired sessions in the database stops before the
+	// database is closed.
+	if a.Session != nil {
+		if store, ok := a.Session.Store.(interface{ StopCleanup() }); ok {
+			hooks = append(hooks, Hook{
+				Name: "session",
+				OnStop: func(ctx context.Context) error {
+					store.StopCleanup()
+					return nil
+				},
+			})
+		}
+	}
+
+	if closer, ok := a.Cache.(io.Closer); ok {
+		hooks = append(hooks, Hook{
+			Name: "cache",
+			OnStop: func(ctx context.Context) error {
+				return closer.Close()
+			},
+		})
+	}
+
+	if a.Mail.Jobs != nil {
+		done := make(chan struct{})
+		hooks = append(hooks, Hook{
+			Name: "mail",
+			OnStart: func(ctx context.Context) error {
+				go func() {
+					defer close(done)
+					a.Mail.ListenForMail()
+				}()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				// Closing the jobs channel lets the listener send the queued messages
+				// before it returns.
+				close(a.Mail.Jobs)
+				return wait(ctx, done)
+			},
+		})
+	}
+
+	if a.Scheduler != nil {
+		hooks = append(hooks, Hook{
+			Name: "scheduler",
+			OnStart: func(ctx context.Context) error {
+				a.Scheduler.Start()
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				return wait(ctx, a.Scheduler.Stop().Done())
+			},
+		})
+	}
+
+	hooks = append(hooks, a.providerHooks()...)
+	hooks = append(hooks, a.lifecycle.hooks...)
+
+	if a.rpcConfigured() {
+		hooks = append(hooks, Hook{
+			Name: "rpc",
+			OnStart: func(ctx context.Context) error {
+				return a.StartRPC()
+			},
+			OnStop: func(ctx context.Context) error {
+				return a.StopRPC()
+			},
+		})
+	}
+
+	if a.Routes != nil {
+		hooks = append(hooks, Hook{
+			Name: "http",
+			OnStart: func(ctx context.Context) error {
+				server := a.NewHTTPServer()
+
+				// Listen before serving so a port that is in use fails the start.
+				listener, err := net.Listen("tcp", server.Addr)
+				if err != nil {
+					return err
+				}
+
+				a.lifecycle.httpServer = server
+				serveErr := a.lifecycle.serveErr
+
+				go func() {
+					if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+						serveErr <- err
+					}
+				}()
+
+				return nil
+			},
+			OnStop: func(ctx context.Context) error {
+				if a.lifecycle.httpServer == nil {
+					return nil
+				}
+				err := a.lifecycle.httpServer.Shutdown(ctx)
+				a.lifecycle.httpServer = nil
+				return err
+			},
+		})
+	}
+
+	return hooks
+}
+
+// Error describes the component and the failure.
+func (e *ComponentError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Component, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ComponentError) Unwrap() error {
+	return e.Err
+}
+
+// Wait for the channel to close or the context to be done.
+func wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Return the duration or the default when the duration is not set.
+func durationOrDefault(d, defaultValue time.Duration) time.Duration {
+	if d <= 0 {
+		return defaultValue
+	}
+	return d
+}
@@ -0,0 +1,190 @@
+package adele
+
+import (
+	"context"
+	"errors"
+	"net/rpc"
+	"strings"
+	"testing"
+
+	"github.com/cidekar/adele-framework/mailer"
+)
+
+// Create a hook that records its start and stop in the order they happen.
+func recordingHook(name string, events *[]string, startErr, stopErr error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			*events = append(*events, "start "+name)
+			return startErr
+		},
+		OnStop: func(ctx context.Context) error {
+			*events = append(*events, "stop "+name)
+			return stopErr
+		},
+	}
+}
+
+func TestLifecycle_StartAndShutdownOrder(t *testing.T) {
+	a := &Adele{}
+
+	var events []string
+	a.AddHook(recordingHook("first", &events, nil, nil))
+	a.AddHook(recordingHook("second", &events, nil, nil))
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := a.Start(context.Background()); err == nil {
+		t.Error("expected an error when starting a running application")
+	}
+
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	want := "start first,start second,stop second,stop first"
+	if got := strings.Join(events, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestLifecycle_StartFailureRollsBack(t *testing.T) {
+	a := &Adele{}
+
+	var events []string
+	failure := errors.New("boom")
+	a.AddHook(recordingHook("first", &events, nil, nil))
+	a.AddHook(recordingHook("second", &events, failure, nil))
+	a.AddHook(recordingHook("third", &events, nil, nil))
+
+	err := a.Start(context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("Start() error = %v, want %v", err, failure)
+	}
+
+	var cerr *ComponentError
+	if !errors.As(err, &cerr) || cerr.Component != "second" {
+		t.Errorf("expected a ComponentError for second, got %v", err)
+	}
+
+	want := "start first,start second,stop first"
+	if got := strings.Join(events, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestLifecycle_ShutdownAggregatesErrors(t *testing.T) {
+	a := &Adele{}
+
+	var events []string
+	a.AddHook(recordingHook("first", &events, nil, errors.New("first failed")))
+	a.AddHook(recordingHook("second", &events, nil, errors.New("second failed")))
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	err := a.Shutdown(context.Background())
+	if err == nil {
+		t.Fatal("expected an error from Shutdown")
+	}
+
+	for _, want := range []string{"first: first failed", "second: second failed"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not mention %q", err.Error(), want)
+		}
+	}
+}
+
+func TestLifecycle_RPCServer(t *testing.T) {
+	newApp := func() *Adele {
+		a := &Adele{}
+		a.config.RPC = RPCConfig{Addr: "127.0.0.1", Port: "0"}
+		if err := a.Start(context.Background()); err != nil {
+			t.Fatalf("Start() error = %v", err)
+		}
+		return a
+	}
+
+	// Two applications in one process keep their own connections.
+	first, second := newApp(), newApp()
+	defer second.Shutdown(context.Background())
+
+	dial := func(a *Adele) *rpc.Client {
+		client, err := rpc.Dial("tcp", (*a.RPCListener).Addr().String())
+		if err != nil {
+			t.Fatalf("Dial() error = %v", err)
+		}
+		return client
+	}
+
+	firstClient, secondClient := dial(first), dial(second)
+	defer secondClient.Close()
+
+	reply := &MaintenanceModeReply{}
+	if err := secondClient.Call("RPCServer.SetMaintenanceMode", &MaintenanceModeArgs{InMaintenanceMode: true}, reply); err != nil {
+		t.Fatalf("Call() error = %v", err)
+	}
+	if reply.Status != "down" || !second.MaintenanceMode {
+		t.Errorf("status = %q, maintenance = %v; want down", reply.Status, second.MaintenanceMode)
+	}
+
+	if err := first.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	if first.RPCListener != nil {
+		t.Error("RPCListener is still set after Shutdown()")
+	}
+	if err := firstClient.Call("RPCServer.SetMaintenanceMode", &MaintenanceModeArgs{}, reply); err == nil {
+		t.Error("connection to the stopped server is still open")
+	}
+
+	if err := secondClient.Call("RPCServer.SetMaintenanceMode", &MaintenanceModeArgs{}, reply); err != nil {
+		t.Errorf("Call() to the running server error = %v", err)
+	}
+	if reply.Status != "up" || second.MaintenanceMode {
+		t.Errorf("status = %q, maintenance = %v; want up", reply.Status, second.MaintenanceMode)
+	}
+}
+
+func TestLifecycle_ConfigIgnoresEnvironment(t *testing.T) {
+	t.Setenv("RPC_SERVER_DISABLE", "true")
+	t.Setenv("RPC_SERVER_PORT", "1")
+	t.Setenv("HTTP_PORT", "1")
+
+	a := &Adele{}
+	a.config.RPC = RPCConfig{Port: "4141"}
+	a.config.HTTP = HTTPConfig{Port: "4242"}
+
+	if !a.rpcConfigured() {
+		t.Error("the RPC server set in code is disabled by the environment")
+	}
+	if addr, port := a.rpcAddress(); addr != "127.0.0.1" || port != "4141" {
+		t.Errorf("rpcAddress() = %s, %s; want 127.0.0.1, 4141", addr, port)
+	}
+	if got := a.NewHTTPServer().Addr; got != ":4242" {
+		t.Errorf("NewHTTPServer().Addr = %s, want :4242", got)
+	}
+}
+
+func TestLifecycle_RestartAfterShutdown(t *testing.T) {
+	a := &Adele{}
+	a.Mail.Jobs = make(chan mailer.Message)
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	// The mail jobs channel is closed, so the application cannot start again.
+	if err := a.Start(context.Background()); !errors.Is(err, ErrApplicationStopped) {
+		t.Errorf("Start() after Shutdown() error = %v, want ErrApplicationStopped", err)
+	}
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown() error = %v", err)
+	}
+}
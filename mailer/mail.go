@@ -18,10 +18,10 @@ import (
 
 // Listen on the mail channel and send when a payload is received.
 // The method will run continually in the background and send error
-// or success messages on the results channel.
+// or success messages on the results channel. Once the jobs channel
+// is closed the queued messages are sent and the method returns.
 func (m *Mail) ListenForMail() {
-	for {
-		msg := <-m.Jobs // listen for jobs
+	for msg := range m.Jobs { // listen for jobs
 		err := m.Send(msg)
 
 		if err != nil {
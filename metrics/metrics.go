@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Upper bounds of the buckets of a histogram created without buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Create an empty registry. Example:
+//
+//	registry := metrics.New()
+//	jobs := registry.Counter("app_jobs_total", "Jobs processed.", "queue")
+//	jobs.With("emails").Inc()
+func New() *Registry {
+	return &Registry{}
+}
+
+// Return the counter with the name, creating it when it does not exist. Asking again for
+// a metric with the same name returns the same counter; asking for it with another kind
+// or other labels panics.
+func (r *Registry) Counter(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{family: r.family(name, help, KindCounter, labels, nil)}
+}
+
+// Return the histogram with the name, creating it with the buckets, or DefaultBuckets
+// when none are given, when it does not exist. Asking for an existing histogram returns it
+// as Counter does.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = slices.Clone(buckets)
+	sort.Float64s(buckets)
+
+	return &HistogramVec{family: r.family(name, help, KindHistogram, labels, buckets)}
+}
+
+// Add a gauge reading its value from the function every time the metrics are written,
+// e.g., the number of open connections of a pool. A gauge added again with the same
+// label values replaces the function.
+func (r *Registry) GaugeFunc(name, help string, labels []string, values []string, fn func() float64) {
+	f := r.family(name, help, KindGauge, labels, nil)
+	s := f.with(values)
+
+	f.mu.Lock()
+	s.gauge = fn
+	f.mu.Unlock()
+}
+
+// Return the counter of the label values, given in the order of the labels.
+func (v *CounterVec) With(values ...string) *Counter {
+	return v.family.with(values).counter
+}
+
+// Add one to the counter.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Add the value to the counter; a negative value panics.
+func (c *Counter) Add(v float64) {
+	if v < 0 {
+		panic("metrics: counter cannot decrease")
+	}
+
+	for {
+		old := atomic.LoadUint64(&c.bits)
+		if atomic.CompareAndSwapUint64(&c.bits, old, math.Float64bits(math.Float64frombits(old)+v)) {
+			return
+		}
+	}
+}
+
+// Return the value of the counter.
+func (c *Counter) Value() float64 {
+	return math.Float64frombits(atomic.LoadUint64(&c.bits))
+}
+
+// Return the histogram of the label values, given in the order of the labels.
+func (v *HistogramVec) With(values ...string) *Histogram {
+	return v.family.with(values).histogram
+}
+
+// Count the observation in the bucket of every upper bound it does not exceed.
+func (h *Histogram) Observe(v float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i, bound := range h.buckets {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+// Return the number of observations and their sum.
+func (h *Histogram) Count() (uint64, float64) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.count, h.sum
+}
+
+// Find or create the family with the name, panicking when it exists with another kind or
+// other labels.
+func (r *Registry) family(name, help, kind string, labels []string, buckets []float64) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if f, ok := r.families[name]; ok {
+		if f.kind != kind || !slices.Equal(f.labels, labels) {
+			panic(fmt.Sprintf("metrics: %s already registered as a %s with labels %v", name, f.kind, f.labels))
+		}
+		return f
+	}
+
+	if r.families == nil {
+		r.families = make(map[string]*family)
+	}
+
+	f := &family{
+		name:    name,
+		help:    help,
+		kind:    kind,
+		labels:  slices.Clone(labels),
+		buckets: buckets,
+		series:  make(map[string]*series),
+	}
+	r.families[name] = f
+
+	return f
+}
+
+// Find or create the series of the label values, panicking when the number of values does
+// not match the labels.
+func (f *family) with(values []string) *series {
+	if len(values) != len(f.labels) {
+		panic(fmt.Sprintf("metrics: %s has labels %v, got values %v", f.name, f.labels, values))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if s, ok := f.series[key]; ok {
+		return s
+	}
+
+	s := &series{values: slices.Clone(values)}
+	switch f.kind {
+	case KindCounter:
+		s.counter = &Counter{}
+	case KindHistogram:
+		s.histogram = &Histogram{buckets: f.buckets, counts: make([]uint64, len(f.buckets))}
+	}
+	f.series[key] = s
+
+	return s
+}
@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_WriteText(t *testing.T) {
+	r := New()
+
+	jobs := r.Counter("jobs_total", "Jobs processed.", "queue")
+	jobs.With("emails").Inc()
+	jobs.With("emails").Add(2)
+	jobs.With("reports").Inc()
+
+	latency := r.Histogram("job_duration_seconds", "Job latency.", []float64{1, 0.1})
+	latency.With().Observe(0.05)
+	latency.With().Observe(0.5)
+	latency.With().Observe(5)
+
+	r.GaugeFunc("open_connections", "Open connections.", []string{"pool"}, []string{"primary"}, func() float64 { return 7 })
+
+	var out strings.Builder
+	if err := r.WriteText(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP job_duration_seconds Job latency.
+# TYPE job_duration_seconds histogram
+job_duration_seconds_bucket{le="0.1"} 1
+job_duration_seconds_bucket{le="1"} 2
+job_duration_seconds_bucket{le="+Inf"} 3
+job_duration_seconds_sum 5.55
+job_duration_seconds_count 3
+# HELP jobs_total Jobs processed.
+# TYPE jobs_total counter
+jobs_total{queue="emails"} 3
+jobs_total{queue="reports"} 1
+# HELP open_connections Open connections.
+# TYPE open_connections gauge
+open_connections{pool="primary"} 7
+`
+	if out.String() != want {
+		t.Errorf("WriteText() =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestRegistry_SameMetric(t *testing.T) {
+	r := New()
+
+	r.Counter("jobs_total", "Jobs processed.", "queue").With("emails").Inc()
+	r.Counter("jobs_total", "Jobs processed.", "queue").With("emails").Inc()
+
+	if got := r.Counter("jobs_total", "", "queue").With("emails").Value(); got != 2 {
+		t.Errorf("Value() = %v, want 2", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic registering a metric with other labels")
+		}
+	}()
+	r.Counter("jobs_total", "Jobs processed.", "worker")
+}
+
+func TestRegistry_EscapesLabels(t *testing.T) {
+	r := New()
+	r.Counter("errors_total", "Errors.", "message").With("say \"hi\"\\\n").Inc()
+
+	var out strings.Builder
+	r.WriteText(&out)
+
+	if want := `errors_total{message="say \"hi\"\\\n"} 1`; !strings.Contains(out.String(), want) {
+		t.Errorf("WriteText() = %s, want it to contain %s", out.String(), want)
+	}
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := New()
+	r.Counter("jobs_total", "Jobs processed.").With().Inc()
+
+	rec := httptest.NewRecorder()
+	r.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, Path, nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type = %s, want the Prometheus text format", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "jobs_total 1") {
+		t.Errorf("body = %s, want jobs_total 1", rec.Body.String())
+	}
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bufio"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Write every metric in the Prometheus text exposition format, sorted by name and
+// label values.
+func (r *Registry) WriteText(w io.Writer) error {
+	r.mu.Lock()
+	families := make([]*family, 0, len(r.families))
+	for _, f := range r.families {
+		families = append(families, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(families, func(i, j int) bool { return families[i].name < families[j].name })
+
+	bw := bufio.NewWriter(w)
+	for _, f := range families {
+		f.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+// Serve the metrics in the Prometheus text exposition format. Example:
+//
+//	app.Routes.Get(metrics.Path, registry.Handler())
+func (r *Registry) Handler() http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	}
+}
+
+// Write the help, type and every series of the family.
+func (f *family) write(w *bufio.Writer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w.WriteString("# HELP " + f.name + " " + escape(f.help, false) + "\n")
+	w.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+
+	for _, key := range keys {
+		s := f.series[key]
+
+		switch f.kind {
+		case KindCounter:
+			sample(w, f.name, f.labels, s.values, s.counter.Value())
+		case KindGauge:
+			if s.gauge != nil {
+				sample(w, f.name, f.labels, s.values, s.gauge())
+			}
+		case KindHistogram:
+			h := s.histogram
+			h.mu.Lock()
+
+			labels := append(f.labels[:len(f.labels):len(f.labels)], "le")
+			for i, bound := range h.buckets {
+				sample(w, f.name+"_bucket", labels, append(s.values[:len(s.values):len(s.values)], formatFloat(bound)), float64(h.counts[i]))
+			}
+			sample(w, f.name+"_bucket", labels, append(s.values[:len(s.values):len(s.values)], "+Inf"), float64(h.count))
+			sample(w, f.name+"_sum", f.labels, s.values, h.sum)
+			sample(w, f.name+"_count", f.labels, s.values, float64(h.count))
+
+			h.mu.Unlock()
+		}
+	}
+}
+
+// Write a line holding a sample.
+func sample(w *bufio.Writer, name string, labels, values []string, v float64) {
+	w.WriteString(name)
+
+	if len(labels) > 0 {
+		w.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			w.WriteString(label + `="` + escape(values[i], true) + `"`)
+		}
+		w.WriteByte('}')
+	}
+
+	w.WriteString(" " + formatFloat(v) + "\n")
+}
+
+// Format a value the way Prometheus parses it.
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// Escape the backslashes and line feeds of a help text or, with quotes, a label value.
+func escape(s string, quotes bool) string {
+	r := strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	if quotes {
+		r = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+	}
+	return r.Replace(s)
+}
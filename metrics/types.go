@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"sync"
+)
+
+// Path the framework registers the metrics handler on.
+const Path = "/metrics"
+
+// Kinds of metric.
+const (
+	KindCounter   = "counter"
+	KindGauge     = "gauge"
+	KindHistogram = "histogram"
+)
+
+// Registry holds the metrics of an application and writes them in the Prometheus text
+// exposition format.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]*family
+}
+
+// A metric and every series of it, one for each combination of label values.
+type family struct {
+	name    string
+	help    string
+	kind    string
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+// The values of a metric with a combination of label values.
+type series struct {
+	values    []string
+	counter   *Counter
+	histogram *Histogram
+	gauge     func() float64
+}
+
+// CounterVec is a counter with labels; With returns the counter of a combination of
+// label values.
+type CounterVec struct {
+	family *family
+}
+
+// Counter is a value that only goes up, e.g., the number of requests served.
+type Counter struct {
+	bits uint64
+}
+
+// HistogramVec is a histogram with labels; With returns the histogram of a combination
+// of label values.
+type HistogramVec struct {
+	family *family
+}
+
+// Histogram counts observations, e.g., request durations, in buckets of upper bounds.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64
+	count   uint64
+	sum     float64
+}
@@ -11,6 +11,15 @@ func (a *Middleware) CheckForMaintenanceMode(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 
 		if a.MaintenanceMode {
+			// paths handled by the application while in maintenance mode e.g., the framework
+			// health checks, which report maintenance mode themselves.
+			for _, path := range a.MaintenanceBypass {
+				if r.URL.Path == path {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
 			// urls accessible while application is in maintenance mode e.g., health check url.
 			urls := strings.Split(os.Getenv("MAINTENANCE_URL"), ",")
 			if len(urls) > 1 {
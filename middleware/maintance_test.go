@@ -53,3 +53,31 @@ func Test_CheckForMaintenanceModeMiddlewareHealthChecks(t *testing.T) {
 	}
 
 }
+
+func Test_CheckForMaintenanceModeMiddlewareBypass(t *testing.T) {
+	r := mux.NewRouter()
+
+	m := Middleware{
+		MaintenanceMode:   true,
+		MaintenanceBypass: []string{"/healthz"},
+	}
+
+	r.Use(m.CheckForMaintenanceMode)
+
+	r.Get("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	ts := httptest.NewServer(r)
+	defer ts.Close()
+
+	res, _ := testRequest(t, ts, "GET", "/healthz", nil)
+	if res.StatusCode != http.StatusTeapot {
+		t.Error("bypassed path was not handled by the application:", res.StatusCode)
+	}
+
+	res, _ = testRequest(t, ts, "GET", "/healthz/other", nil)
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Error("only exact bypass paths should be handled by the application:", res.StatusCode)
+	}
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 
 	chi "github.com/go-chi/chi/v5/middleware"
@@ -28,6 +29,12 @@ func RequestID() func(h http.Handler) http.Handler {
 	return chi.RequestID
 }
 
+// GetReqID returns the request ID set on the context by RequestID, or an empty string when there is none, e.g., to
+// tag the logs written while handling the request.
+func GetReqID(ctx context.Context) string {
+	return chi.GetReqID(ctx)
+}
+
 // Recoverer is a middleware that recovers from panics, logs the panic (and a backtrace), and returns a HTTP 500
 // (Internal Server Error) status if possible. Recoverer prints a request ID if one is provided.
 func Recoverer() func(h http.Handler) http.Handler {
@@ -9,7 +9,13 @@ import (
 	"github.com/go-chi/httprate"
 )
 
+// Limit the number of requests a single IP address can make during a window of time. The
+// rate and duration configured on the middleware take precedence over the environment.
 func (a *Middleware) RateLimiter() func(next http.Handler) http.Handler {
+	if a.Rate > 0 && a.Duration > 0 {
+		return httprate.LimitByIP(a.Rate, a.Duration)
+	}
+
 	var rate int
 	var duration int
 
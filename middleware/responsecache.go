@@ -0,0 +1,427 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache"
+	"github.com/go-chi/chi/v5"
+)
+
+// Prefix of the cache keys of the responses.
+const ResponseCacheKeyPrefix = "response:"
+
+// The largest body of a cached response when the MaxBodySize of the cache is not set.
+const DefaultMaxBodySize = 1 << 20
+
+// Statuses of the responses that are cached.
+var cacheableStatus = map[int]bool{
+	http.StatusOK:                   true,
+	http.StatusNonAuthoritativeInfo: true,
+	http.StatusNoContent:            true,
+	http.StatusMultipleChoices:      true,
+	http.StatusMovedPermanently:     true,
+	http.StatusPermanentRedirect:    true,
+	http.StatusNotFound:             true,
+	http.StatusGone:                 true,
+}
+
+// Create a response cache storing the responses for the TTL unless their route or their
+// Cache-Control header says otherwise. Example:
+//
+//	rc := middleware.NewResponseCache(app.CacheStore(), time.Minute)
+//	rc.Vary = []string{"Accept-Language"}
+//	app.Routes.Use(rc.Handler)
+func NewResponseCache(store cache.Store, ttl time.Duration) *ResponseCache {
+	return &ResponseCache{Store: store, TTL: ttl}
+}
+
+// Serve the responses to GET and HEAD requests from the cache, storing the responses of
+// the handler that can be cached. A request for a cached response with a matching
+// If-None-Match header is answered with 304 Not Modified. The X-Cache header of the
+// response tells whether it was a HIT, a STALE response being refreshed, or a MISS.
+func (c *ResponseCache) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c.Store == nil || !cacheableRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := c.key(r)
+		now := c.clock().UnixNano()
+
+		// A response is only served to a request carrying a session or other cookies
+		// when its handler marked it public.
+		res, err := cache.GetAs[cachedResponse](r.Context(), c.Store, key)
+		usable := err == nil && (res.Public || !c.personal(r))
+		switch {
+		case usable && now < res.Expires:
+			c.serve(w, r, res, "HIT")
+			return
+		case usable && now < res.Stale:
+			c.revalidate(r, key, next)
+			c.serve(w, r, res, "STALE")
+			return
+		case err != nil && !errors.Is(err, cache.ErrMiss) && c.Log != nil:
+			c.Log.Errorf("Response cache read failed: %v", err)
+		}
+
+		// The headers set by the middleware running before the cache are set again when
+		// a cached response is served, so only the headers of the handler are stored.
+		outer := w.Header().Clone()
+		w.Header().Set("X-Cache", "MISS")
+
+		rec := &responseRecorder{ResponseWriter: w, outer: outer, max: c.maxBodySize()}
+		next.ServeHTTP(rec, r)
+
+		c.store(context.WithoutCancel(r.Context()), key, r, rec)
+	})
+}
+
+// Write the cached response, or 304 Not Modified when the client has it already.
+func (c *ResponseCache) serve(w http.ResponseWriter, r *http.Request, res cachedResponse, state string) {
+	h := w.Header()
+	for name, values := range res.Header {
+		if name != "Vary" {
+			h[name] = slices.Clone(values)
+			continue
+		}
+		for _, value := range values {
+			if !slices.Contains(h.Values(name), value) {
+				h.Add(name, value)
+			}
+		}
+	}
+
+	age := (c.clock().UnixNano() - res.Stored) / int64(time.Second)
+	h.Set("Age", strconv.FormatInt(max(age, 0), 10))
+	h.Set("X-Cache", state)
+
+	if etagMatch(r.Header.Get("If-None-Match"), h.Get("ETag")) {
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.WriteHeader(res.Status)
+	if r.Method != http.MethodHead {
+		w.Write(res.Body)
+	}
+}
+
+// Store the recorded response when it can be cached.
+func (c *ResponseCache) store(ctx context.Context, key string, r *http.Request, rec *responseRecorder) {
+	status := rec.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if rec.overflow || !cacheableStatus[status] {
+		return
+	}
+
+	header := handlerHeader(rec.Header(), rec.outer)
+	header.Del("X-Cache")
+
+	if header.Get("Set-Cookie") != "" {
+		return
+	}
+
+	cc := parseCacheControl(header.Get("Cache-Control"))
+	for _, directive := range []string{"private", "no-store", "no-cache"} {
+		if _, ok := cc[directive]; ok {
+			return
+		}
+	}
+
+	// The response to a request carrying cookies may belong to the user, e.g., a page
+	// rendered for the session, unless the handler marked it public.
+	_, public := cc["public"]
+	if c.personal(r) && !public {
+		return
+	}
+
+	// A response varying on a header missing from the key cannot be told apart.
+	for _, value := range header.Values("Vary") {
+		for _, name := range strings.Split(value, ",") {
+			name = strings.TrimSpace(name)
+			if name == "*" || (name != "" && !c.varies(name)) {
+				return
+			}
+		}
+	}
+
+	ttl := c.ttl(r, cc)
+	if ttl <= 0 {
+		return
+	}
+
+	stale := c.StaleWhileRevalidate
+	if v, ok := cc["stale-while-revalidate"]; ok {
+		if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+			stale = time.Duration(seconds) * time.Second
+		}
+	}
+
+	body := rec.body.Bytes()
+	if header.Get("ETag") == "" {
+		sum := sha256.Sum256(body)
+		header.Set("ETag", `"`+hex.EncodeToString(sum[:16])+`"`)
+	}
+
+	now := c.clock()
+	res := cachedResponse{
+		Status:  status,
+		Header:  header,
+		Body:    body,
+		Stored:  now.UnixNano(),
+		Expires: now.Add(ttl).UnixNano(),
+		Stale:   now.Add(ttl + stale).UnixNano(),
+		Public:  public,
+	}
+
+	if err := cache.SetAs(ctx, c.Store, key, res, ttl+stale); err != nil && c.Log != nil {
+		c.Log.Errorf("Response cache write failed: %v", err)
+	}
+}
+
+// Refresh the cached response in the background, once at a time for each key.
+func (c *ResponseCache) revalidate(r *http.Request, key string, next http.Handler) {
+	if _, refreshing := c.refreshing.LoadOrStore(key, struct{}{}); refreshing {
+		return
+	}
+
+	req := detachRequest(r)
+
+	go func() {
+		defer c.refreshing.Delete(key)
+		defer func() {
+			if err := recover(); err != nil && c.Log != nil {
+				c.Log.Errorf("Response cache refresh of %s panicked: %v", req.URL.Path, err)
+			}
+		}()
+
+		rec := &responseRecorder{ResponseWriter: &discardWriter{header: http.Header{}}, outer: http.Header{}, max: c.maxBodySize()}
+		next.ServeHTTP(rec, req)
+
+		c.store(req.Context(), key, req, rec)
+	}()
+}
+
+// Return the TTL of the response: the s-maxage or max-age of its Cache-Control header,
+// else the TTL of its route, else the TTL of the cache.
+func (c *ResponseCache) ttl(r *http.Request, cc map[string]string) time.Duration {
+	for _, directive := range []string{"s-maxage", "max-age"} {
+		if v, ok := cc[directive]; ok {
+			seconds, err := strconv.Atoi(v)
+			if err != nil {
+				return 0
+			}
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	if c.RouteTTL != nil {
+		if ttl, ok := c.RouteTTL(r); ok {
+			return ttl
+		}
+	}
+
+	return c.TTL
+}
+
+// Return the cache key of the request, a hash of the method, the path, the query with
+// its parameters sorted and the values of the Vary headers.
+func (c *ResponseCache) key(r *http.Request) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.Query().Encode())
+	for _, name := range c.Vary {
+		fmt.Fprintf(h, "\n%s: %s", http.CanonicalHeaderKey(name), strings.Join(r.Header.Values(name), ", "))
+	}
+	return ResponseCacheKeyPrefix + hex.EncodeToString(h.Sum(nil))
+}
+
+// Report whether the request carries cookies that are not part of the cache key, so its
+// response may be meant for the user alone.
+func (c *ResponseCache) personal(r *http.Request) bool {
+	return r.Header.Get("Cookie") != "" && !c.varies("Cookie")
+}
+
+// Report whether the header is one of the Vary headers of the cache key.
+func (c *ResponseCache) varies(name string) bool {
+	return slices.ContainsFunc(c.Vary, func(v string) bool { return strings.EqualFold(v, name) })
+}
+
+func (c *ResponseCache) maxBodySize() int {
+	if c.MaxBodySize > 0 {
+		return c.MaxBodySize
+	}
+	return DefaultMaxBodySize
+}
+
+func (c *ResponseCache) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+// Report whether the response to the request may come from the cache. Requests carrying
+// credentials or asking to bypass caches are passed to the handler.
+func cacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+	if r.Header.Get("Authorization") != "" {
+		return false
+	}
+
+	cc := parseCacheControl(r.Header.Get("Cache-Control"))
+	_, noStore := cc["no-store"]
+	_, noCache := cc["no-cache"]
+
+	return !noStore && !noCache
+}
+
+// Parse a Cache-Control header into its directives, keyed by their lower case name.
+func parseCacheControl(header string) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(header, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name == "" {
+			continue
+		}
+		directives[strings.ToLower(name)] = strings.Trim(strings.TrimSpace(value), `"`)
+	}
+	return directives
+}
+
+// Return the headers set by the handler: the headers missing from or changed since the
+// outer headers, less the values added after the outer values of a header.
+func handlerHeader(header, outer http.Header) http.Header {
+	own := make(http.Header)
+	for name, values := range header {
+		before := outer[name]
+		switch {
+		case slices.Equal(values, before):
+		case len(before) > 0 && len(values) > len(before) && slices.Equal(values[:len(before)], before):
+			own[name] = slices.Clone(values[len(before):])
+		default:
+			own[name] = slices.Clone(values)
+		}
+	}
+	return own
+}
+
+// Report whether the If-None-Match header matches the entity tag, comparing the tags
+// weakly as required for GET and HEAD requests.
+func etagMatch(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" || etag == "" {
+		return false
+	}
+
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Copy the request for use after its response was written. The routing context of the
+// request is recycled once the request is served, so the copy is given its own.
+func detachRequest(r *http.Request) *http.Request {
+	ctx := context.WithoutCancel(r.Context())
+
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		detached := chi.NewRouteContext()
+		detached.Routes = rctx.Routes
+		detached.RoutePath = rctx.RoutePath
+		detached.RouteMethod = rctx.RouteMethod
+		detached.RoutePatterns = slices.Clone(rctx.RoutePatterns)
+		detached.URLParams.Keys = slices.Clone(rctx.URLParams.Keys)
+		detached.URLParams.Values = slices.Clone(rctx.URLParams.Values)
+		ctx = context.WithValue(ctx, chi.RouteCtxKey, detached)
+	}
+
+	req := r.Clone(ctx)
+	req.Header.Del("If-None-Match")
+	req.Header.Del("If-Modified-Since")
+
+	return req
+}
+
+// A response writer passing the response through while keeping a copy of it, up to the
+// largest body that is cached. A response that is flushed while it is written, e.g., a
+// stream of events, is not cached.
+type responseRecorder struct {
+	http.ResponseWriter
+	outer    http.Header
+	status   int
+	body     bytes.Buffer
+	max      int
+	overflow bool
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.status == 0 {
+		rec.status = status
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if rec.status == 0 {
+		rec.status = http.StatusOK
+	}
+
+	if !rec.overflow {
+		if rec.body.Len()+len(b) > rec.max {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(b)
+		}
+	}
+
+	return rec.ResponseWriter.Write(b)
+}
+
+func (rec *responseRecorder) Flush() {
+	rec.overflow = true
+	if f, ok := rec.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Return the wrapped writer, so http.ResponseController reaches it.
+func (rec *responseRecorder) Unwrap() http.ResponseWriter {
+	return rec.ResponseWriter
+}
+
+// A response writer for the background refresh of a response, which has no client.
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header {
+	return d.header
+}
+
+func (d *discardWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (d *discardWriter) WriteHeader(int) {}
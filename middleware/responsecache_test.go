@@ -0,0 +1,276 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/cache/memorydriver"
+	"github.com/cidekar/adele-framework/mux"
+)
+
+// Serve a request to the handler, returning the recorded response.
+func serveCached(h http.Handler, method, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestResponseCache_HitAndConditional(t *testing.T) {
+	var calls atomic.Int32
+
+	rc := NewResponseCache(memorydriver.New(0, 0), 0)
+
+	r := mux.NewRouter()
+	rc.RouteTTL = r.CacheTTL
+	r.Use(rc.Handler)
+	r.Get("/posts[cache:5m]", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		fmt.Fprintf(w, "posts %s", r.URL.Query().Encode())
+	})
+	r.Get("/uncached", func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	})
+
+	w := serveCached(r, "GET", "/posts?b=2&a=1", nil)
+	if w.Header().Get("X-Cache") != "MISS" || w.Body.String() != "posts a=1&b=2" {
+		t.Fatalf("first response = %s %q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	// The query is normalized, so the order of the parameters does not matter.
+	w = serveCached(r, "GET", "/posts?a=1&b=2", nil)
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "posts a=1&b=2" || calls.Load() != 1 {
+		t.Fatalf("second response = %s %q after %d calls", w.Header().Get("X-Cache"), w.Body.String(), calls.Load())
+	}
+
+	etag := w.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("cached response has no ETag")
+	}
+
+	w = serveCached(r, "GET", "/posts?a=1&b=2", http.Header{"If-None-Match": {etag}})
+	if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+		t.Errorf("conditional request = %d %q, want 304", w.Code, w.Body.String())
+	}
+
+	// A route without a TTL is not cached when the cache has none.
+	serveCached(r, "GET", "/uncached", nil)
+	if w := serveCached(r, "GET", "/uncached", nil); w.Header().Get("X-Cache") != "MISS" || calls.Load() != 3 {
+		t.Errorf("uncached route = %s after %d calls", w.Header().Get("X-Cache"), calls.Load())
+	}
+}
+
+func TestResponseCache_Bypass(t *testing.T) {
+	var calls atomic.Int32
+
+	rc := NewResponseCache(memorydriver.New(0, 0), time.Minute)
+	h := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private, max-age=60")
+		case "/expired":
+			w.Header().Set("Cache-Control", "max-age=0")
+		case "/cookie":
+			http.SetCookie(w, &http.Cookie{Name: "id", Value: "42"})
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+
+	for _, path := range []string{"/private", "/expired", "/cookie", "/error"} {
+		calls.Store(0)
+		serveCached(h, "GET", path, nil)
+		serveCached(h, "GET", path, nil)
+		if calls.Load() != 2 {
+			t.Errorf("response to %s should not be cached", path)
+		}
+	}
+
+	calls.Store(0)
+	serveCached(h, "GET", "/public", nil)
+	serveCached(h, "GET", "/public", http.Header{"Authorization": {"Bearer token"}})
+	serveCached(h, "GET", "/public", http.Header{"Cache-Control": {"no-cache"}})
+	serveCached(h, "POST", "/public", nil)
+	if calls.Load() != 4 {
+		t.Errorf("handler called %d times, want 4", calls.Load())
+	}
+
+	if w := serveCached(h, "GET", "/public", nil); w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("/public = %s, want HIT", w.Header().Get("X-Cache"))
+	}
+}
+
+func TestResponseCache_Vary(t *testing.T) {
+	rc := NewResponseCache(memorydriver.New(0, 0), time.Minute)
+	rc.Vary = []string{"Accept-Language"}
+
+	h := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Accept-Language")
+		w.Write([]byte(r.Header.Get("Accept-Language")))
+	}))
+
+	serveCached(h, "GET", "/", http.Header{"Accept-Language": {"en"}})
+	serveCached(h, "GET", "/", http.Header{"Accept-Language": {"fr"}})
+
+	for _, lang := range []string{"en", "fr"} {
+		w := serveCached(h, "GET", "/", http.Header{"Accept-Language": {lang}})
+		if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != lang {
+			t.Errorf("response in %s = %s %q", lang, w.Header().Get("X-Cache"), w.Body.String())
+		}
+	}
+
+	// A response varying on a header missing from the key is not cached.
+	h = rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Vary", "Cookie")
+	}))
+	serveCached(h, "GET", "/cookie", nil)
+	if w := serveCached(h, "GET", "/cookie", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("response varying on Cookie = %s, want MISS", w.Header().Get("X-Cache"))
+	}
+}
+
+func TestResponseCache_Cookies(t *testing.T) {
+	var calls atomic.Int32
+
+	rc := NewResponseCache(memorydriver.New(0, 0), time.Minute)
+	h := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.URL.Path == "/about" {
+			w.Header().Set("Cache-Control", "public")
+		}
+		if c, err := r.Cookie("session"); err == nil {
+			fmt.Fprintf(w, "hello %s", c.Value)
+			return
+		}
+		w.Write([]byte("hello guest"))
+	}))
+
+	alice := http.Header{"Cookie": {"session=alice"}}
+	bob := http.Header{"Cookie": {"session=bob"}}
+
+	// A page rendered for a session is neither cached nor served to another session.
+	serveCached(h, "GET", "/", alice)
+	if w := serveCached(h, "GET", "/", bob); w.Body.String() != "hello bob" || calls.Load() != 2 {
+		t.Errorf("second session = %q after %d calls, want hello bob", w.Body.String(), calls.Load())
+	}
+
+	// A page cached for guests is not served to a session.
+	serveCached(h, "GET", "/", nil)
+	if w := serveCached(h, "GET", "/", alice); w.Header().Get("X-Cache") != "MISS" || w.Body.String() != "hello alice" {
+		t.Errorf("session after guest = %s %q, want MISS", w.Header().Get("X-Cache"), w.Body.String())
+	}
+	if w := serveCached(h, "GET", "/", nil); w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "hello guest" {
+		t.Errorf("guest = %s %q, want HIT", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	// A page marked public is cached and served whatever the cookies.
+	serveCached(h, "GET", "/about", alice)
+	if w := serveCached(h, "GET", "/about", bob); w.Header().Get("X-Cache") != "HIT" {
+		t.Errorf("public page = %s, want HIT", w.Header().Get("X-Cache"))
+	}
+
+	// With Cookie in the key, every session has its own copy.
+	rc.Vary = []string{"Cookie"}
+	serveCached(h, "GET", "/account", alice)
+	serveCached(h, "GET", "/account", bob)
+	for _, header := range []http.Header{alice, bob} {
+		w := serveCached(h, "GET", "/account", header)
+		if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "hello "+header.Get("Cookie")[len("session="):] {
+			t.Errorf("%s = %s %q", header.Get("Cookie"), w.Header().Get("X-Cache"), w.Body.String())
+		}
+	}
+}
+
+func TestResponseCache_StaleWhileRevalidate(t *testing.T) {
+	var version atomic.Int32
+	refreshed := make(chan struct{}, 1)
+
+	now := time.Now()
+	rc := NewResponseCache(memorydriver.New(0, 0), time.Minute)
+	rc.StaleWhileRevalidate = time.Minute
+	rc.now = func() time.Time { return now }
+
+	h := rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "v%d", version.Add(1))
+		if version.Load() > 1 {
+			select {
+			case refreshed <- struct{}{}:
+			default:
+			}
+		}
+	}))
+
+	serveCached(h, "GET", "/", nil)
+
+	now = now.Add(90 * time.Second)
+	w := serveCached(h, "GET", "/", nil)
+	if w.Header().Get("X-Cache") != "STALE" || w.Body.String() != "v1" {
+		t.Fatalf("stale response = %s %q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	select {
+	case <-refreshed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stale response not refreshed")
+	}
+
+	// The refresh stores the response just after the handler returns.
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if w = serveCached(h, "GET", "/", nil); w.Header().Get("X-Cache") == "HIT" {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if w.Header().Get("X-Cache") != "HIT" || w.Body.String() != "v2" {
+		t.Errorf("refreshed response = %s %q", w.Header().Get("X-Cache"), w.Body.String())
+	}
+
+	now = now.Add(3 * time.Minute)
+	if w := serveCached(h, "GET", "/", nil); w.Header().Get("X-Cache") != "MISS" {
+		t.Errorf("response past the stale window = %s, want MISS", w.Header().Get("X-Cache"))
+	}
+}
+
+func TestResponseCache_OuterHeaders(t *testing.T) {
+	var requests atomic.Int32
+
+	rc := NewResponseCache(memorydriver.New(0, 0), time.Minute)
+
+	// A middleware running before the cache sets its headers on every response.
+	outer := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Request", fmt.Sprint(requests.Add(1)))
+			w.Header().Add("Vary", "Cookie")
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	h := outer(rc.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain")
+	})))
+
+	serveCached(h, "GET", "/", nil)
+	w := serveCached(h, "GET", "/", nil)
+
+	if w.Header().Get("X-Cache") != "HIT" {
+		t.Fatalf("response = %s, want HIT", w.Header().Get("X-Cache"))
+	}
+	if got := w.Header().Get("X-Request"); got != "2" {
+		t.Errorf("X-Request = %s, want the value set for the request", got)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/plain" {
+		t.Errorf("Content-Type = %s, want the value set by the handler", got)
+	}
+	if got := w.Header().Values("Vary"); len(got) != 1 {
+		t.Errorf("Vary = %v, want [Cookie]", got)
+	}
+}
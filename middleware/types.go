@@ -4,9 +4,11 @@ import (
 	"io"
 	"net/http"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/alexedwards/scs/v2"
+	"github.com/cidekar/adele-framework/cache"
 	"github.com/go-chi/httprate"
 	"github.com/sirupsen/logrus"
 )
@@ -44,16 +46,53 @@ type FrameworkTraceEntry struct {
 }
 
 type Middleware struct {
-	Cookie           Cookie
-	FrameworkVersion string
-	AppName          string
-	RootPath         string
-	Log              *logrus.Logger
-	MaintenanceMode  bool
-	Session          *scs.SessionManager
-	Rate             int
-	Duration         time.Duration
-	Limit            func(requestLimit int, windowLength time.Duration, options ...httprate.Option) func(next http.Handler) http.Handler
+	Cookie            Cookie
+	FrameworkVersion  string
+	AppName           string
+	RootPath          string
+	Log               *logrus.Logger
+	MaintenanceMode   bool
+	MaintenanceBypass []string
+	Session           *scs.SessionManager
+	Rate              int
+	Duration          time.Duration
+	Limit             func(requestLimit int, windowLength time.Duration, options ...httprate.Option) func(next http.Handler) http.Handler
+}
+
+// ResponseCache caches whole responses to GET and HEAD requests in a cache Store, keyed by
+// the method, the path, the normalized query and the values of the Vary request headers.
+// The TTL of a response is the max-age or s-maxage of its Cache-Control header, else the
+// TTL of its route returned by RouteTTL, else TTL; responses with a TTL of zero, marked
+// private, no-store or no-cache, or setting a cookie are not cached. The responses to
+// requests carrying cookies, e.g., the session cookie, are neither cached nor served from
+// the cache unless their handler marks them public in their Cache-Control header or Vary
+// holds Cookie. An expired response is still served for StaleWhileRevalidate, or the
+// stale-while-revalidate of its Cache-Control header, while it is refreshed in the
+// background.
+type ResponseCache struct {
+	Store                cache.Store
+	TTL                  time.Duration
+	StaleWhileRevalidate time.Duration
+	Vary                 []string
+	RouteTTL             func(r *http.Request) (time.Duration, bool)
+
+	// Responses with a larger body are not cached; zero uses DefaultMaxBodySize.
+	MaxBodySize int
+	Log         *logrus.Logger
+
+	refreshing sync.Map
+	now        func() time.Time
+}
+
+// A response kept in the cache.
+type cachedResponse struct {
+	Status  int
+	Header  http.Header
+	Body    []byte
+	Stored  int64
+	Expires int64
+	Stale   int64
+	Public  bool
 }
 
 // used for testing the recoverer output
@@ -3,8 +3,9 @@ package mux
 import (
 	"net/http"
 	"reflect"
-	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -44,6 +45,30 @@ func (r *Mux) GetScopes(path string) MuxRouteScope {
 	return scope
 }
 
+// Return the time responses to the request are cached for, set on the route handling
+// the request with a cache annotation, e.g.:
+//
+//	mux.Get("/posts[cache:5m]", handlers.Posts)
+func (r *Mux) CacheTTL(req *http.Request) (time.Duration, bool) {
+	method := req.Method
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	pattern := r.Mux.Find(chi.NewRouteContext(), method, req.URL.Path)
+	if pattern == "" {
+		return 0, false
+	}
+
+	for _, router := range MuxRouterTree {
+		if strings.TrimSuffix(router.Base, "/")+router.Route == pattern && router.CacheTTL > 0 {
+			return router.CacheTTL, true
+		}
+	}
+
+	return 0, false
+}
+
 // With adds inline middlewares for an endpoint handler.
 func (r *Mux) With(middlewares ...func(http.Handler) http.Handler) chi.Router {
 	mx := r.Mux.With(middlewares...).(*chi.Mux)
@@ -199,7 +224,7 @@ func (r *Mux) Mount(pattern string, handler http.Handler) {
 	r.Mux.Mount(pattern, handler)
 
 	var extractRoute func([]chi.Route, string)
-	processRouter := func(r chi.Router, basePattern string) {
+	processRouter := func(r chi.Routes, basePattern string) {
 		extractRoute(r.Routes(), basePattern)
 	}
 
@@ -223,7 +248,10 @@ func (r *Mux) Mount(pattern string, handler http.Handler) {
 		}
 	}
 
-	processRouter(r, pattern)
+	// only the routes of the mounted router are served under the pattern
+	if sub, ok := handler.(chi.Routes); ok {
+		processRouter(sub, pattern)
+	}
 }
 
 // Middlewares returns a slice of middleware handler functions.
@@ -241,30 +269,47 @@ func (r *Mux) Routes() []chi.Route {
 // return the pattern used for HTTP routing. The scope annotation i.e.,
 // string pattern is enclosed in square brackets.
 func cleanMuxScopeAnnotation(pattern string) string {
-
-	re := regexp.MustCompile(`(?:\[|\])`)
-	hasAnnotation := re.MatchString(pattern)
+	route, annotations := splitMuxAnnotations(pattern)
 
 	// nothing to do here if the pattern has no annotation
-	if !hasAnnotation {
+	if annotations == nil {
 		MuxRouterTree = append(MuxRouterTree, MuxRouteInfo{
 			Route: pattern,
 		})
 		return pattern
 	}
 
-	re = regexp.MustCompile(`(.*)(?:\[(.*)\])`)
-	annotation := re.FindStringSubmatch(pattern)
-	if len(annotation) != 3 {
-		panic("adele: detected malformed annotation in pattern; " + pattern)
-	}
-
 	MuxRouterTree = append(MuxRouterTree, MuxRouteInfo{
 		Annotation: pattern,
-		Route:      annotation[1],
+		Route:      route,
+		CacheTTL:   extractCacheTTLFromMuxAnnotations(pattern, annotations),
 	})
 
-	return annotation[1]
+	return route
+}
+
+// Split the pattern into the route and the annotations that follow it, each enclosed in
+// square brackets, e.g., /posts[scopes:read][cache:5m]. The annotations are nil when the
+// pattern has none.
+func splitMuxAnnotations(pattern string) (string, []string) {
+	start := strings.IndexAny(pattern, "[]")
+	if start < 0 {
+		return pattern, nil
+	}
+
+	route, rest := pattern[:start], pattern[start:]
+
+	var annotations []string
+	for rest != "" {
+		end := strings.Index(rest, "]")
+		if rest[0] != '[' || end < 0 || strings.Contains(rest[1:end], "[") {
+			panic("adele: detected malformed annotation in pattern; " + pattern)
+		}
+		annotations = append(annotations, rest[1:end])
+		rest = rest[end+1:]
+	}
+
+	return route, annotations
 }
 
 // Extract a "scope" value from a given string pattern, where the scope
@@ -272,29 +317,48 @@ func cleanMuxScopeAnnotation(pattern string) string {
 // (e.g., [scope:value] or [scopes:value]). The extracted scopes, scopes
 // assigned to the route, are returned.
 func extractScopeFromMuxPattern(pattern string) string {
-	re := regexp.MustCompile(`(?:\[|\])`)
-	hasAnnotation := re.MatchString(pattern)
-	if !hasAnnotation {
-		return ""
-	}
+	_, annotations := splitMuxAnnotations(pattern)
 
-	re = regexp.MustCompile(`(.*)(?:\[(.*)\])`)
-	annotation := re.FindStringSubmatch(pattern)
-	if len(annotation) != 3 {
-		panic("adele: detected malformed annotation in pattern; " + pattern)
-	}
+	scope := ""
+	for _, annotation := range annotations {
+		typ, val, has := strings.Cut(annotation, ":")
+		if !has {
+			panic("adele: detected malformed annotation type in pattern: " + annotation)
+		}
 
-	typ, val, has := strings.Cut(annotation[2], ":")
-	if !has {
-		panic("adele: detected malformed annotation type in pattern: " + annotation[2])
+		switch strings.TrimSpace(typ) {
+		case "scopes", "scope":
+			scope = strings.TrimSpace(val)
+		case "cache":
+		default:
+			panic("adele: detected unknown annotation type in pattern: " + strings.TrimSpace(typ))
+		}
 	}
 
-	typ = strings.TrimSpace(typ)
+	return scope
+}
 
-	if typ == "scopes" || typ == "scope" {
-		return strings.TrimSpace(val)
-	}
+// Extract the time responses to the route are cached for from the cache annotation of
+// the pattern, e.g., [cache:5m] or [cache:300], given as a duration or a number of
+// seconds. Zero is returned when the pattern has no cache annotation.
+func extractCacheTTLFromMuxAnnotations(pattern string, annotations []string) time.Duration {
+	for _, annotation := range annotations {
+		typ, val, has := strings.Cut(annotation, ":")
+		if !has || strings.TrimSpace(typ) != "cache" {
+			continue
+		}
 
-	panic("adele: detected unknown annotation type in pattern: " + typ)
+		val = strings.TrimSpace(val)
+		if seconds, err := strconv.Atoi(val); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second
+		}
+
+		ttl, err := time.ParseDuration(val)
+		if err != nil || ttl < 0 {
+			panic("adele: detected malformed cache annotation in pattern: " + pattern)
+		}
+		return ttl
+	}
 
+	return 0
 }
@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -576,3 +577,42 @@ func TestMux_CheckAnnotation(t *testing.T) {
 	t.Log(w.Result().StatusCode)
 
 }
+
+func TestMux_CacheTTL(t *testing.T) {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	mux := NewRouter()
+	mux.Get("/cached[cache:5m]", h)
+	mux.Get("/uncached", h)
+
+	api := NewRouter()
+	api.Get("/posts[scopes:read][cache:30]", h)
+	mux.Mount("/api", api)
+
+	for _, tt := range []struct {
+		method, path string
+		ttl          time.Duration
+	}{
+		{http.MethodGet, "/cached", 5 * time.Minute},
+		{http.MethodHead, "/cached", 5 * time.Minute},
+		{http.MethodGet, "/uncached", 0},
+		{http.MethodGet, "/missing", 0},
+		{http.MethodGet, "/api/posts", 30 * time.Second},
+	} {
+		r := httptest.NewRequest(tt.method, tt.path, nil)
+		if ttl, ok := mux.CacheTTL(r); ttl != tt.ttl || ok != (tt.ttl > 0) {
+			t.Errorf("CacheTTL(%s %s) = %v, %v; want %v", tt.method, tt.path, ttl, ok, tt.ttl)
+		}
+	}
+
+	if scope := mux.GetScopes("/api/posts"); strings.Join(scope.Scope, " ") != "read" {
+		t.Errorf("GetScopes() = %v, want [read]", scope.Scope)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic registering a malformed cache annotation")
+		}
+	}()
+	mux.Get("/broken[cache:soon]", h)
+}
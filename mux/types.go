@@ -2,6 +2,7 @@ package mux
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 )
@@ -58,6 +59,7 @@ type MuxRouteInfo struct {
 	Route      string
 	Base       string
 	Scope      string
+	CacheTTL   time.Duration
 }
 
 type MuxRouteScope struct {
@@ -0,0 +1,319 @@
+package adele
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/cidekar/adele-framework/filesystem"
+	"github.com/cidekar/adele-framework/filesystem/miniofilesystem"
+	"github.com/cidekar/adele-framework/filesystem/s3filesystem"
+	"github.com/cidekar/adele-framework/filesystem/sftpfilesystem"
+	"github.com/cidekar/adele-framework/filesystem/webdavfilesystem"
+)
+
+// Add a provider to the application under a unique name. Providers added before the
+// application is bootstrapped are registered once the framework subsystems are ready,
+// after the providers they depend on; a provider added later is registered right away.
+// Every provider is booted when the application starts and shut down when it stops, so
+// a provider cannot be added to a running application; the error wraps
+// ErrApplicationRunning. Example:
+//
+//	err := app.AddProvider("queue", &queue.Provider{})
+func (a *Adele) AddProvider(name string, p Provider) error {
+	a.registry.mu.Lock()
+
+	if a.registry.started {
+		a.registry.mu.Unlock()
+		return fmt.Errorf("%w: provider %s cannot be booted", ErrApplicationRunning, name)
+	}
+
+	if a.registry.names[name] {
+		a.registry.mu.Unlock()
+		return fmt.Errorf("provider %s already added", name)
+	}
+
+	np := namedProvider{name: name, provider: p}
+
+	if !a.registry.registered {
+		a.reserveProvider(name)
+		a.registry.pending = append(a.registry.pending, np)
+		a.registry.mu.Unlock()
+		return nil
+	}
+
+	for _, dep := range dependencies(p) {
+		if !a.hasProvider(dep) {
+			a.registry.mu.Unlock()
+			return fmt.Errorf("%w: %s depends on %s, which is not registered", ErrProviderDependency, name, dep)
+		}
+	}
+
+	// Register may provide services, so it is called without holding the lock. The name
+	// is reserved first so a concurrent call cannot add it again.
+	a.reserveProvider(name)
+	a.registry.mu.Unlock()
+
+	if err := p.Register(a); err != nil {
+		a.registry.mu.Lock()
+		delete(a.registry.names, name)
+		a.registry.mu.Unlock()
+		return &ComponentError{Component: name, Err: err}
+	}
+
+	a.registry.mu.Lock()
+	a.registry.providers = append(a.registry.providers, np)
+	a.registry.mu.Unlock()
+
+	return nil
+}
+
+// Add a provider to the application before it is bootstrapped by NewWithConfig. The
+// provider is added under the name returned by its Name method, when it implements
+// Named, or its type name otherwise. NewWithConfig returns the errors adding and
+// registering the provider, e.g., a duplicate name or a dependency cycle. Example:
+//
+//	app, err := adele.NewWithConfig(rootPath, cfg, adele.WithProvider(&queue.Provider{}))
+func WithProvider(p Provider) Option {
+	return func(a *Adele) {
+		if err := a.AddProvider(providerName(p), p); err != nil {
+			a.registry.mu.Lock()
+			a.registry.errs = append(a.registry.errs, err)
+			a.registry.mu.Unlock()
+		}
+	}
+}
+
+// Return the name a provider is added under by WithProvider.
+func providerName(p Provider) string {
+	if n, ok := p.(Named); ok {
+		return n.Name()
+	}
+	return reflect.TypeOf(p).String()
+}
+
+// Reserve the name of a provider being added. The caller holds the lock.
+func (a *Adele) reserveProvider(name string) {
+	if a.registry.names == nil {
+		a.registry.names = make(map[string]bool)
+	}
+	a.registry.names[name] = true
+}
+
+// Register the providers added before bootstrap, ordering them so each provider follows
+// the providers it depends on.
+func (a *Adele) registerProviders() error {
+	a.registry.mu.Lock()
+	sorted, err := sortProviders(a.registry.pending)
+	a.registry.pending = nil
+	a.registry.registered = true
+	a.registry.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, np := range sorted {
+		if err := np.provider.Register(a); err != nil {
+			return &ComponentError{Component: np.name, Err: err}
+		}
+
+		a.registry.mu.Lock()
+		a.registry.providers = append(a.registry.providers, np)
+		a.registry.mu.Unlock()
+	}
+
+	return nil
+}
+
+// Build the lifecycle hooks booting and shutting down the registered providers.
+func (a *Adele) providerHooks() []Hook {
+	a.registry.mu.RLock()
+	defer a.registry.mu.RUnlock()
+
+	hooks := make([]Hook, 0, len(a.registry.providers))
+	for _, np := range a.registry.providers {
+		hooks = append(hooks, Hook{
+			Name:    np.name,
+			OnStart: np.provider.Boot,
+			OnStop:  np.provider.Shutdown,
+		})
+	}
+
+	return hooks
+}
+
+// Record whether the providers were booted, so AddProvider refuses new ones.
+func (a *Adele) setProvidersStarted(started bool) {
+	a.registry.mu.Lock()
+	defer a.registry.mu.Unlock()
+	a.registry.started = started
+}
+
+// Report whether a provider with the name has been registered. The caller holds the lock.
+func (a *Adele) hasProvider(name string) bool {
+	for _, np := range a.registry.providers {
+		if np.name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Provide a service to the application under its type, replacing any service provided
+// with the same type. Provide the service as an interface type to let callers resolve
+// the interface rather than the implementation. Example:
+//
+//	adele.Provide[search.Client](app, client)
+func Provide[T any](a *Adele, service T) {
+	a.registry.mu.Lock()
+	defer a.registry.mu.Unlock()
+
+	if a.registry.services == nil {
+		a.registry.services = make(map[reflect.Type]interface{})
+	}
+
+	a.registry.services[reflect.TypeFor[T]()] = service
+}
+
+// Resolve the service provided to the application with the type. The error wraps
+// ErrNotProvided when no service of the type was provided. Example:
+//
+//	client, err := adele.Resolve[search.Client](app)
+func Resolve[T any](a *Adele) (T, error) {
+	a.registry.mu.RLock()
+	defer a.registry.mu.RUnlock()
+
+	var zero T
+
+	service, ok := a.registry.services[reflect.TypeFor[T]()]
+	if !ok {
+		return zero, fmt.Errorf("%w: %s", ErrNotProvided, reflect.TypeFor[T]())
+	}
+
+	return service.(T), nil
+}
+
+// Resolve the service provided to the application with the type, panicking when no
+// service of the type was provided.
+func MustResolve[T any](a *Adele) T {
+	service, err := Resolve[T](a)
+	if err != nil {
+		panic(err)
+	}
+	return service
+}
+
+// Provide the framework subsystems so providers can resolve them by type.
+func (a *Adele) provideSubsystems() {
+	if a.DB != nil {
+		Provide(a, a.DB)
+	}
+	if a.Cache != nil {
+		Provide(a, a.Cache)
+	}
+	if a.Health != nil {
+		Provide(a, a.Health)
+	}
+	if a.Metrics != nil {
+		Provide(a, a.Metrics)
+	}
+	Provide(a, a.Log)
+	Provide(a, &a.Mail)
+	Provide(a, a.Render)
+	Provide(a, a.Routes)
+	Provide(a, a.Scheduler)
+	Provide(a, a.Session)
+}
+
+// Return the configured file system with the name, i.e., S3, MINIO, SFTP or WEBDAV. The
+// error wraps ErrFilesystemNotProvided when the file system is not configured. Example:
+//
+//	fs, err := app.Filesystem("s3")
+//	if err != nil {
+//	    return err
+//	}
+//	err = fs.Put(fileName, "uploads")
+func (a *Adele) Filesystem(name string) (filesystem.FS, error) {
+	switch fs := a.FileSystem[strings.ToUpper(name)].(type) {
+	case s3filesystem.S3:
+		return &fs, nil
+	case miniofilesystem.Minio:
+		return &fs, nil
+	case sftpfilesystem.SFTP:
+		return &fs, nil
+	case webdavfilesystem.WebDAV:
+		return &fs, nil
+	case filesystem.FS:
+		return fs, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrFilesystemNotProvided, name)
+	}
+}
+
+// Return the names of the providers the provider depends on.
+func dependencies(p Provider) []string {
+	if d, ok := p.(Dependent); ok {
+		return d.DependsOn()
+	}
+	return nil
+}
+
+// Order the providers so every provider follows the providers it depends on. Providers
+// without a dependency between them keep the order they were added in.
+func sortProviders(providers []namedProvider) ([]namedProvider, error) {
+	index := make(map[string]int, len(providers))
+	for i, np := range providers {
+		index[np.name] = i
+	}
+
+	remaining := make([]int, len(providers))
+	dependents := make(map[int][]int)
+
+	for i, np := range providers {
+		for _, dep := range dependencies(np.provider) {
+			j, ok := index[dep]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s depends on %s, which was not added", ErrProviderDependency, np.name, dep)
+			}
+			remaining[i]++
+			dependents[j] = append(dependents[j], i)
+		}
+	}
+
+	var ready []int
+	for i := range providers {
+		if remaining[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	sorted := make([]namedProvider, 0, len(providers))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+
+		sorted = append(sorted, providers[i])
+
+		for _, d := range dependents[i] {
+			remaining[d]--
+			if remaining[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	if len(sorted) != len(providers) {
+		var cycle []string
+		for i, np := range providers {
+			if remaining[i] > 0 {
+				cycle = append(cycle, np.name)
+			}
+		}
+		return nil, fmt.Errorf("%w: dependency cycle between %s", ErrProviderDependency, strings.Join(cycle, ", "))
+	}
+
+	return sorted, nil
+}
@@ -0,0 +1,259 @@
+package adele
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/cidekar/adele-framework/filesystem/s3filesystem"
+)
+
+// A provider recording the calls made to it.
+type testProvider struct {
+	name        string
+	dependsOn   []string
+	events      *[]string
+	registerErr error
+}
+
+func (p *testProvider) Name() string {
+	return p.name
+}
+
+func (p *testProvider) Register(a *Adele) error {
+	*p.events = append(*p.events, "register "+p.name)
+	Provide(a, p)
+	return p.registerErr
+}
+
+func (p *testProvider) Boot(ctx context.Context) error {
+	*p.events = append(*p.events, "boot "+p.name)
+	return nil
+}
+
+func (p *testProvider) Shutdown(ctx context.Context) error {
+	*p.events = append(*p.events, "shutdown "+p.name)
+	return nil
+}
+
+func (p *testProvider) DependsOn() []string {
+	return p.dependsOn
+}
+
+func TestProviders_DependencyOrder(t *testing.T) {
+	a := &Adele{}
+
+	var events []string
+	a.AddProvider("search", &testProvider{name: "search", dependsOn: []string{"queue"}, events: &events})
+	a.AddProvider("queue", &testProvider{name: "queue", events: &events})
+
+	if err := a.registerProviders(); err != nil {
+		t.Fatalf("registerProviders() error = %v", err)
+	}
+
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatalf("Start() error = %v", err)
+	}
+
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	want := "register queue,register search,boot queue,boot search,shutdown search,shutdown queue"
+	if got := strings.Join(events, ","); got != want {
+		t.Errorf("events = %s, want %s", got, want)
+	}
+}
+
+func TestProviders_DependencyErrors(t *testing.T) {
+	var events []string
+
+	a := &Adele{}
+	a.AddProvider("search", &testProvider{name: "search", dependsOn: []string{"missing"}, events: &events})
+	if err := a.registerProviders(); !errors.Is(err, ErrProviderDependency) {
+		t.Errorf("expected ErrProviderDependency for a missing provider, got %v", err)
+	}
+
+	a = &Adele{}
+	a.AddProvider("a", &testProvider{name: "a", dependsOn: []string{"b"}, events: &events})
+	a.AddProvider("b", &testProvider{name: "b", dependsOn: []string{"a"}, events: &events})
+
+	if err := a.AddProvider("a", &testProvider{name: "a", events: &events}); err == nil {
+		t.Error("expected an error adding a provider with a duplicate name")
+	}
+
+	if err := a.registerProviders(); !errors.Is(err, ErrProviderDependency) {
+		t.Errorf("expected ErrProviderDependency for a cycle, got %v", err)
+	}
+
+	if len(events) != 0 {
+		t.Errorf("no provider should be registered, got %v", events)
+	}
+}
+
+func TestWithProvider(t *testing.T) {
+	cfg := ConfigFromEnv()
+	cfg.Cache = CacheConfig{}
+
+	var events []string
+	a, err := NewWithConfig(testRoot(t), cfg,
+		WithProvider(&testProvider{name: "search", dependsOn: []string{"queue"}, events: &events}),
+		WithProvider(&testProvider{name: "queue", events: &events}),
+		WithProvider(&blockingProvider{}),
+	)
+	if err != nil {
+		t.Fatalf("NewWithConfig() error = %v", err)
+	}
+	defer a.Shutdown(context.Background())
+
+	if got := strings.Join(events, ","); got != "register queue,register search" {
+		t.Errorf("events = %s, want register queue,register search", got)
+	}
+	if !a.hasProvider("*adele.blockingProvider") {
+		t.Error("a provider without a name is not added under its type name")
+	}
+
+	failure := errors.New("boom")
+	tests := []struct {
+		name      string
+		providers []Provider
+		want      error
+	}{
+		{"cycle", []Provider{
+			&testProvider{name: "a", dependsOn: []string{"b"}, events: &events},
+			&testProvider{name: "b", dependsOn: []string{"a"}, events: &events},
+		}, ErrProviderDependency},
+		{"duplicate", []Provider{
+			&testProvider{name: "a", events: &events},
+			&testProvider{name: "a", events: &events},
+		}, nil},
+		{"register", []Provider{
+			&testProvider{name: "a", events: &events, registerErr: failure},
+		}, failure},
+	}
+
+	for _, tt := range tests {
+		opts := make([]Option, 0, len(tt.providers))
+		for _, p := range tt.providers {
+			opts = append(opts, WithProvider(p))
+		}
+
+		_, err := NewWithConfig(testRoot(t), cfg, opts...)
+		if err == nil {
+			t.Errorf("%s: expected an error from NewWithConfig", tt.name)
+		}
+		if tt.want != nil && !errors.Is(err, tt.want) {
+			t.Errorf("%s: error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
+
+func TestProviders_AddAfterBootstrap(t *testing.T) {
+	a := &Adele{}
+	if err := a.registerProviders(); err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	if err := a.AddProvider("queue", &testProvider{name: "queue", events: &events}); err != nil {
+		t.Fatalf("AddProvider() error = %v", err)
+	}
+
+	if err := a.AddProvider("search", &testProvider{name: "search", dependsOn: []string{"missing"}, events: &events}); !errors.Is(err, ErrProviderDependency) {
+		t.Errorf("expected ErrProviderDependency, got %v", err)
+	}
+
+	if got := strings.Join(events, ","); got != "register queue" {
+		t.Errorf("events = %s, want register queue", got)
+	}
+
+	// A provider added to a running application would never be booted.
+	if err := a.Start(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.AddProvider("mail", &testProvider{name: "mail", events: &events}); !errors.Is(err, ErrApplicationRunning) {
+		t.Errorf("expected ErrApplicationRunning, got %v", err)
+	}
+	if err := a.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProviders_AddConcurrently(t *testing.T) {
+	a := &Adele{}
+	if err := a.registerProviders(); err != nil {
+		t.Fatal(err)
+	}
+
+	var added atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if a.AddProvider("queue", &blockingProvider{}) == nil {
+				added.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n := added.Load(); n != 1 {
+		t.Errorf("provider added %d times under one name, want once", n)
+	}
+}
+
+// A provider whose registration takes long enough for concurrent calls to overlap.
+type blockingProvider struct{}
+
+func (p *blockingProvider) Register(a *Adele) error {
+	time.Sleep(10 * time.Millisecond)
+	return nil
+}
+
+func (p *blockingProvider) Boot(ctx context.Context) error     { return nil }
+func (p *blockingProvider) Shutdown(ctx context.Context) error { return nil }
+
+func TestResolve(t *testing.T) {
+	a := &Adele{}
+
+	if _, err := Resolve[*testProvider](a); !errors.Is(err, ErrNotProvided) {
+		t.Errorf("expected ErrNotProvided, got %v", err)
+	}
+
+	p := &testProvider{name: "queue"}
+	Provide(a, p)
+	Provide[Provider](a, p)
+
+	got, err := Resolve[*testProvider](a)
+	if err != nil || got != p {
+		t.Errorf("Resolve() = %v, %v; want the provided service", got, err)
+	}
+
+	if iface := MustResolve[Provider](a); iface != p {
+		t.Error("MustResolve() did not return the service provided under the interface type")
+	}
+}
+
+func TestFilesystem(t *testing.T) {
+	a := &Adele{FileSystem: map[string]interface{}{
+		"S3": s3filesystem.S3{Bucket: "uploads"},
+	}}
+
+	fs, err := a.Filesystem("s3")
+	if err != nil {
+		t.Fatalf("Filesystem() error = %v", err)
+	}
+
+	if s3, ok := fs.(*s3filesystem.S3); !ok || s3.Bucket != "uploads" {
+		t.Errorf("Filesystem() = %#v, want the configured S3 file system", fs)
+	}
+
+	if _, err := a.Filesystem("sftp"); !errors.Is(err, ErrFilesystemNotProvided) {
+		t.Errorf("expected ErrFilesystemNotProvided, got %v", err)
+	}
+}
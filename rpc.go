@@ -0,0 +1,151 @@
+package adele
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/rpc"
+)
+
+// Start the RPC server the adele CLI uses to switch the maintenance mode of the
+// application, unless it is disabled. Run starts the server when RPC is configured, and
+// starting a server that is already listening does nothing.
+func (a *Adele) StartRPC() error {
+	if a.rpcDisabled() {
+		return nil
+	}
+
+	a.rpc.mu.Lock()
+	defer a.rpc.mu.Unlock()
+
+	if a.rpc.listener != nil {
+		return nil
+	}
+
+	// A server per start keeps the receiver from clashing with one published by another
+	// application on the default server.
+	server := rpc.NewServer()
+	err := server.RegisterName("RPCServer", &rpcReceiver{app: a})
+	if err != nil {
+		return fmt.Errorf("failed to publish the reciever: %s", err)
+	}
+
+	addr, port := a.rpcAddress()
+
+	listener, err := net.Listen("tcp", addr+":"+port)
+	if err != nil {
+		return fmt.Errorf("failed to announce on the local network address: %s", err)
+	}
+
+	a.rpc.listener = listener
+	a.rpc.conns = map[net.Conn]struct{}{}
+	a.RPCListener = &listener
+
+	go a.rpc.serve(server, listener)
+
+	return nil
+}
+
+// Stop the RPC server, closing the listener and every connection it accepted.
+func (a *Adele) StopRPC() error {
+	a.rpc.mu.Lock()
+	defer a.rpc.mu.Unlock()
+
+	if a.rpc.listener == nil {
+		return nil
+	}
+
+	err := a.rpc.listener.Close()
+	if err != nil && !errors.Is(err, net.ErrClosed) {
+		return fmt.Errorf("failed to close RPC listener: %w", err)
+	}
+
+	// Closing the listener stops new connections; close the open ones too so their
+	// goroutines return.
+	for conn := range a.rpc.conns {
+		conn.Close()
+	}
+
+	a.rpc.listener = nil
+	a.rpc.conns = nil
+	a.RPCListener = nil
+
+	return nil
+}
+
+// Check the configuration for an RPC server Run should start: one with an address or
+// port that is not disabled.
+func (a *Adele) rpcConfigured() bool {
+	c := a.config.RPC
+	return !a.rpcDisabled() && (c.Addr != "" || c.Port != "")
+}
+
+// Check the application configuration for the RPC server being disabled.
+func (a *Adele) rpcDisabled() bool {
+	return a.config.RPC.Disabled
+}
+
+// Resolve the address and port the RPC server listens on from the application
+// configuration, falling back to the framework defaults.
+func (a *Adele) rpcAddress() (string, string) {
+	c := a.config.RPC
+
+	addr := c.Addr
+	if addr == "" {
+		addr = "127.0.0.1"
+	}
+
+	port := c.Port
+	if port == "" {
+		port = "4040"
+	}
+
+	return addr, port
+}
+
+// Accept connections until the listener is closed, tracking each until the client
+// disconnects.
+func (s *rpcServer) serve(server *rpc.Server, listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+
+		s.mu.Lock()
+		if s.listener != listener {
+			s.mu.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		go func() {
+			server.ServeConn(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+		}()
+	}
+}
+
+// The receiver of the RPC calls published by the server.
+type rpcReceiver struct {
+	app *Adele
+}
+
+// Put the application in or out of maintenance mode.
+func (r *rpcReceiver) SetMaintenanceMode(args *MaintenanceModeArgs, reply *MaintenanceModeReply) error {
+	r.app.MaintenanceMode = args.InMaintenanceMode
+	if r.app.MaintenanceMode {
+		reply.Status = "down"
+	} else {
+		reply.Status = "up"
+	}
+	return nil
+}
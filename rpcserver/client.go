@@ -7,13 +7,9 @@ import (
 	"github.com/cidekar/adele-framework"
 )
 
-type MaintenanceModeArgs struct {
-	InMaintenanceMode bool
-}
+type MaintenanceModeArgs = adele.MaintenanceModeArgs
 
-type MaintenanceModeReply struct {
-	Status string
-}
+type MaintenanceModeReply = adele.MaintenanceModeReply
 
 type RPCClient struct {
 	client *rpc.Client
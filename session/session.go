@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/alexedwards/scs/sqlite3store"
 	"github.com/alexedwards/scs/v2"
 )
 
@@ -43,8 +44,14 @@ func (s *Session) InitSession() *scs.SessionManager {
 	session.Cookie.Domain = s.CookieDomain
 	session.Cookie.SameSite = http.SameSiteLaxMode
 
-	// Use cookie for sessions
+	// Store the sessions in the SQLite database when one is given, in memory otherwise.
 	// TODO: allow for other session store types
+	switch strings.ToLower(s.SessionType) {
+	case "sqlite", "sqlite3":
+		if s.DBPool != nil {
+			session.Store = sqlite3store.New(s.DBPool)
+		}
+	}
 
 	return session
 }
@@ -1,14 +1,25 @@
 package adele
 
 import (
+	"context"
 	"net"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
 
 	"github.com/CloudyKit/jet/v6"
 	"github.com/alexedwards/scs/v2"
 	"github.com/cidekar/adele-framework/cache"
 	"github.com/cidekar/adele-framework/database"
+	"github.com/cidekar/adele-framework/filesystem/miniofilesystem"
+	"github.com/cidekar/adele-framework/filesystem/s3filesystem"
+	"github.com/cidekar/adele-framework/filesystem/sftpfilesystem"
+	"github.com/cidekar/adele-framework/filesystem/webdavfilesystem"
+	"github.com/cidekar/adele-framework/health"
 	"github.com/cidekar/adele-framework/helpers"
 	"github.com/cidekar/adele-framework/mailer"
+	"github.com/cidekar/adele-framework/metrics"
 	"github.com/cidekar/adele-framework/middleware"
 	"github.com/cidekar/adele-framework/mux"
 	"github.com/cidekar/adele-framework/render"
@@ -18,20 +29,25 @@ import (
 
 type Adele struct {
 	AppName          string
-	config           config
+	config           Config
 	Cache            cache.Cache
 	DB               *database.Database
 	Debug            bool
 	FileSystem       map[string]interface{}
+	Health           *health.Registry
 	Helpers          *helpers.Helpers
 	JetViews         *jet.Set
+	lifecycle        lifecycle
 	Log              *logrus.Logger
 	Mail             mailer.Mail
 	middleware       middleware.Middleware
 	MaintenanceMode  bool
+	Metrics          *metrics.Registry
+	registry         registry
 	Render           *render.Render
 	Routes           *mux.Mux
 	RootPath         string
+	rpc              rpcServer
 	RPCListener      *net.Listener
 	Scheduler        *cron.Cron
 	Session          *scs.SessionManager
@@ -39,8 +55,285 @@ type Adele struct {
 	ViewsTemplateDir string
 }
 
-type config struct {
-	port        string
-	renderer    string
-	sessionType string
+// Config holds every setting used to bootstrap the framework. The environment loader
+// is one source that fills the struct; callers may build it by hand or override any
+// section with an Option before handing it to NewWithConfig.
+type Config struct {
+	AppName          string
+	Debug            bool
+	Renderer         string
+	ViewsTemplateDir string
+	Database         DatabaseConfig
+	Cache            CacheConfig
+	Mail             MailConfig
+	Session          SessionConfig
+	HTTP             HTTPConfig
+	RPC              RPCConfig
+	Filesystem       FilesystemConfig
+	Upload           UploadConfig
+	Health           HealthConfig
+	Metrics          MetricsConfig
+	ShutdownTimeout  time.Duration
+}
+
+// Connection settings for the application database. An empty Type disables the
+// database subsystem.
+type DatabaseConfig struct {
+	Type     string
+	Host     string
+	Port     string
+	User     string
+	Password string
+	Name     string
+	SSLMode  string
+
+	// The number of times a failed connection is retried at startup and the wait before
+	// the first retry, doubled after every attempt.
+	ConnectRetries int
+	ConnectBackoff time.Duration
+
+	// Settings of the connection pool, where zero uses the framework default, and how long
+	// the first ping may take.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	PingTimeout     time.Duration
+
+	// The number of queries waiting for a free connection within a minute that logs a
+	// warning; zero disables the warning.
+	WaitWarning int
+
+	// How long a SQLite connection waits for the lock held by another and the journal
+	// mode of the database file. Name is the path of the file, or :memory:.
+	BusyTimeout time.Duration
+	JournalMode string
+
+	// Whether every statement is logged, and with the values of its arguments rather than
+	// [redacted]. Statements taking SlowQuery or longer are logged as a warning; zero
+	// disables the warning. In debug mode, a request running one statement at least
+	// RepeatedQueryWarning times logs a warning of an N+1 query.
+	LogQueries           bool
+	LogQueryArgs         bool
+	SlowQuery            time.Duration
+	RepeatedQueryWarning int
+
+	// The hosts of the read replicas, as host or host:port, and the policy selecting the
+	// replica for a read, i.e., round-robin or least-connections. A request reads from
+	// the primary for StickyWindow after it wrote.
+	Replicas             []string
+	ReplicaPolicy        string
+	StickyWindow         time.Duration
+	ReplicaCheckInterval time.Duration
+}
+
+// Cache settings where Driver selects the backing store, i.e., redis, badger, database or
+// memory, and Codec the encoding of typed values, i.e., json, gob or msgpack. The database
+// store keeps the cache in a table of the application database. A LocalTTL above zero
+// keeps the entries read from Redis, Badger or the database in memory for that long,
+// within the limits of the memory cache; with Redis, every instance evicts the keys
+// changed by the others.
+type CacheConfig struct {
+	Driver   string
+	Codec    string
+	LocalTTL time.Duration
+	Redis    RedisConfig
+	Badger   BadgerConfig
+	Memory   MemoryConfig
+}
+
+// Redis connection settings. URL, when set, replaces the host, port, credentials, database
+// and TLS settings and is the only way to use Sentinel or a cluster; see
+// redisdriver.ParseURL for its format.
+type RedisConfig struct {
+	URL         string
+	Host        string
+	Port        string
+	Username    string
+	Password    string
+	DB          int
+	TLS         bool
+	Prefix      string
+	MaxIdle     int
+	MaxActive   int
+	IdleTimeout time.Duration
+}
+
+// Badger settings. The database is kept at Path, or only in memory when InMemory is set,
+// and an EncryptionKey of 16, 24 or 32 bytes encrypts it at rest. Every GCInterval the
+// entries expiring first are evicted while the database is larger than MaxSize bytes, and
+// the value log files with at least GCDiscardRatio of stale data are rewritten.
+type BadgerConfig struct {
+	Path           string
+	InMemory       bool
+	EncryptionKey  string
+	GCInterval     time.Duration
+	GCDiscardRatio float64
+	MaxSize        int64
+}
+
+// Limits of the in-memory cache; the least recently used entries are evicted once either
+// limit is reached. A limit of zero disables it.
+type MemoryConfig struct {
+	MaxEntries int
+	MaxBytes   int64
+}
+
+// Mailer settings for both SMTP delivery and the supported third-party APIs.
+type MailConfig struct {
+	Domain      string
+	Host        string
+	Port        int
+	Username    string
+	Password    string
+	Encryption  string
+	FromName    string
+	FromAddress string
+	API         string
+	APIKey      string
+	APIURL      string
+}
+
+// Session store type and the cookie used to carry the session token. The cookie
+// lifetime is expressed in minutes.
+type SessionConfig struct {
+	Type           string
+	CookieName     string
+	CookieDomain   string
+	CookieLifetime int
+	CookiePersist  bool
+	CookieSecure   bool
+}
+
+// HTTP server settings. The rate limit is the number of requests allowed from a
+// single IP address during the rate duration window.
+type HTTPConfig struct {
+	Port         string
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+	RateLimit    int
+	RateDuration time.Duration
+}
+
+type RPCConfig struct {
+	Disabled bool
+	Addr     string
+	Port     string
+}
+
+// Remote file systems; a file system is only configured when its credentials are
+// present.
+type FilesystemConfig struct {
+	S3     s3filesystem.S3
+	Minio  miniofilesystem.Minio
+	SFTP   sftpfilesystem.SFTP
+	WebDAV webdavfilesystem.WebDAV
+}
+
+// File upload limits where AllowedMimeTypes extends the types allowed by the
+// framework.
+type UploadConfig struct {
+	MaxSize          int64
+	AllowedMimeTypes []string
+}
+
+// Health check settings. Timeout applies to every check of a configured subsystem.
+type HealthConfig struct {
+	Disabled bool
+	Timeout  time.Duration
+}
+
+// Metrics settings. The metrics are only collected and served on Path when enabled since
+// they describe the internals of the application.
+type MetricsConfig struct {
+	Enabled bool
+	Path    string
+}
+
+// Option overrides part of the application before the framework is bootstrapped.
+type Option func(*Adele)
+
+// Hook ties a subsystem into the application lifecycle. OnStart is called by Start in
+// the order hooks were added and OnStop is called by Shutdown in the reverse order.
+// Either function may be nil.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// ComponentError reports the lifecycle component that failed to start or stop.
+type ComponentError struct {
+	Component string
+	Err       error
+}
+
+// State of the application lifecycle shared by Start, Run and Shutdown.
+type lifecycle struct {
+	mu         sync.Mutex
+	hooks      []Hook
+	started    []Hook
+	running    bool
+	stopped    bool
+	httpServer *http.Server
+	serveErr   chan error
+}
+
+// Arguments of the RPC call switching the maintenance mode of the application.
+type MaintenanceModeArgs struct {
+	InMaintenanceMode bool
+}
+
+// Reply of the RPC call switching the maintenance mode, with the status "down" or "up".
+type MaintenanceModeReply struct {
+	Status string
+}
+
+// The RPC server of the application and the connections it accepted, closed when the
+// server is stopped.
+type rpcServer struct {
+	mu       sync.Mutex
+	listener net.Listener
+	conns    map[net.Conn]struct{}
+}
+
+// Provider plugs a subsystem, e.g., a queue, search client or payment gateway, into the
+// application. Register is called while the application is bootstrapped and should
+// provide the services of the subsystem without opening any connection; Boot and
+// Shutdown are called when the application starts and stops.
+type Provider interface {
+	Register(a *Adele) error
+	Boot(ctx context.Context) error
+	Shutdown(ctx context.Context) error
+}
+
+// Dependent is implemented by a provider that must be registered and booted after the
+// providers it names.
+type Dependent interface {
+	DependsOn() []string
+}
+
+// Named is implemented by a provider added with WithProvider to choose the name it is
+// added under; other providers are added under their type name.
+type Named interface {
+	Name() string
+}
+
+// A provider and the name it was added with.
+type namedProvider struct {
+	name     string
+	provider Provider
+}
+
+// Services provided to the application, keyed by type, and the providers added to it.
+type registry struct {
+	mu         sync.RWMutex
+	services   map[reflect.Type]interface{}
+	pending    []namedProvider
+	providers  []namedProvider
+	names      map[string]bool
+	errs       []error
+	registered bool
+	started    bool
 }